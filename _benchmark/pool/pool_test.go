@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	logger "github.com/blendlabs/go-logger"
+	"github.com/blendlabs/go-web"
+)
+
+// Run with `go test -bench . -benchmem ./_benchmark/pool` to compare allocations with and without pooling,
+// with request logging off and on; logged requests retain their ctx, so they are never pooled.
+
+type message struct {
+	Message string `json:"message"`
+}
+
+var largeMessage = &message{Message: strings.Repeat("Hello, World! ", 256)}
+
+func newApp(pooled, logged bool) *web.App {
+	app := web.New()
+	if pooled {
+		app.SetCtxPool(web.NewCtxPool())
+	}
+	if logged {
+		app.SetLogger(logger.New(logger.NewEventFlagSetWithEvents(logger.EventWebRequest), logger.NewLogWriter(ioutil.Discard)))
+	}
+	app.GET("/json", func(ctx *web.Ctx) web.Result {
+		return ctx.JSON().Result(&message{Message: "Hello, World!"})
+	})
+	app.GET("/large", func(ctx *web.Ctx) web.Result {
+		return ctx.JSON().Result(largeMessage)
+	})
+	return app
+}
+
+func benchmarkApp(b *testing.B, pooled, logged bool, path, acceptEncoding string) {
+	app := newApp(pooled, logged)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			req := httptest.NewRequest("GET", path, nil)
+			if len(acceptEncoding) > 0 {
+				req.Header.Set(web.HeaderAcceptEncoding, acceptEncoding)
+			}
+			app.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	})
+}
+
+func BenchmarkJSONPooled(b *testing.B) {
+	benchmarkApp(b, true, false, "/json", "")
+}
+
+func BenchmarkJSONUnpooled(b *testing.B) {
+	benchmarkApp(b, false, false, "/json", "")
+}
+
+func BenchmarkGZIPPooled(b *testing.B) {
+	benchmarkApp(b, true, false, "/large", web.ContentEncodingGZIP)
+}
+
+func BenchmarkGZIPUnpooled(b *testing.B) {
+	benchmarkApp(b, false, false, "/large", web.ContentEncodingGZIP)
+}
+
+func BenchmarkJSONPooledLogged(b *testing.B) {
+	benchmarkApp(b, true, true, "/json", "")
+}
+
+func BenchmarkJSONUnpooledLogged(b *testing.B) {
+	benchmarkApp(b, false, true, "/json", "")
+}
+
+func BenchmarkGZIPPooledLogged(b *testing.B) {
+	benchmarkApp(b, true, true, "/large", web.ContentEncodingGZIP)
+}
+
+func BenchmarkGZIPUnpooledLogged(b *testing.B) {
+	benchmarkApp(b, false, true, "/large", web.ContentEncodingGZIP)
+}
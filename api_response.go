@@ -4,6 +4,7 @@ package web
 type APIResponseMeta struct {
 	StatusCode int
 	Message    string `json:",omitempty"`
+	RequestID  string `json:",omitempty"`
 }
 
 // APIResponse is the standard API response format.
@@ -17,10 +17,7 @@ func (ar *APIResultProvider) NotFound() Result {
 	return &JSONResult{
 		StatusCode: http.StatusNotFound,
 		Response: &APIResponse{
-			Meta: &APIResponseMeta{
-				StatusCode: http.StatusNotFound,
-				Message:    "Not Found",
-			},
+			Meta: ar.meta(http.StatusNotFound, "Not Found"),
 		},
 	}
 }
@@ -30,10 +27,7 @@ func (ar *APIResultProvider) NotAuthorized() Result {
 	return &JSONResult{
 		StatusCode: http.StatusForbidden,
 		Response: &APIResponse{
-			Meta: &APIResponseMeta{
-				StatusCode: http.StatusForbidden,
-				Message:    "Not Authorized",
-			},
+			Meta: ar.meta(http.StatusForbidden, "Not Authorized"),
 		},
 	}
 }
@@ -47,10 +41,7 @@ func (ar *APIResultProvider) InternalError(err error) Result {
 	return &JSONResult{
 		StatusCode: http.StatusInternalServerError,
 		Response: &APIResponse{
-			Meta: &APIResponseMeta{
-				StatusCode: http.StatusInternalServerError,
-				Message:    err.Error(),
-			},
+			Meta: ar.meta(http.StatusInternalServerError, err.Error()),
 		},
 	}
 }
@@ -60,10 +51,29 @@ func (ar *APIResultProvider) BadRequest(message string) Result {
 	return &JSONResult{
 		StatusCode: http.StatusBadRequest,
 		Response: &APIResponse{
-			Meta: &APIResponseMeta{
-				StatusCode: http.StatusBadRequest,
-				Message:    message,
-			},
+			Meta: ar.meta(http.StatusBadRequest, message),
+		},
+	}
+}
+
+// BadRequestError returns a service response for an error.
+// Validation and bind errors include their field failures as the response.
+func (ar *APIResultProvider) BadRequestError(err error) Result {
+	return &JSONResult{
+		StatusCode: http.StatusBadRequest,
+		Response: &APIResponse{
+			Meta:     ar.meta(http.StatusBadRequest, err.Error()),
+			Response: badRequestDetails(err),
+		},
+	}
+}
+
+// Status returns a service response for a status code.
+func (ar *APIResultProvider) Status(statusCode int, message string) Result {
+	return &JSONResult{
+		StatusCode: statusCode,
+		Response: &APIResponse{
+			Meta: ar.meta(statusCode, statusMessage(statusCode, message)),
 		},
 	}
 }
@@ -73,10 +83,7 @@ func (ar *APIResultProvider) OK() Result {
 	return &JSONResult{
 		StatusCode: http.StatusOK,
 		Response: &APIResponse{
-			Meta: &APIResponseMeta{
-				StatusCode: http.StatusOK,
-				Message:    "OK!",
-			},
+			Meta: ar.meta(http.StatusOK, "OK!"),
 		},
 	}
 }
@@ -86,11 +93,17 @@ func (ar *APIResultProvider) Result(response interface{}) Result {
 	return &JSONResult{
 		StatusCode: http.StatusOK,
 		Response: &APIResponse{
-			Meta: &APIResponseMeta{
-				StatusCode: http.StatusOK,
-				Message:    "OK!",
-			},
+			Meta:     ar.meta(http.StatusOK, "OK!"),
 			Response: response,
 		},
 	}
 }
+
+// meta returns the response meta, with the request id if the provider has a ctx.
+func (ar *APIResultProvider) meta(statusCode int, message string) *APIResponseMeta {
+	meta := &APIResponseMeta{StatusCode: statusCode, Message: message}
+	if ar.ctx != nil {
+		meta.RequestID = ar.ctx.RequestID()
+	}
+	return meta
+}
@@ -1,15 +1,20 @@
 package web
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"net/url"
@@ -39,31 +44,46 @@ const (
 
 	// DefaultPort is the default port the server binds to.
 	DefaultPort = "8080"
+
+	// DefaultShutdownPollInterval is how often we check if in-flight requests have drained during shutdown.
+	DefaultShutdownPollInterval = 50 * time.Millisecond
 )
 
 // New returns a new app.
 func New() *App {
-	return &App{
+	app := &App{
 		staticRewriteRules:    map[string][]*RewriteRule{},
 		staticHeaders:         map[string]http.Header{},
 		auth:                  NewAuthManager(),
 		viewCache:             NewViewCache(),
+		validator:             NewValidator(),
+		compression:           NewCompression(),
+		webSocketUpgrader:     NewWebSocketUpgrader(),
 		readTimeout:           5 * time.Second,
 		tlsConfig:             &tls.Config{},
 		redirectTrailingSlash: true,
-		//ctxPool:               NewCtxPool(256),
+		shutdownStarted:       make(chan struct{}),
+		shutdownComplete:      make(chan struct{}),
+		requestIDHeader:       HeaderXRequestID,
+		requestIDGenerator:    NewRequestID,
 	}
+	app.viewCache.FuncMap()[ViewFuncURLFor] = app.urlForView
+	return app
 }
 
 // AppStartDelegate is a function that is run on start. Typically you use this to initialize the app.
 type AppStartDelegate func(app *App) error
 
+// AppStopDelegate is a function that is run on shutdown. Typically you use this to release resources acquired on start.
+type AppStopDelegate func(app *App) error
+
 // App is the server for the app.
 type App struct {
-	name     string
-	baseURL  *url.URL
-	bindAddr string
-	port     string
+	name       string
+	baseURL    *url.URL
+	bindAddr   string
+	port       string
+	production bool
 
 	logger *logger.Agent
 
@@ -71,6 +91,14 @@ type App struct {
 	tlsConfig *tls.Config
 
 	startDelegate AppStartDelegate
+	stopDelegates []AppStopDelegate
+
+	server           *http.Server
+	serverLock       sync.Mutex
+	inFlight         int32
+	shutdownOnce     sync.Once
+	shutdownStarted  chan struct{}
+	shutdownComplete chan struct{}
 
 	staticRewriteRules map[string][]*RewriteRule
 	staticHeaders      map[string]http.Header
@@ -86,7 +114,25 @@ type App struct {
 
 	defaultMiddleware []Middleware
 
-	viewCache *ViewCache
+	viewCache     *ViewCache
+	validator     *Validator
+	problemMapper ProblemMapper
+	compression   *Compression
+	cors          *CORS
+	maxBodySize   int64
+	metrics       *Metrics
+	tracer        *Tracer
+
+	trustedProxies   []*net.IPNet
+	forwardedHeaders ForwardedHeaders
+
+	requestIDHeader    string
+	requestIDGenerator RequestIDGenerator
+
+	webSocketUpgrader *WebSocketUpgrader
+
+	ctxPool       *CtxPool
+	ctxSafetyMode bool
 
 	readTimeout       time.Duration
 	readHeaderTimeout time.Duration
@@ -110,6 +156,91 @@ func (a *App) SetName(name string) {
 	}
 }
 
+// IsProduction returns if the app is running in production mode.
+// In production mode, problem responses do not include internal error details.
+func (a *App) IsProduction() bool {
+	return a.production
+}
+
+// SetProduction sets if the app is running in production mode.
+func (a *App) SetProduction(production bool) {
+	a.production = production
+}
+
+// ProblemMapper returns the problem mapper used by the problem result provider.
+func (a *App) ProblemMapper() ProblemMapper {
+	return a.problemMapper
+}
+
+// SetProblemMapper sets the problem mapper used by the problem result provider.
+func (a *App) SetProblemMapper(mapper ProblemMapper) {
+	a.problemMapper = mapper
+}
+
+// Compression returns the response compression config.
+func (a *App) Compression() *Compression {
+	return a.compression
+}
+
+// SetCompression sets the response compression config. Setting it to nil disables compression.
+func (a *App) SetCompression(compression *Compression) {
+	a.compression = compression
+}
+
+// CORS returns the app wide CORS config, if set.
+func (a *App) CORS() *CORS {
+	return a.cors
+}
+
+// SetCORS sets the app wide CORS config; routes can override it with `Route.WithCORS`.
+func (a *App) SetCORS(cors *CORS) {
+	a.cors = cors
+}
+
+// HandleOptions returns if the app answers OPTIONS requests for routed paths with an `Allow` header.
+func (a *App) HandleOptions() bool {
+	return a.handleOptions
+}
+
+// SetHandleOptions sets if the app answers OPTIONS requests for routed paths with an `Allow` header.
+func (a *App) SetHandleOptions(handleOptions bool) {
+	a.handleOptions = handleOptions
+}
+
+// HandleMethodNotAllowed returns if the app answers requests for routed paths with an unrouted method with a 405.
+func (a *App) HandleMethodNotAllowed() bool {
+	return a.handleMethodNotAllowed
+}
+
+// SetHandleMethodNotAllowed sets if the app answers requests for routed paths with an unrouted method with a 405.
+func (a *App) SetHandleMethodNotAllowed(handleMethodNotAllowed bool) {
+	a.handleMethodNotAllowed = handleMethodNotAllowed
+}
+
+// CtxPool returns the pool request contexts and response writers are reused from.
+func (a *App) CtxPool() *CtxPool {
+	return a.ctxPool
+}
+
+// SetCtxPool sets the pool request contexts and response writers are reused from.
+// Pooling is off by default, so a new ctx is allocated for every request. A ctx is not pooled once it is retained,
+// which includes every request that fires an enabled log event, so pooling pays off when request logging is disabled.
+func (a *App) SetCtxPool(pool *CtxPool) {
+	a.ctxPool = pool
+}
+
+// CtxSafetyMode returns if released contexts are poisoned instead of reused.
+func (a *App) CtxSafetyMode() bool {
+	return a.ctxSafetyMode
+}
+
+// SetCtxSafetyMode sets if released contexts are poisoned instead of reused,
+// so that using a ctx after its request completes panics with `ErrCtxReleased`.
+// This is meant for tests and debugging, as it gives up the benefit of pooling.
+func (a *App) SetCtxSafetyMode(safetyMode bool) {
+	a.ctxSafetyMode = safetyMode
+}
+
 // BaseURL returns the domain for the app.
 func (a *App) BaseURL() *url.URL {
 	return a.baseURL
@@ -224,6 +355,9 @@ func (a *App) SetLogger(agent *logger.Agent) {
 		a.logger.AddEventListener(logger.EventWebRequestPostBody, a.onRequestPostBody)
 		a.logger.AddEventListener(logger.EventWebRequest, a.onRequestComplete)
 		a.logger.AddEventListener(logger.EventWebResponse, a.onResponse)
+		a.logger.AddEventListener(logger.EventFatalError, a.onRequestError)
+		a.logger.AddEventListener(logger.EventError, a.onRequestError)
+		a.logger.AddEventListener(EventWebRequestTimeout, a.onRequestError)
 	}
 }
 
@@ -237,6 +371,21 @@ func (a *App) SetAuth(auth *AuthManager) {
 	a.auth = auth
 }
 
+// Validator returns the validator used by `Ctx.Bind`.
+func (a *App) Validator() *Validator {
+	return a.validator
+}
+
+// SetValidator sets the validator used by `Ctx.Bind`.
+func (a *App) SetValidator(validator *Validator) {
+	a.validator = validator
+}
+
+// RegisterValidator registers a named validation rule for use in `validate` struct tags.
+func (a *App) RegisterValidator(name string, rule ValidatorFunc) {
+	a.validator.Register(name, rule)
+}
+
 // SetPort sets the port the app listens on.
 // If BindAddr is not set, this will be returned in the form
 // :Port(), as a result the server will bind to all available interfaces.
@@ -275,6 +424,19 @@ func (a *App) OnStart(action AppStartDelegate) {
 	a.startDelegate = action
 }
 
+// OnStop lets you register a task that is run after the server has shut down and drained.
+// Tasks run in the reverse order they were registered, so an `OnStart` task can
+// register the matching stop hook for anything it sets up.
+func (a *App) OnStop(action AppStopDelegate) {
+	a.stopDelegates = append(a.stopDelegates, action)
+}
+
+// InFlight returns the number of requests currently being processed.
+// Hijacked connections, like websockets, are not counted once they are hijacked.
+func (a *App) InFlight() int {
+	return int(atomic.LoadInt32(&a.inFlight))
+}
+
 // SetBindAddr sets the bind address of the server.
 // It is the first in order of precedence for what ultimately will
 // form the bind address that the server binds to.
@@ -322,6 +484,10 @@ func (a *App) commonStartupTasks() error {
 // This lets you configure things like TLS keys and
 // other options.
 func (a *App) StartWithServer(server *http.Server) error {
+	a.serverLock.Lock()
+	a.server = server
+	a.serverLock.Unlock()
+
 	a.logger.OnEvent(EventAppStart, a)
 	defer a.logger.OnEvent(EventAppExit, a)
 
@@ -360,15 +526,114 @@ func (a *App) StartWithServer(server *http.Server) error {
 	}
 
 	if a.listenTLS {
-		return exception.Wrap(server.ListenAndServeTLS("", ""))
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err == http.ErrServerClosed {
+		// if the server was closed by `Shutdown`, wait for it to finish draining before we exit;
+		// if it was closed directly, there is nothing to wait for.
+		select {
+		case <-a.shutdownStarted:
+			<-a.shutdownComplete
+		default:
+		}
+		return nil
+	}
+	return exception.Wrap(err)
+}
+
+// Shutdown gracefully stops the server. It stops accepting new connections, waits for
+// in-flight requests to complete (or the context to be cancelled), and then runs any
+// tasks registered with `OnStop`.
+// If the context expires before the requests drain, the context error is returned.
+func (a *App) Shutdown(ctx context.Context) error {
+	var err error
+	a.shutdownOnce.Do(func() {
+		close(a.shutdownStarted)
+		defer close(a.shutdownComplete)
+		a.logger.OnEvent(EventAppShutdown, a)
+
+		a.serverLock.Lock()
+		server := a.server
+		a.serverLock.Unlock()
+
+		if server != nil {
+			a.logger.Sync().Infof("server shutting down, waiting for %d in-flight request(s)", a.InFlight())
+			err = server.Shutdown(ctx)
+		}
+		if err == nil {
+			err = a.drain(ctx)
+		}
+		if err != nil {
+			a.logger.Sync().Infof("server shutdown did not drain cleanly: %v", err)
+		}
+
+		for i := len(a.stopDelegates) - 1; i >= 0; i-- {
+			if stopErr := a.stopDelegates[i](a); stopErr != nil {
+				a.logger.Sync().Fatalf("app stop tasks error: %v", stopErr)
+				if err == nil {
+					err = stopErr
+				}
+			}
+		}
+	})
+	return exception.Wrap(err)
+}
+
+// ShutdownStarted returns a channel that is closed when the app starts shutting down.
+// Long lived handlers that the server does not track, like websockets, should close their connections when it is.
+func (a *App) ShutdownStarted() <-chan struct{} {
+	return a.shutdownStarted
+}
+
+// isShuttingDown returns if the app has started shutting down.
+func (a *App) isShuttingDown() bool {
+	select {
+	case <-a.shutdownStarted:
+		return true
+	default:
+		return false
 	}
+}
 
-	return exception.Wrap(server.ListenAndServe())
+// drain waits for the in-flight request count to reach zero or the context to be done.
+func (a *App) drain(ctx context.Context) error {
+	if a.InFlight() == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(DefaultShutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if a.InFlight() == 0 {
+				return nil
+			}
+		}
+	}
 }
 
 // Register registers a controller with the app's router.
-func (a *App) Register(c Controller) {
-	c.Register(a)
+// The controller can be either a `Controller` or a `RegistrarController`; anything else panics.
+func (a *App) Register(c interface{}) {
+	switch controller := c.(type) {
+	case Controller:
+		controller.Register(a)
+	case RegistrarController:
+		controller.Register(a)
+	default:
+		panic(fmt.Sprintf("controller must be a Controller or a RegistrarController, has: %T", c))
+	}
+}
+
+// Group returns a route group with a shared path prefix and middleware.
+func (a *App) Group(prefix string, middleware ...Middleware) *RouteGroup {
+	return NewRouteGroup(a, prefix, middleware...)
 }
 
 // --------------------------------------------------------------------------------
@@ -376,48 +641,100 @@ func (a *App) Register(c Controller) {
 // --------------------------------------------------------------------------------
 
 // GET registers a GET request handler.
-func (a *App) GET(path string, action Action, middleware ...Middleware) {
-	a.handle("GET", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
+func (a *App) GET(path string, action Action, middleware ...Middleware) *Route {
+	return a.handle("GET", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
 }
 
 // OPTIONS registers a OPTIONS request handler.
-func (a *App) OPTIONS(path string, action Action, middleware ...Middleware) {
-	a.handle("OPTIONS", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
+func (a *App) OPTIONS(path string, action Action, middleware ...Middleware) *Route {
+	return a.handle("OPTIONS", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
 }
 
 // HEAD registers a HEAD request handler.
-func (a *App) HEAD(path string, action Action, middleware ...Middleware) {
-	a.handle("HEAD", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
+func (a *App) HEAD(path string, action Action, middleware ...Middleware) *Route {
+	return a.handle("HEAD", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
 }
 
 // PUT registers a PUT request handler.
-func (a *App) PUT(path string, action Action, middleware ...Middleware) {
-	a.handle("PUT", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
+func (a *App) PUT(path string, action Action, middleware ...Middleware) *Route {
+	return a.handle("PUT", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
 }
 
 // PATCH registers a PATCH request handler.
-func (a *App) PATCH(path string, action Action, middleware ...Middleware) {
-	a.handle("PATCH", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
+func (a *App) PATCH(path string, action Action, middleware ...Middleware) *Route {
+	return a.handle("PATCH", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
 }
 
 // POST registers a POST request actions.
-func (a *App) POST(path string, action Action, middleware ...Middleware) {
-	a.handle("POST", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
+func (a *App) POST(path string, action Action, middleware ...Middleware) *Route {
+	return a.handle("POST", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
 }
 
 // DELETE registers a DELETE request handler.
-func (a *App) DELETE(path string, action Action, middleware ...Middleware) {
-	a.handle("DELETE", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
+func (a *App) DELETE(path string, action Action, middleware ...Middleware) *Route {
+	return a.handle("DELETE", path, a.renderAction(a.middlewarePipeline(action, middleware...)))
 }
 
 // Lookup finds the route data for a given method and path.
+// HEAD lookups fall back to the GET route if there is no matching HEAD route.
 func (a *App) Lookup(method, path string) (route *Route, params RouteParameters, slashRedirect bool) {
 	if root := a.routes[method]; root != nil {
-		return root.getValue(path)
+		route, params, slashRedirect = root.getValue(path)
+		if route != nil || slashRedirect {
+			return
+		}
+	}
+	if method == "HEAD" {
+		if root := a.routes["GET"]; root != nil {
+			return root.getValue(path)
+		}
 	}
 	return nil, nil, false
 }
 
+// RouteByName returns the route registered with a given name.
+// Routes are searched in method order; names are expected to be unique.
+// An empty name never matches, since unnamed routes have an empty name.
+func (a *App) RouteByName(name string) *Route {
+	if len(name) == 0 {
+		return nil
+	}
+	methods := make([]string, 0, len(a.routes))
+	for method := range a.routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	for _, method := range methods {
+		if route := a.routes[method].findRouteByName(name); route != nil {
+			return route
+		}
+	}
+	return nil
+}
+
+// URLFor returns the path for a named route, filling in the route parameters.
+// Parameters not present in the route path are added as query string values.
+func (a *App) URLFor(name string, params RouteParameters) (string, error) {
+	route := a.RouteByName(name)
+	if route == nil {
+		return "", exception.Newf("no route named `%s`", name)
+	}
+	return route.URL(params)
+}
+
+// urlForView is the `url_for` view func; params are given as alternating keys and values.
+func (a *App) urlForView(name string, keysAndValues ...string) (string, error) {
+	if len(keysAndValues)%2 != 0 {
+		return "", exception.Newf("url_for `%s` requires an even number of key value arguments", name)
+	}
+	params := RouteParameters{}
+	for index := 0; index < len(keysAndValues); index += 2 {
+		params.Set(keysAndValues[index], keysAndValues[index+1])
+	}
+	return a.URLFor(name, params)
+}
+
 // ServeHTTP makes the router implement the http.Handler interface.
 func (a *App) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if a.panicHandler != nil {
@@ -426,9 +743,16 @@ func (a *App) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	path := req.URL.Path
 
+	if isPreflightRequest(req) {
+		if cors := a.corsFor(path, req.Header.Get(HeaderAccessControlRequestMethod)); cors != nil {
+			cors.ServePreflight(w, req, a.allowed(path, req.Method))
+			return
+		}
+	}
+
 	if root := a.routes[req.Method]; root != nil {
 		if route, params, tsr := root.getValue(path); route != nil {
-			route.Handler(w, req, route, params, nil)
+			route.Handler(w, req, route, params)
 			return
 		} else if req.Method != "CONNECT" && path != "/" {
 			code := 301 // Permanent redirect, request with GET method
@@ -448,6 +772,15 @@ func (a *App) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	if req.Method == "HEAD" {
+		if root := a.routes["GET"]; root != nil {
+			if route, params, _ := root.getValue(path); route != nil {
+				a.serveHeadAsGet(w, req, route, params)
+				return
+			}
+		}
+	}
+
 	if req.Method == "OPTIONS" {
 		// Handle OPTIONS requests
 		if a.handleOptions {
@@ -462,7 +795,7 @@ func (a *App) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			if allow := a.allowed(path, req.Method); len(allow) > 0 {
 				w.Header().Set("Allow", allow)
 				if a.methodNotAllowedHandler != nil {
-					a.methodNotAllowedHandler(w, req, nil, nil, nil)
+					a.methodNotAllowedHandler(w, req, nil, nil)
 				} else {
 					http.Error(w,
 						http.StatusText(http.StatusMethodNotAllowed),
@@ -476,7 +809,7 @@ func (a *App) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	// Handle 404
 	if a.notFoundHandler != nil {
-		a.notFoundHandler(w, req, nil, nil, nil)
+		a.notFoundHandler(w, req, nil, nil)
 	} else {
 		http.NotFound(w, req)
 	}
@@ -552,9 +885,9 @@ func (a *App) SetPanicHandler(handler PanicAction) {
 	a.panicAction = handler
 	a.panicHandler = func(w http.ResponseWriter, r *http.Request, err interface{}) {
 		a.renderAction(func(ctx *Ctx) Result {
-			a.logger.ErrorEventWithState(logger.EventFatalError, logger.ColorRed, fmt.Errorf("%v", err), ctx)
+			ctx.logFatal(fmt.Errorf("%v", err))
 			return handler(ctx, err)
-		})(w, r, nil, nil, nil)
+		})(w, r, nil, nil)
 	}
 }
 
@@ -579,7 +912,7 @@ func (a *App) onRequestStart(writer logger.Logger, ts logger.TimeSource, eventFl
 	if !isContext {
 		return
 	}
-	logger.WriteRequestStart(writer, ts, context.Request)
+	logger.WriteRequestStart(withRequestID(writer, context), ts, a.loggedRequest(context.Request))
 }
 
 func (a *App) onRequestPostBody(writer logger.Logger, ts logger.TimeSource, eventFlag logger.EventFlag, state ...interface{}) {
@@ -592,7 +925,7 @@ func (a *App) onRequestPostBody(writer logger.Logger, ts logger.TimeSource, even
 		return
 	}
 
-	logger.WriteRequestBody(writer, ts, body)
+	logger.WriteRequestBody(withRequestID(writer, ctxFromEventState(state)), ts, body)
 }
 
 func (a *App) onRequestComplete(writer logger.Logger, ts logger.TimeSource, eventFlag logger.EventFlag, state ...interface{}) {
@@ -603,7 +936,21 @@ func (a *App) onRequestComplete(writer logger.Logger, ts logger.TimeSource, even
 	if !isContext {
 		return
 	}
-	logger.WriteRequest(writer, ts, context.Request, context.Response.StatusCode(), context.Response.ContentLength(), context.Elapsed())
+	logger.WriteRequest(withRequestID(writer, context), ts, a.loggedRequest(context.Request), context.Response.StatusCode(), context.Response.ContentLength(), context.Elapsed())
+}
+
+// onRequestError writes error events that carry a ctx, tagged with its request id.
+// Errors without a ctx are left to the agent.
+func (a *App) onRequestError(writer logger.Logger, ts logger.TimeSource, eventFlag logger.EventFlag, state ...interface{}) {
+	if len(state) < 1 {
+		return
+	}
+	err, isError := state[0].(error)
+	context := ctxFromEventState(state)
+	if !isError || context == nil {
+		return
+	}
+	withRequestID(writer, context).Errorf("%v", err)
 }
 
 func (a *App) onResponse(writer logger.Logger, ts logger.TimeSource, eventFlag logger.EventFlag, state ...interface{}) {
@@ -614,19 +961,56 @@ func (a *App) onResponse(writer logger.Logger, ts logger.TimeSource, eventFlag l
 	if !stateIsBody {
 		return
 	}
-	logger.WriteResponseBody(writer, ts, body)
+	logger.WriteResponseBody(withRequestID(writer, ctxFromEventState(state)), ts, body)
 }
 
 // renderAction is the translation step from Action to Handler.
 // this is where the bulk of the "pipeline" happens.
 func (a *App) renderAction(action Action) Handler {
-	return func(w http.ResponseWriter, r *http.Request, route *Route, p RouteParameters, tx *sql.Tx) {
+	return func(w http.ResponseWriter, r *http.Request, route *Route, p RouteParameters) {
+		atomic.AddInt32(&a.inFlight, 1)
+		var hijacked bool
+		defer func() {
+			if !hijacked {
+				atomic.AddInt32(&a.inFlight, -1)
+			}
+		}()
+
+		metrics := a.metrics
+		if metrics != nil {
+			metrics.RequestStarted(route, r.Method)
+		}
+		var completed bool
+		defer func() {
+			if metrics != nil && !completed {
+				metrics.RequestPanicked(route, r.Method)
+			}
+		}()
+
 		a.setResponseHeaders(w)
+		a.setCORSHeaders(w, r, route)
 		response := a.newResponse(w, r)
 		context := a.pipelineInit(response, r, route, p)
-		context = context.WithTx(tx)
+		// a hijacked connection (i.e. a websocket) is done with as a request; it does not hold up a drain,
+		// and its metrics record the upgrade rather than how long the connection stays open.
+		context.onHijack = func() {
+			if hijacked {
+				return
+			}
+			hijacked = true
+			atomic.AddInt32(&a.inFlight, -1)
+			if metrics != nil {
+				metrics.RequestCompleted(route, r.Method, http.StatusSwitchingProtocols, context.Elapsed(), 0)
+			}
+			completed = true
+		}
 		a.renderResult(action, context)
 		a.pipelineComplete(context)
+		if metrics != nil && !hijacked {
+			metrics.RequestCompleted(route, r.Method, context.getLoggedStatusCode(), context.Elapsed(), context.getLoggedContentLength())
+		}
+		completed = true
+		a.releaseCtx(context)
 	}
 }
 
@@ -635,42 +1019,102 @@ func (a *App) setResponseHeaders(w http.ResponseWriter) {
 	w.Header().Set(HeaderXServedBy, PackageName)
 }
 
-func (a *App) newResponse(w http.ResponseWriter, r *http.Request) ResponseWriter {
-	var response ResponseWriter
-	if a.shouldCompressOutput(r) {
-		w.Header().Set(HeaderContentEncoding, ContentEncodingGZIP)
-		if a.logger.IsEnabled(logger.EventWebResponse) {
-			response = NewBufferedCompressedResponseWriter(w)
-		} else {
-			response = NewCompressedResponseWriter(w)
+// setCORSHeaders sets the CORS headers for the route's config, or the app config, if either is set.
+func (a *App) setCORSHeaders(w http.ResponseWriter, r *http.Request, route *Route) {
+	cors := a.cors
+	if route != nil && route.CORS != nil {
+		cors = route.CORS
+	}
+	if cors != nil {
+		cors.ApplyHeaders(w.Header(), r)
+	}
+}
+
+// corsFor returns the CORS config for the route a preflight request is asking about.
+func (a *App) corsFor(path, method string) *CORS {
+	method = strings.ToUpper(method)
+	if root := a.routes[method]; root != nil {
+		if route, _, _ := root.getValue(path); route != nil && route.CORS != nil {
+			return route.CORS
 		}
-	} else {
-		w.Header().Set(HeaderContentEncoding, ContentEncodingIdentity)
-		if a.logger.IsEnabled(logger.EventWebResponse) {
-			response = NewBufferedResponseWriter(w)
-		} else {
-			response = NewResponseWriter(w)
+	}
+	if method == "HEAD" {
+		if root := a.routes["GET"]; root != nil {
+			if route, _, _ := root.getValue(path); route != nil && route.CORS != nil {
+				return route.CORS
+			}
+		}
+	}
+	if a.cors != nil && len(a.allowed(path, "OPTIONS")) > 0 {
+		return a.cors
+	}
+	return nil
+}
+
+func (a *App) newResponse(w http.ResponseWriter, r *http.Request) ResponseWriter {
+	buffered := a.logger.IsEnabled(logger.EventWebResponse)
+	if a.compression != nil {
+		addVaryHeader(w.Header(), HeaderAcceptEncoding)
+		if encoding := a.negotiateCompression(r); len(encoding) > 0 {
+			if buffered {
+				return a.compression.NewBufferedResponseWriter(w, encoding)
+			}
+			if a.ctxPool != nil {
+				return a.compression.acquireResponseWriter(w, encoding)
+			}
+			return a.compression.NewResponseWriter(w, encoding)
 		}
 	}
-	return response
+	if buffered {
+		return NewBufferedResponseWriter(w)
+	}
+	if a.ctxPool != nil {
+		return acquireResponseWriter(w)
+	}
+	return NewResponseWriter(w)
 }
 
-func (a *App) shouldCompressOutput(r *http.Request) bool {
-	return strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
+// negotiateCompression returns the content encoding to compress the response with, if any.
+// HEAD requests are negotiated like GET so their headers match what GET would send.
+func (a *App) negotiateCompression(r *http.Request) string {
+	if a.compression == nil {
+		return ""
+	}
+	return a.compression.Negotiate(r.Header.Get(HeaderAcceptEncoding))
 }
 
 func (a *App) pipelineInit(w ResponseWriter, r *http.Request, route *Route, p RouteParameters) *Ctx {
 	context := a.newCtx(w, r, route, p)
+	context.limitBody(a.maxBodySizeFor(route))
+	context.requestID = a.requestID(r)
+	if len(a.requestIDHeader) > 0 {
+		w.Header().Set(a.requestIDHeader, context.requestID)
+	}
+	if a.tracer != nil {
+		context.startRequestSpan(a.tracer)
+	}
 	context.onRequestStart()
+	if a.logger.IsEnabled(logger.EventWebRequestStart) {
+		context.Retain()
+	}
 	a.logger.OnEvent(logger.EventWebRequestStart, context)
 	return context
 }
 
 // Ctx creates a context.
 func (a *App) newCtx(w ResponseWriter, r *http.Request, route *Route, p RouteParameters) *Ctx {
-	ctx := NewCtx(w, r, p) //a.ctxPool.Get()
+	var ctx *Ctx
+	if a.ctxPool != nil {
+		ctx = a.ctxPool.Get()
+	} else {
+		ctx = NewCtx(w, r, p)
+	}
 	ctx.Response = w
 	ctx.Request = r
+	ctx.requestContext = nil
+	if r != nil {
+		ctx.requestContext = r.Context()
+	}
 	ctx.routeParameters = p
 
 	ctx.app = a
@@ -683,12 +1127,40 @@ func (a *App) newCtx(w ResponseWriter, r *http.Request, route *Route, p RoutePar
 	return ctx
 }
 
+// releaseCtx returns a ctx and its response writer to their pools, unless the ctx was retained.
+// In safety mode the ctx is poisoned instead, so later use panics with `ErrCtxReleased`.
+func (a *App) releaseCtx(ctx *Ctx) {
+	if a.ctxPool == nil || ctx.retained {
+		return
+	}
+	if a.ctxSafetyMode {
+		ctx.Reset()
+		ctx.Response = releasedResponseWriter{}
+		ctx.released = true
+		return
+	}
+	releaseResponseWriter(ctx.Response)
+	a.ctxPool.Put(ctx)
+}
+
 func (a *App) renderResult(action Action, ctx *Ctx) error {
-	result := action(ctx)
+	var result Result
+	if ctx.IsBodyTooLarge() {
+		result = StatusResult(ctx.DefaultResultProvider(), http.StatusRequestEntityTooLarge, "")
+	} else {
+		result = action(ctx)
+		// the action ran into the body size limit; report that rather than however it handled the read error.
+		if ctx.IsBodyTooLarge() && ctx.Response.StatusCode() == 0 {
+			result = StatusResult(ctx.DefaultResultProvider(), http.StatusRequestEntityTooLarge, "")
+		}
+	}
 	if result != nil {
+		span, parent := ctx.enterSpan(SpanNameRender)
 		err := result.Render(ctx)
+		span.SetError(err)
+		ctx.exitSpan(span, parent)
 		if err != nil {
-			a.logger.Error(err)
+			ctx.logError(err)
 			return err
 		}
 	}
@@ -696,29 +1168,37 @@ func (a *App) renderResult(action Action, ctx *Ctx) error {
 }
 
 func (a *App) pipelineComplete(ctx *Ctx) {
-	err := ctx.Response.Flush()
+	err := ctx.Response.FlushError()
 	if err != nil && err != http.ErrBodyNotAllowed {
-		a.logger.Error(err)
+		ctx.logError(err)
 	}
 	ctx.onRequestEnd()
 	ctx.setLoggedStatusCode(ctx.Response.StatusCode())
 	ctx.setLoggedContentLength(ctx.Response.ContentLength())
+	if err := ctx.finishRequestSpan(); err != nil {
+		ctx.logError(err)
+	}
 	if a.logger.IsEnabled(logger.EventWebResponse) {
-		a.logger.OnEvent(logger.EventWebResponse, ctx.Response.Bytes())
+		ctx.Retain()
+		a.logger.OnEvent(logger.EventWebResponse, ctx.Response.Bytes(), ctx)
 	}
 
 	err = ctx.Response.Close()
 	if err != nil && err != http.ErrBodyNotAllowed {
-		a.logger.Error(err)
+		ctx.logError(err)
 	}
 
 	// effectively "request complete"
+	if a.logger.IsEnabled(logger.EventWebRequest) {
+		ctx.Retain()
+	}
 	a.logger.OnEvent(logger.EventWebRequest, ctx)
 }
 
 func (a *App) middlewarePipeline(action Action, middleware ...Middleware) Action {
+	action = tracedAction(SpanNameAction, action)
 	if len(middleware) == 0 && len(a.defaultMiddleware) == 0 {
-		return action
+		return tracedAction(SpanNameMiddleware, action)
 	}
 
 	finalMiddleware := make([]Middleware, len(middleware)+len(a.defaultMiddleware))
@@ -733,7 +1213,7 @@ func (a *App) middlewarePipeline(action Action, middleware ...Middleware) Action
 		cursor--
 	}
 
-	return NestMiddleware(action, finalMiddleware...)
+	return tracedAction(SpanNameMiddleware, NestMiddleware(action, finalMiddleware...))
 }
 
 // staticAction returns a Action for a given static path and root.
@@ -764,7 +1244,7 @@ func (a *App) staticAction(path string, root http.FileSystem) Action {
 	}
 }
 
-func (a *App) handle(method, path string, handler Handler) {
+func (a *App) handle(method, path string, handler Handler) *Route {
 	if len(path) == 0 {
 		panic("path must not be empty")
 	}
@@ -781,7 +1261,17 @@ func (a *App) handle(method, path string, handler Handler) {
 		a.routes[method] = root
 	}
 
-	root.addRoute(method, path, handler)
+	route := root.addRoute(method, path, handler)
+	route.Params = routeParamNames(path)
+	return route
+}
+
+func (a *App) hasRoute(method, path string) bool {
+	if root := a.routes[method]; root != nil {
+		route, _, _ := root.getValue(path)
+		return route != nil
+	}
+	return false
 }
 
 func (a *App) allowed(path, reqMethod string) (allow string) {
@@ -814,6 +1304,11 @@ func (a *App) allowed(path, reqMethod string) (allow string) {
 			} else {
 				allow += ", " + method
 			}
+
+			// HEAD is served by GET routes if there is no explicit HEAD route.
+			if method == "GET" && reqMethod != "HEAD" && !a.hasRoute("HEAD", path) {
+				allow += ", HEAD"
+			}
 		}
 	}
 	if len(allow) > 0 {
@@ -822,6 +1317,16 @@ func (a *App) allowed(path, reqMethod string) (allow string) {
 	return
 }
 
+// serveHeadAsGet runs a GET route for a HEAD request, discarding the response body.
+func (a *App) serveHeadAsGet(w http.ResponseWriter, req *http.Request, route *Route, params RouteParameters) *HeadResponseWriter {
+	head := NewHeadResponseWriter(w)
+	route.Handler(head, req, route, params)
+	if err := head.Close(); err != nil {
+		a.logger.Error(err)
+	}
+	return head
+}
+
 func (a *App) recover(w http.ResponseWriter, req *http.Request) {
 	if rcv := recover(); rcv != nil {
 		a.panicHandler(w, req, rcv)
@@ -2,9 +2,12 @@ package web
 
 import (
 	"bytes"
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/blendlabs/go-assert"
 	"github.com/blendlabs/go-logger"
@@ -299,3 +302,170 @@ func TestAppWritesLogs(t *testing.T) {
 	assert.NotZero(buffer.Len())
 	assert.NotEmpty(buffer.String())
 }
+
+func TestAppShutdownDrainsInFlight(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		close(started)
+		<-release
+		return r.Raw([]byte("ok!"))
+	})
+
+	var didStop bool
+	app.OnStop(func(a *App) error {
+		didStop = true
+		return nil
+	})
+
+	go func() {
+		app.Mock().Get("/").Execute()
+		close(done)
+	}()
+	<-started
+	assert.Equal(1, app.InFlight())
+
+	go func() {
+		time.Sleep(2 * DefaultShutdownPollInterval)
+		close(release)
+	}()
+	assert.Nil(app.Shutdown(context.Background()))
+	<-done
+	assert.Zero(app.InFlight())
+	assert.True(didStop)
+}
+
+func TestAppShutdownDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		close(started)
+		<-release
+		return nil
+	})
+
+	go app.Mock().Get("/").Execute()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownPollInterval)
+	defer cancel()
+	assert.NotNil(app.Shutdown(ctx))
+}
+
+func TestAppStartShutdown(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	app := New()
+	app.SetLogger(logger.New(logger.NewEventFlagSetNone()))
+	app.SetBindAddr("127.0.0.1:0")
+	app.OnStart(func(a *App) error {
+		close(started)
+		return nil
+	})
+
+	stopOrder := []int{}
+	app.OnStop(func(a *App) error {
+		stopOrder = append(stopOrder, 1)
+		return nil
+	})
+	app.OnStop(func(a *App) error {
+		stopOrder = append(stopOrder, 2)
+		return nil
+	})
+
+	exited := make(chan error)
+	go func() {
+		exited <- app.Start()
+	}()
+	<-started
+
+	assert.Nil(app.Shutdown(context.Background()))
+	assert.Nil(<-exited)
+	assert.Equal([]int{2, 1}, stopOrder)
+}
+
+func TestAppStartServerClosedDirectly(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	app := New()
+	app.SetLogger(logger.New(logger.NewEventFlagSetNone()))
+	app.OnStart(func(a *App) error {
+		close(started)
+		return nil
+	})
+
+	server := &http.Server{Addr: "127.0.0.1:0", Handler: app}
+	exited := make(chan error)
+	go func() {
+		exited <- app.StartWithServer(server)
+	}()
+	<-started
+
+	assert.Nil(server.Shutdown(context.Background()))
+	select {
+	case err := <-exited:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		t.Fatal("StartWithServer did not return after the server was shut down")
+	}
+}
+
+func TestAppHeadFallsBackToGet(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		return r.Text().Result("ok!")
+	})
+	app.POST("/post", controllerNoOp)
+
+	req := httptest.NewRequest("HEAD", "/", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("3", res.Header().Get(HeaderContentLength))
+	assert.Equal(ContentTypeText, res.Header().Get(HeaderContentType))
+	assert.Zero(res.Body.Len())
+
+	body, meta, err := app.Mock().Head("/").BytesWithMeta()
+	assert.Nil(err)
+	assert.Empty(body)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal(3, meta.ContentLength)
+
+	req = httptest.NewRequest("HEAD", "/post", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(http.StatusNotFound, res.Code)
+}
+
+func TestAppHeadPrefersHeadRoute(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		return r.Text().Result("get")
+	})
+	app.HEAD("/", func(r *Ctx) Result {
+		r.Response.Header().Set("X-Head", "true")
+		return r.NoContent()
+	})
+
+	req := httptest.NewRequest("HEAD", "/", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(http.StatusNoContent, res.Code)
+	assert.Equal("true", res.Header().Get("X-Head"))
+}
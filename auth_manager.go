@@ -38,16 +38,17 @@ const (
 // NewAuthManager returns a new session manager.
 func NewAuthManager() *AuthManager {
 	return &AuthManager{
-		sessionCache:                NewSessionCache(),
+		sessionStore:                NewMemorySessionStore(),
 		sessionCookieIsSessionBound: true,
 		sessionParamName:            DefaultSessionParamName,
 		secureSessionParamName:      DefaultSecureSessionParamName,
+		csrf:                        NewCSRF(),
 	}
 }
 
 // AuthManager is a manager for sessions.
 type AuthManager struct {
-	sessionCache           *SessionCache
+	sessionStore           SessionStore
 	persistHandler         func(*Ctx, *Session, *sql.Tx) error
 	fetchHandler           func(sessionID string, tx *sql.Tx) (*Session, error)
 	removeHandler          func(sessionID string, tx *sql.Tx) error
@@ -57,10 +58,14 @@ type AuthManager struct {
 	secureSessionParamName string
 
 	secret []byte
+	csrf   *CSRF
 
 	sessionCookieIsSessionBound  bool
 	sessionCookieIsHTTPSOnly     bool
 	sessionCookieTimeoutProvider func(rc *Ctx) *time.Time
+
+	sessionLifetime    time.Duration
+	sessionIdleTimeout time.Duration
 }
 
 // --------------------------------------------------------------------------------
@@ -89,7 +94,11 @@ func (am *AuthManager) Login(userID int64, context *Ctx) (session *Session, err
 		}
 	}
 
-	am.sessionCache.Add(session)
+	err = am.sessionStore.Put(session)
+	if err != nil {
+		return nil, err
+	}
+
 	am.injectCookie(am.sessionParamName, context, sessionID)
 	if am.ShouldIssueSecureSesssionID() {
 		am.injectCookie(am.secureSessionParamName, context, secureSessionID)
@@ -103,7 +112,10 @@ func (am *AuthManager) Logout(session *Session, context *Ctx) error {
 		return nil
 	}
 
-	am.sessionCache.Expire(session.SessionID)
+	err := am.sessionStore.Remove(session.SessionID)
+	if err != nil {
+		return err
+	}
 
 	if context != nil {
 		context.ExpireCookie(am.sessionParamName, DefaultSessionCookiePath)
@@ -121,6 +133,7 @@ func (am *AuthManager) Logout(session *Session, context *Ctx) error {
 }
 
 // VerifySession checks a sessionID to see if it's valid.
+// It does not mark the session as used; see `MarkSessionActive`.
 func (am *AuthManager) VerifySession(context *Ctx) (*Session, error) {
 	sessionID := am.readSessionID(context)
 
@@ -137,7 +150,14 @@ func (am *AuthManager) VerifySession(context *Ctx) (*Session, error) {
 		}
 	}
 
-	if session, hasSession := am.sessionCache.Get(sessionID); hasSession {
+	session, err := am.sessionStore.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session != nil {
+		if am.isSessionExpired(session) {
+			return nil, am.Logout(session, context)
+		}
 		return session, nil
 	}
 
@@ -148,7 +168,6 @@ func (am *AuthManager) VerifySession(context *Ctx) (*Session, error) {
 		return nil, nil
 	}
 
-	var session *Session
 	if context != nil {
 		session, err = am.fetchHandler(sessionID, context.Tx())
 	} else {
@@ -167,6 +186,10 @@ func (am *AuthManager) VerifySession(context *Ctx) (*Session, error) {
 		return nil, nil
 	}
 
+	if am.isSessionExpired(session) {
+		return nil, am.Logout(session, context)
+	}
+
 	if am.validateHandler != nil {
 		if context != nil {
 			err = am.validateHandler(session, context.Tx())
@@ -178,15 +201,41 @@ func (am *AuthManager) VerifySession(context *Ctx) (*Session, error) {
 		}
 	}
 
-	am.sessionCache.Add(session)
+	err = am.sessionStore.Put(session)
+	if err != nil {
+		return nil, err
+	}
 	return session, nil
 }
 
+// MarkSessionActive records that a session was used on the request.
+// It touches the session in the session store, which records its `LastSeenUTC`, and if the
+// session has an idle timeout and non-session-bound cookies, re-issues the session cookies
+// with a sliding expiry of the idle timeout.
+func (am *AuthManager) MarkSessionActive(context *Ctx, session *Session) error {
+	if session == nil {
+		return nil
+	}
+
+	err := am.sessionStore.Touch(session.SessionID)
+	if err != nil {
+		return err
+	}
+
+	if context != nil && am.sessionIdleTimeout > 0 && !am.sessionCookieIsSessionBound {
+		context.ExtendCookieByDuration(am.sessionParamName, DefaultSessionCookiePath, am.sessionIdleTimeout)
+		if am.ShouldIssueSecureSesssionID() {
+			context.ExtendCookieByDuration(am.secureSessionParamName, DefaultSessionCookiePath, am.sessionIdleTimeout)
+		}
+	}
+	return nil
+}
+
 // Redirect returns a redirect result for when auth fails and you need to
-// send the user to a login page.
+// send the user to a login page. The login redirect handler is given the full url the client requested.
 func (am *AuthManager) Redirect(context *Ctx) Result {
 	if am.loginRedirectHandler != nil {
-		redirectTo := context.auth.loginRedirectHandler(context.Request.URL)
+		redirectTo := context.auth.loginRedirectHandler(context.URL())
 		if redirectTo != nil {
 			return context.Redirect(redirectTo.String())
 		}
@@ -208,6 +257,16 @@ func (am *AuthManager) Secret() []byte {
 	return am.secret
 }
 
+// CSRF returns the csrf config used by `CSRFProtected` and the `csrf_token` view func.
+func (am *AuthManager) CSRF() *CSRF {
+	return am.csrf
+}
+
+// SetCSRF sets the csrf config.
+func (am *AuthManager) SetCSRF(csrf *CSRF) {
+	am.csrf = csrf
+}
+
 // ShouldIssueSecureSesssionID indicates if we shoul issue a second secure sessionID to check the sessionID.
 func (am *AuthManager) ShouldIssueSecureSesssionID() bool {
 	return len(am.secret) > 0
@@ -225,6 +284,28 @@ func (am *AuthManager) SetCookieTimeout(timeoutProvider func(rc *Ctx) *time.Time
 	am.sessionCookieTimeoutProvider = timeoutProvider
 }
 
+// SessionLifetime returns the absolute lifetime of a session.
+func (am *AuthManager) SessionLifetime() time.Duration {
+	return am.sessionLifetime
+}
+
+// SetSessionLifetime sets the absolute lifetime of a session, measured from when it was created.
+// Sessions older than the lifetime are logged out when verified. A value of 0 disables the check.
+func (am *AuthManager) SetSessionLifetime(lifetime time.Duration) {
+	am.sessionLifetime = lifetime
+}
+
+// SessionIdleTimeout returns the session idle timeout.
+func (am *AuthManager) SessionIdleTimeout() time.Duration {
+	return am.sessionIdleTimeout
+}
+
+// SetSessionIdleTimeout sets how long a session can go unused before it is logged out.
+// A value of 0 disables the check.
+func (am *AuthManager) SetSessionIdleTimeout(idleTimeout time.Duration) {
+	am.sessionIdleTimeout = idleTimeout
+}
+
 // SetCookieAsHTTPSOnly overrides defaults when determining if we should use the HTTPS only cooikie option.
 // The default depends on the app configuration (if tls is configured and enabled).
 func (am *AuthManager) SetCookieAsHTTPSOnly(isHTTPSOnly bool) {
@@ -277,9 +358,15 @@ func (am *AuthManager) SetLoginRedirectHandler(handler func(*url.URL) *url.URL)
 	am.loginRedirectHandler = handler
 }
 
-// SessionCache returns the session cache.
-func (am AuthManager) SessionCache() *SessionCache {
-	return am.sessionCache
+// SessionStore returns the session store.
+func (am *AuthManager) SessionStore() SessionStore {
+	return am.sessionStore
+}
+
+// SetSessionStore sets the session store.
+// The store is checked before the fetch handler, and sessions are added to it on login and after they are fetched.
+func (am *AuthManager) SetSessionStore(store SessionStore) {
+	am.sessionStore = store
 }
 
 // IsCookieHTTPSOnly returns if the session cookie is configured to be secure only.
@@ -301,13 +388,23 @@ func (am AuthManager) createSecureSessionID(sessionID string) (string, error) {
 	return EncodeSignSessionID(sessionID, am.secret)
 }
 
+// isSessionExpired returns if a session has exceeded the lifetime or idle timeout.
+func (am *AuthManager) isSessionExpired(session *Session) bool {
+	return session.IsExpired(time.Now().UTC(), am.sessionLifetime, am.sessionIdleTimeout)
+}
+
+// isCookieSecure returns if cookies should be secure only, either because they are configured to be or the client used https.
+func (am *AuthManager) isCookieSecure(context *Ctx) bool {
+	return am.sessionCookieIsHTTPSOnly || (context != nil && context.Request != nil && context.IsSecure())
+}
+
 // InjectCookie injects a session cookie into the context.
 func (am *AuthManager) injectCookie(paramName string, context *Ctx, sessionID string) {
 	if context != nil {
 		if am.sessionCookieIsSessionBound {
-			context.WriteNewCookie(paramName, sessionID, nil, DefaultSessionCookiePath, am.IsCookieHTTPSOnly())
+			context.WriteNewCookie(paramName, sessionID, nil, DefaultSessionCookiePath, am.isCookieSecure(context))
 		} else if am.sessionCookieTimeoutProvider != nil {
-			context.WriteNewCookie(paramName, sessionID, am.sessionCookieTimeoutProvider(context), DefaultSessionCookiePath, am.IsCookieHTTPSOnly())
+			context.WriteNewCookie(paramName, sessionID, am.sessionCookieTimeoutProvider(context), DefaultSessionCookiePath, am.isCookieSecure(context))
 		}
 	}
 }
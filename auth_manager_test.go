@@ -2,7 +2,11 @@ package web
 
 import (
 	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"os"
 	"testing"
+	"time"
 
 	assert "github.com/blendlabs/go-assert"
 )
@@ -126,3 +130,166 @@ func TestAuthManagerIsCookieSecure(t *testing.T) {
 	sm.SetCookieAsHTTPSOnly(false)
 	assert.False(sm.IsCookieHTTPSOnly())
 }
+
+func TestAuthManagerVerifySessionLifetime(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	am := NewAuthManager()
+	am.SetSessionLifetime(time.Hour)
+
+	session := NewSession(1, NewSessionID())
+	session.CreatedUTC = time.Now().UTC().Add(-2 * time.Hour)
+	assert.Nil(am.SessionStore().Put(session))
+
+	rc, err := app.Mock().WithHeader(am.SessionParamName(), session.SessionID).Ctx(nil)
+	assert.Nil(err)
+
+	verified, err := am.VerifySession(rc)
+	assert.Nil(err)
+	assert.Nil(verified)
+
+	stored, err := am.SessionStore().Get(session.SessionID)
+	assert.Nil(err)
+	assert.Nil(stored, "expired sessions should be removed from the store")
+}
+
+func TestAuthManagerVerifySessionIdleTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	am := NewAuthManager()
+	am.SetSessionIdleTimeout(15 * time.Minute)
+
+	idle := NewSession(1, NewSessionID())
+	idle.LastSeenUTC = time.Now().UTC().Add(-time.Hour)
+	assert.Nil(am.SessionStore().Put(idle))
+
+	active := NewSession(2, NewSessionID())
+	active.CreatedUTC = time.Now().UTC().Add(-time.Hour)
+	active.LastSeenUTC = time.Now().UTC().Add(-time.Minute)
+	assert.Nil(am.SessionStore().Put(active))
+
+	rc, err := app.Mock().WithHeader(am.SessionParamName(), idle.SessionID).Ctx(nil)
+	assert.Nil(err)
+	verified, err := am.VerifySession(rc)
+	assert.Nil(err)
+	assert.Nil(verified)
+
+	rc, err = app.Mock().WithHeader(am.SessionParamName(), active.SessionID).Ctx(nil)
+	assert.Nil(err)
+	verified, err = am.VerifySession(rc)
+	assert.Nil(err)
+	assert.NotNil(verified)
+}
+
+func TestAuthManagerMarkSessionActiveSlidesCookie(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.Auth().SetSessionIdleTimeout(15 * time.Minute)
+	app.Auth().SetCookieTimeout(func(rc *Ctx) *time.Time {
+		expires := time.Now().UTC().Add(15 * time.Minute)
+		return &expires
+	})
+
+	session := NewSession(1, NewSessionID())
+	session.LastSeenUTC = time.Now().UTC().Add(-10 * time.Minute)
+	assert.Nil(app.Auth().SessionStore().Put(session))
+
+	app.GET("/", func(r *Ctx) Result {
+		return r.Text().Result("ok")
+	}, SessionRequired)
+
+	meta, err := app.Mock().Get("/").WithCookie(&http.Cookie{Name: app.Auth().SessionParamName(), Value: session.SessionID}).ExecuteWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.True(time.Now().UTC().Sub(session.LastSeen()) < time.Minute)
+
+	cookies := (&http.Response{Header: meta.Headers}).Cookies()
+	assert.Len(cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(session.SessionID, cookie.Value)
+	assert.True(cookie.HttpOnly)
+	assert.True(cookie.Expires.After(time.Now().UTC().Add(14 * time.Minute)))
+}
+
+func TestAuthManagerMarkSessionActivePersistsLastSeen(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := ioutil.TempDir("", "sessions")
+	assert.Nil(err)
+	defer os.RemoveAll(path)
+	fileStore, err := NewFileSessionStore(path)
+	assert.Nil(err)
+
+	for _, store := range []SessionStore{NewMemorySessionStore(), fileStore} {
+		app := New()
+		app.Auth().SetSessionStore(store)
+		app.Auth().SetSessionIdleTimeout(15 * time.Minute)
+		app.GET("/", func(r *Ctx) Result {
+			return r.Text().Result("ok")
+		}, SessionRequired)
+
+		session := NewSession(1, NewSessionID())
+		session.LastSeenUTC = time.Now().UTC().Add(-10 * time.Minute)
+		assert.Nil(store.Put(session))
+		if store == SessionStore(fileStore) {
+			// the file is as old as the session was last seen.
+			sessionPath, err := fileStore.sessionPath(session.SessionID)
+			assert.Nil(err)
+			assert.Nil(os.Chtimes(sessionPath, session.LastSeenUTC, session.LastSeenUTC))
+		}
+
+		meta, err := app.Mock().Get("/").WithCookie(&http.Cookie{Name: app.Auth().SessionParamName(), Value: session.SessionID}).ExecuteWithMeta()
+		assert.Nil(err)
+		assert.Equal(http.StatusOK, meta.StatusCode)
+
+		fetched, err := store.Get(session.SessionID)
+		assert.Nil(err)
+		assert.NotNil(fetched)
+		assert.True(time.Now().UTC().Sub(fetched.LastSeenUTC) < time.Minute, "the store should return the touched last seen time")
+	}
+}
+
+func TestAuthManagerMarkSessionActiveDoesNotWaitOnSessionLock(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.Auth().SetSessionIdleTimeout(15 * time.Minute)
+	session := NewSession(1, NewSessionID())
+	assert.Nil(app.Auth().SessionStore().Put(session))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	app.GET("/stream", func(r *Ctx) Result {
+		close(started)
+		<-release
+		return r.Text().Result("done")
+	}, SessionRequired)
+	app.GET("/", func(r *Ctx) Result {
+		return r.Text().Result("ok")
+	}, SessionRequired)
+
+	cookie := &http.Cookie{Name: app.Auth().SessionParamName(), Value: session.SessionID}
+	streamed := make(chan error, 1)
+	go func() {
+		_, err := app.Mock().Get("/stream").WithCookie(cookie).ExecuteWithMeta()
+		streamed <- err
+	}()
+	<-started
+
+	completed := make(chan int, 1)
+	go func() {
+		meta, err := app.Mock().Get("/").WithCookie(cookie).ExecuteWithMeta()
+		assert.Nil(err)
+		completed <- meta.StatusCode
+	}()
+	select {
+	case statusCode := <-completed:
+		assert.Equal(http.StatusOK, statusCode)
+	case <-time.After(time.Second):
+		t.Fatal("a request should not wait on another request holding the session read lock")
+	}
+	close(release)
+	assert.Nil(<-streamed)
+}
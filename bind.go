@@ -0,0 +1,193 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// TagNameRoute is the struct tag for binding a field from a route parameter.
+	TagNameRoute = "route"
+	// TagNameQuery is the struct tag for binding a field from a query string parameter.
+	TagNameQuery = "query"
+	// TagNameHeader is the struct tag for binding a field from a request header.
+	TagNameHeader = "header"
+	// TagNameForm is the struct tag for binding a field from a form value.
+	TagNameForm = "form"
+
+	// BindSourceBody is the source name for errors reading the request body.
+	BindSourceBody = "body"
+)
+
+var (
+	bindTagNames = []string{TagNameRoute, TagNameQuery, TagNameHeader, TagNameForm}
+)
+
+// BindFieldError is an error binding an individual field.
+type BindFieldError struct {
+	Field   string `json:"field"`
+	Source  string `json:"source"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+// Error returns the error message.
+func (bfe BindFieldError) Error() string {
+	if len(bfe.Name) > 0 {
+		return fmt.Sprintf("%s `%s`: %v", bfe.Source, bfe.Name, bfe.Err)
+	}
+	return fmt.Sprintf("%s: %v", bfe.Source, bfe.Err)
+}
+
+// BindError is an aggregate of the field errors from binding a request.
+type BindError struct {
+	Fields []BindFieldError `json:"fields"`
+}
+
+// Error returns the combined error message.
+func (be *BindError) Error() string {
+	buffer := bytes.NewBufferString("invalid request")
+	for index, field := range be.Fields {
+		if index == 0 {
+			buffer.WriteString(": ")
+		} else {
+			buffer.WriteString("; ")
+		}
+		buffer.WriteString(field.Error())
+	}
+	return buffer.String()
+}
+
+func (be *BindError) add(field, source, name string, err error) {
+	be.Fields = append(be.Fields, BindFieldError{Field: field, Source: source, Name: name, Message: err.Error(), Err: err})
+}
+
+// Bind populates the fields of the target struct from the request.
+// JSON request bodies are decoded into the target first, and then fields are set from
+// the `route`, `query`, `header` and `form` struct tags, e.g.
+//
+//	type getUserRequest struct {
+//		ID   int64  `route:"id"`
+//		Page int    `query:"page"`
+//		Auth string `header:"X-Auth"`
+//	}
+//
+// Missing values are left as is. Any fields that cannot be parsed are collected into
+// a single `*BindError`, which can be passed to `DefaultResultProvider().BadRequest`.
+// Once bound, the target is checked against its `validate` struct tags using the app validator,
+// and any failures are returned as a `*ValidationError`.
+func (rc *Ctx) Bind(target interface{}) error {
+	if target == nil || reflect.TypeOf(target).Kind() != reflect.Ptr || reflectType(target).Kind() != reflect.Struct {
+		return exception.New("bind target must be a pointer to a struct")
+	}
+
+	bindErr := &BindError{}
+	if rc.hasJSONBody() {
+		body, err := rc.PostBody()
+		if err == nil && len(body) > 0 {
+			err = json.Unmarshal(body, target)
+		}
+		if err != nil {
+			bindErr.add("", BindSourceBody, "", err)
+		}
+	}
+
+	targetType := reflectType(target)
+	for index := 0; index < targetType.NumField(); index++ {
+		field := targetType.Field(index)
+		if len(field.PkgPath) > 0 {
+			continue // unexported
+		}
+
+		for _, tagName := range bindTagNames {
+			name := bindTagValue(field, tagName)
+			if len(name) == 0 {
+				continue
+			}
+
+			value, hasValue := rc.bindValue(tagName, name, field.Type)
+			if !hasValue {
+				continue
+			}
+			if err := setValueByName(target, field.Name, value); err != nil {
+				bindErr.add(field.Name, tagName, name, err)
+			}
+		}
+	}
+
+	if len(bindErr.Fields) > 0 {
+		return bindErr
+	}
+	return rc.Validator().Validate(target)
+}
+
+// Validator returns the app validator, or the `DefaultValidator` if there is no app.
+func (rc *Ctx) Validator() *Validator {
+	if rc.app != nil && rc.app.validator != nil {
+		return rc.app.validator
+	}
+	return DefaultValidator
+}
+
+// bindValue returns the raw value for a given source and name.
+// Slices of strings are bound from all values for a query or form key.
+func (rc *Ctx) bindValue(source, name string, fieldType reflect.Type) (interface{}, bool) {
+	if rc.Request == nil {
+		return nil, false
+	}
+
+	var values []string
+	switch source {
+	case TagNameRoute:
+		if rc.routeParameters.Has(name) {
+			values = []string{rc.routeParameters.Get(name)}
+		}
+	case TagNameQuery:
+		if rc.Request.URL != nil {
+			values = rc.Request.URL.Query()[name]
+		}
+	case TagNameHeader:
+		if rc.Request.Header != nil {
+			values = rc.Request.Header[http.CanonicalHeaderKey(name)]
+		}
+	case TagNameForm:
+		if rc.Request.Form == nil {
+			rc.Request.ParseMultipartForm(PostBodySize)
+		}
+		values = rc.Request.Form[name]
+	}
+
+	if len(values) == 0 {
+		return nil, false
+	}
+	if fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.String {
+		return values, true
+	}
+	return values[0], true
+}
+
+func (rc *Ctx) hasJSONBody() bool {
+	if rc.Request == nil || rc.Request.Body == nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(rc.Request.Header.Get(HeaderContentType)), "json")
+}
+
+// bindTagValue returns the name portion of a bind tag, ignoring options and `-`.
+func bindTagValue(field reflect.StructField, tagName string) string {
+	tag := field.Tag.Get(tagName)
+	if index := strings.Index(tag, ","); index >= 0 {
+		tag = tag[:index]
+	}
+	if tag == "-" {
+		return ""
+	}
+	return tag
+}
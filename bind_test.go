@@ -0,0 +1,142 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type bindTestRequest struct {
+	ID      int64    `route:"id"`
+	Page    int      `query:"page"`
+	Tags    []string `query:"tag"`
+	Debug   bool     `query:"debug"`
+	Token   string   `header:"X-Token"`
+	Comment string   `form:"comment"`
+	Name    string   `json:"name"`
+	Score   float32  `json:"score"`
+	ignored string   `query:"ignored"`
+}
+
+func TestCtxBind(t *testing.T) {
+	assert := assert.New(t)
+
+	var req bindTestRequest
+	app := New()
+	app.POST("/users/:id", func(ctx *Ctx) Result {
+		if err := ctx.Bind(&req); err != nil {
+			return ctx.DefaultResultProvider().BadRequest(err.Error())
+		}
+		return ctx.NoContent()
+	})
+
+	meta, err := app.Mock().Post("/users/123").
+		WithQueryString("page", "2").
+		WithQueryString("tag", "a").
+		WithQueryString("tag", "b").
+		WithQueryString("debug", "true").
+		WithQueryString("ignored", "yes").
+		WithHeader("X-Token", "secret").
+		WithHeader(HeaderContentType, ContentTypeApplicationJSON).
+		WithPostBodyAsJSON(map[string]interface{}{"name": "bailey", "score": 1.5}).
+		ExecuteWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusNoContent, meta.StatusCode)
+
+	assert.Equal(123, req.ID)
+	assert.Equal(2, req.Page)
+	assert.Equal([]string{"a", "b"}, req.Tags)
+	assert.True(req.Debug)
+	assert.Equal("secret", req.Token)
+	assert.Equal("bailey", req.Name)
+	assert.Equal(float32(1.5), req.Score)
+	assert.Empty(req.ignored)
+}
+
+func TestCtxBindForm(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, err := NewMockRequestBuilder(nil).Post("/").WithFormValue("comment", "hello").Ctx(nil)
+	assert.Nil(err)
+
+	var req bindTestRequest
+	assert.Nil(ctx.Bind(&req))
+	assert.Equal("hello", req.Comment)
+}
+
+func TestCtxBindErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, err := NewMockRequestBuilder(nil).
+		WithQueryString("page", "two").
+		WithQueryString("debug", "maybe").
+		Ctx(RouteParameters{"id": "abc"})
+	assert.Nil(err)
+
+	var req bindTestRequest
+	err = ctx.Bind(&req)
+	assert.NotNil(err)
+
+	bindErr, isBindErr := err.(*BindError)
+	assert.True(isBindErr)
+	assert.Len(bindErr.Fields, 3)
+	assert.Equal("ID", bindErr.Fields[0].Field)
+	assert.Equal(TagNameRoute, bindErr.Fields[0].Source)
+	assert.Contains("query `page`", err.Error())
+
+	assert.NotNil(ctx.Bind(req), "non-pointer targets should error")
+}
+
+func TestCtxBindPointersAndTimes(t *testing.T) {
+	assert := assert.New(t)
+
+	var req struct {
+		Limit *int       `query:"limit"`
+		Since *time.Time `query:"since"`
+		Until time.Time  `query:"until"`
+		Skip  *int       `query:"skip"`
+	}
+	ctx, err := NewMockRequestBuilder(nil).Get("/").
+		WithQueryString("limit", "10").
+		WithQueryString("since", "2017-06-01").
+		WithQueryString("until", "2017-07-01T00:00:00Z").
+		Ctx(nil)
+	assert.Nil(err)
+	assert.Nil(ctx.Bind(&req))
+	assert.NotNil(req.Limit)
+	assert.Equal(10, *req.Limit)
+	assert.NotNil(req.Since)
+	assert.Equal(time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC), *req.Since)
+	assert.Equal(time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC), req.Until)
+	assert.Nil(req.Skip)
+}
+
+func TestCtxBindUnconvertibleFields(t *testing.T) {
+	assert := assert.New(t)
+
+	var req struct {
+		IDs     []int          `query:"id"`
+		Labels  map[string]int `query:"labels"`
+		Count   uint           `query:"count"`
+		Retries uint16         `query:"retries"`
+	}
+	ctx, err := NewMockRequestBuilder(nil).Get("/").
+		WithQueryString("id", "1").
+		WithQueryString("labels", "a").
+		WithQueryString("count", "-1").
+		WithQueryString("retries", "70000").
+		Ctx(nil)
+	assert.Nil(err)
+
+	err = ctx.Bind(&req)
+	bindErr, isBindErr := err.(*BindError)
+	assert.True(isBindErr)
+	assert.Len(bindErr.Fields, 4)
+	assert.Equal("IDs", bindErr.Fields[0].Field)
+	assert.Equal("Labels", bindErr.Fields[1].Field)
+	assert.Equal("Count", bindErr.Fields[2].Field)
+	assert.Equal("Retries", bindErr.Fields[3].Field)
+	assert.Zero(req.Count)
+}
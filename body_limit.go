@@ -0,0 +1,78 @@
+package web
+
+import (
+	"io"
+	"net/http"
+)
+
+// MaxBodySize returns the app wide request body size limit.
+// Zero (the default) means `PostBodySizeMax`.
+func (a *App) MaxBodySize() int64 {
+	return a.maxBodySize
+}
+
+// SetMaxBodySize sets the app wide request body size limit; routes can override it with `Route.WithMaxBodySize`.
+// Requests over the limit get a `413 Request Entity Too Large` from the default result provider.
+// Limits are capped at `PostBodySizeMax`.
+func (a *App) SetMaxBodySize(maxBodySize int64) {
+	a.maxBodySize = maxBodySize
+}
+
+// maxBodySizeFor returns the body size limit for a route.
+func (a *App) maxBodySizeFor(route *Route) int64 {
+	maxBodySize := a.maxBodySize
+	if route != nil && route.MaxBodySize > 0 {
+		maxBodySize = route.MaxBodySize
+	}
+	if maxBodySize <= 0 || maxBodySize > PostBodySizeMax {
+		return PostBodySizeMax
+	}
+	return maxBodySize
+}
+
+// limitBody limits the request body to a number of bytes.
+func (rc *Ctx) limitBody(maxBodySize int64) {
+	if rc.Request == nil {
+		return
+	}
+	body := &limitedBody{remaining: maxBodySize}
+	if rc.Request.ContentLength > maxBodySize {
+		body.exceeded = true
+	}
+	if rc.Request.Body != nil && rc.Request.Body != http.NoBody {
+		body.ReadCloser = rc.Request.Body
+		rc.Request.Body = body
+	}
+	rc.body = body
+}
+
+// IsBodyTooLarge returns if the request body is over the body size limit, either by its content length or by what has been read.
+func (rc *Ctx) IsBodyTooLarge() bool {
+	return rc.body != nil && rc.body.exceeded
+}
+
+// limitedBody is a request body that fails with `ErrRequestBodyTooLarge` once more than the limit has been read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+// Read reads from the body, up to the limit.
+func (lb *limitedBody) Read(contents []byte) (int, error) {
+	if lb.exceeded {
+		return 0, ErrRequestBodyTooLarge
+	}
+	if int64(len(contents)) > lb.remaining+1 {
+		contents = contents[:lb.remaining+1]
+	}
+	read, err := lb.ReadCloser.Read(contents)
+	if int64(read) <= lb.remaining {
+		lb.remaining -= int64(read)
+		return read, err
+	}
+	read = int(lb.remaining)
+	lb.remaining = 0
+	lb.exceeded = true
+	return read, ErrRequestBodyTooLarge
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestAppMaxBodySize(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	assert.Equal(PostBodySizeMax, app.maxBodySizeFor(nil))
+	app.SetMaxBodySize(16)
+	assert.Equal(16, app.MaxBodySize())
+	assert.Equal(16, app.maxBodySizeFor(&Route{}))
+	assert.Equal(32, app.maxBodySizeFor((&Route{}).WithMaxBodySize(32)))
+	app.SetMaxBodySize(PostBodySizeMax + 1)
+	assert.Equal(PostBodySizeMax, app.maxBodySizeFor(nil))
+}
+
+func TestBodyLimitContentLength(t *testing.T) {
+	assert := assert.New(t)
+
+	var ran bool
+	app := New()
+	app.SetMaxBodySize(4)
+	app.POST("/", func(r *Ctx) Result {
+		ran = true
+		return r.Text().Result("ok")
+	})
+	app.POST("/large", func(r *Ctx) Result {
+		body, err := r.PostBodyAsString()
+		assert.Nil(err)
+		return r.Text().Result(body)
+	}).WithMaxBodySize(16)
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("POST", "/", strings.NewReader("too large")))
+	assert.Equal(http.StatusRequestEntityTooLarge, res.Code)
+	assert.False(ran)
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("POST", "/large", strings.NewReader("too large")))
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("too large", res.Body.String())
+}
+
+func TestBodyLimitStreamed(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetMaxBodySize(4)
+	app.POST("/", func(r *Ctx) Result {
+		_, err := r.PostBody()
+		assert.Equal(ErrRequestBodyTooLarge, err)
+		return r.Text().BadRequest(err.Error())
+	}, APIProviderAsDefault)
+
+	// no content length, so the limit is only hit while reading.
+	req := httptest.NewRequest("POST", "/", ioutil.NopCloser(bytes.NewBufferString("too large")))
+	req.ContentLength = -1
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(http.StatusRequestEntityTooLarge, res.Code)
+	assert.Contains("\"StatusCode\":413", res.Body.String())
+}
+
+func TestLimitedBody(t *testing.T) {
+	assert := assert.New(t)
+
+	body := &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("abcd")), remaining: 4}
+	contents, err := ioutil.ReadAll(body)
+	assert.Nil(err)
+	assert.Equal("abcd", string(contents))
+	assert.False(body.exceeded)
+
+	body = &limitedBody{ReadCloser: ioutil.NopCloser(strings.NewReader("abcde")), remaining: 4}
+	contents, err = ioutil.ReadAll(body)
+	assert.Equal(ErrRequestBodyTooLarge, err)
+	assert.Equal("abcd", string(contents))
+	assert.True(body.exceeded)
+}
@@ -1,8 +1,9 @@
 package web
 
 import (
+	"bufio"
 	"bytes"
-	"compress/gzip"
+	"net"
 	"net/http"
 )
 
@@ -11,46 +12,72 @@ import (
 // --------------------------------------------------------------------------------
 
 // NewCompressedResponseWriter returns a new gzipped response writer.
+// It always compresses; use `Compression.NewResponseWriter` to apply size and content type thresholds.
 func NewCompressedResponseWriter(w http.ResponseWriter) ResponseWriter {
 	return &CompressedResponseWriter{
 		innerResponse: w,
+		encoding:      ContentEncodingGZIP,
+		encoder:       GZIPEncoder,
 	}
 }
 
-// NewBufferedCompressedResponseWriter returns a new gzipped response writer.
+// NewBufferedCompressedResponseWriter returns a new gzipped response writer that retains the uncompressed response.
 func NewBufferedCompressedResponseWriter(w http.ResponseWriter) ResponseWriter {
 	return &CompressedResponseWriter{
 		innerResponse:  w,
+		encoding:       ContentEncodingGZIP,
+		encoder:        GZIPEncoder,
 		responseBuffer: bytes.NewBuffer([]byte{}),
 	}
 }
 
 // CompressedResponseWriter is a response writer that compresses output.
+// If it has a compression config, writes are held until the body reaches the minimum size
+// (or the response completes) to decide if the response should be compressed at all.
 type CompressedResponseWriter struct {
-	gzipWriter     *gzip.Writer
 	innerResponse  http.ResponseWriter
+	encoding       string
+	encoder        CompressionEncoder
+	compression    *Compression
+	compressor     CompressionWriter
+	pending        *bytes.Buffer
 	responseBuffer *bytes.Buffer
 	statusCode     int
 	contentLength  int
-}
-
-func (crw *CompressedResponseWriter) ensureCompressedStream() {
-	if crw.gzipWriter == nil {
-		crw.gzipWriter = gzip.NewWriter(crw.innerResponse)
-	}
+	wroteHeader    bool
+	decided        bool
+	streaming      bool
+	hijacked       bool
 }
 
 // Write writes the byes to the stream.
 func (crw *CompressedResponseWriter) Write(b []byte) (int, error) {
-	if crw.responseBuffer == nil {
-		crw.ensureCompressedStream()
-		written, err := crw.gzipWriter.Write(b)
-		crw.contentLength += written
-		return written, err
+	if crw.hijacked {
+		return 0, http.ErrHijacked
+	}
+	if !crw.wroteHeader {
+		crw.WriteHeader(http.StatusOK)
+	}
+	crw.contentLength += len(b)
+	if crw.responseBuffer != nil {
+		retainResponseBytes(crw.responseBuffer, b)
+	}
+
+	if !crw.decided {
+		if crw.pending == nil {
+			crw.pending = bytes.NewBuffer([]byte{})
+		}
+		crw.pending.Write(b)
+		if crw.pending.Len() >= crw.minSize() {
+			return len(b), crw.decide(true)
+		}
+		return len(b), nil
+	}
+
+	if crw.compressor != nil {
+		return crw.compressor.Write(b)
 	}
-	written, err := crw.responseBuffer.Write(b)
-	crw.contentLength += written
-	return written, err
+	return crw.innerResponse.Write(b)
 }
 
 // Header returns the headers for the response.
@@ -59,9 +86,19 @@ func (crw *CompressedResponseWriter) Header() http.Header {
 }
 
 // WriteHeader writes a status code.
+// The status is held until it is known if the response will be compressed.
 func (crw *CompressedResponseWriter) WriteHeader(code int) {
+	if crw.wroteHeader || crw.hijacked {
+		return
+	}
+	crw.wroteHeader = true
 	crw.statusCode = code
-	crw.innerResponse.WriteHeader(code)
+
+	if !crw.isEligible() {
+		crw.decide(false)
+	} else if crw.minSize() <= 0 {
+		crw.decide(true)
+	}
 }
 
 // InnerResponse returns the backing http response.
@@ -69,12 +106,22 @@ func (crw *CompressedResponseWriter) InnerResponse() http.ResponseWriter {
 	return crw.innerResponse
 }
 
+// Encoding returns the content encoding the writer compresses with.
+func (crw *CompressedResponseWriter) Encoding() string {
+	return crw.encoding
+}
+
+// IsCompressed returns if the response is being compressed.
+func (crw *CompressedResponseWriter) IsCompressed() bool {
+	return crw.compressor != nil
+}
+
 // StatusCode returns the status code for the request.
 func (crw *CompressedResponseWriter) StatusCode() int {
 	return crw.statusCode
 }
 
-// ContentLength returns the content length for the request.
+// ContentLength returns the (uncompressed) content length for the request.
 func (crw *CompressedResponseWriter) ContentLength() int {
 	return crw.contentLength
 }
@@ -87,26 +134,125 @@ func (crw *CompressedResponseWriter) Bytes() []byte {
 	return crw.responseBuffer.Bytes()
 }
 
-// Flush pushes any buffered data out to the response.
-func (crw *CompressedResponseWriter) Flush() error {
-	crw.ensureCompressedStream()
-	if crw.responseBuffer != nil {
-		written, err := crw.gzipWriter.Write(crw.responseBuffer.Bytes())
-		crw.contentLength = written
-		if err != nil {
+// Flush implements `http.Flusher`; see `FlushError`.
+func (crw *CompressedResponseWriter) Flush() {
+	crw.FlushError()
+}
+
+// FlushError pushes any buffered data out to the response, and on to the client if the inner response supports it.
+// If the body has not reached the minimum size by the first flush, it is sent uncompressed, unless the response is a stream.
+func (crw *CompressedResponseWriter) FlushError() error {
+	if !crw.wroteHeader || crw.hijacked {
+		return nil
+	}
+	if !crw.decided {
+		if err := crw.decide(crw.streaming || (crw.pending != nil && crw.pending.Len() >= crw.minSize())); err != nil {
+			return err
+		}
+	}
+	if crw.compressor != nil {
+		if err := crw.compressor.Flush(); err != nil {
 			return err
 		}
 	}
-	return crw.gzipWriter.Flush()
+	flushInnerResponse(crw.innerResponse)
+	return nil
+}
+
+// Hijack takes over the underlying connection, if the inner response supports it and nothing has been written.
+func (crw *CompressedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if crw.wroteHeader {
+		return nil, nil, ErrHijackAfterWrite
+	}
+	conn, buffered, err := hijackInnerResponse(crw.innerResponse)
+	if err == nil {
+		crw.hijacked = true
+		crw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, buffered, err
+}
+
+// disableCompression stops the response from being compressed, if it has not been decided yet.
+func (crw *CompressedResponseWriter) disableCompression() {
+	if !crw.decided {
+		crw.encoder = nil
+	}
+}
+
+// stream marks the response as a stream of unknown length, so it is compressed (if eligible) regardless of size.
+func (crw *CompressedResponseWriter) stream() {
+	crw.streaming = true
 }
 
 // Close closes any underlying resources.
 func (crw *CompressedResponseWriter) Close() error {
+	err := crw.FlushError()
 	crw.responseBuffer = nil
-	if crw.gzipWriter != nil {
-		err := crw.gzipWriter.Close()
-		crw.gzipWriter = nil
-		return err
+	if crw.compressor != nil {
+		closeErr := crw.compressor.Close()
+		releaseCompressionWriter(crw.compressor)
+		crw.compressor = nil
+		if err == nil {
+			err = closeErr
+		}
 	}
-	return nil
+	return err
+}
+
+// reset clears the writer so it can be reused for another response.
+func (crw *CompressedResponseWriter) reset() {
+	*crw = CompressedResponseWriter{}
+}
+
+func (crw *CompressedResponseWriter) minSize() int {
+	if crw.compression == nil {
+		return 0
+	}
+	return crw.compression.MinSize()
+}
+
+func (crw *CompressedResponseWriter) isEligible() bool {
+	if crw.encoder == nil {
+		return false
+	}
+	if crw.compression == nil {
+		return true
+	}
+	return crw.compression.ShouldCompress(crw.Header(), crw.statusCode)
+}
+
+// decide writes the held status code and any pending bytes, compressed or not.
+func (crw *CompressedResponseWriter) decide(compress bool) error {
+	crw.decided = true
+	header := crw.Header()
+	if compress && crw.compression != nil && crw.pending != nil && len(header.Get(HeaderContentType)) == 0 {
+		// sniff from the uncompressed bytes; the server would otherwise sniff the compressed ones.
+		header.Set(HeaderContentType, http.DetectContentType(crw.pending.Bytes()))
+	}
+	if compress && !crw.isEligible() {
+		compress = false
+	}
+
+	if compress {
+		compressor, err := crw.encoder(crw.innerResponse)
+		if err != nil {
+			return err
+		}
+		crw.compressor = compressor
+		header.Set(HeaderContentEncoding, crw.encoding)
+		header.Del(HeaderContentLength)
+	}
+	crw.innerResponse.WriteHeader(crw.statusCode)
+
+	if crw.pending == nil || crw.pending.Len() == 0 {
+		return nil
+	}
+	var err error
+	if crw.compressor != nil {
+		_, err = crw.compressor.Write(crw.pending.Bytes())
+	} else {
+		_, err = crw.innerResponse.Write(crw.pending.Bytes())
+	}
+	crw.pending = nil
+	return err
 }
@@ -0,0 +1,275 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultCompressionMinSize is the default size in bytes a response body must reach before it is compressed.
+	DefaultCompressionMinSize = 1024
+)
+
+var (
+	// DefaultCompressionPreference is the default order encodings are preferred in when the client accepts them equally.
+	DefaultCompressionPreference = []string{ContentEncodingGZIP, ContentEncodingDeflate}
+
+	// DefaultCompressionExcludedContentTypes are content types that are already compressed.
+	// Entries ending in `/` match any subtype.
+	DefaultCompressionExcludedContentTypes = []string{
+		"image/png",
+		"image/jpeg",
+		"image/gif",
+		"image/webp",
+		"video/",
+		"audio/",
+		"font/woff",
+		"font/woff2",
+		"application/zip",
+		"application/gzip",
+		"application/x-gzip",
+		"application/x-bzip2",
+		"application/x-xz",
+		"application/x-7z-compressed",
+		"application/x-rar-compressed",
+	}
+)
+
+var (
+	gzipWriterPool = sync.Pool{
+		New: func() interface{} {
+			return gzip.NewWriter(nil)
+		},
+	}
+	zlibWriterPool = sync.Pool{
+		New: func() interface{} {
+			return zlib.NewWriter(nil)
+		},
+	}
+)
+
+// CompressionWriter is a stream that compresses what is written to it.
+type CompressionWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// CompressionEncoder returns a compression stream for an encoding that writes to a given writer.
+type CompressionEncoder func(w io.Writer) (CompressionWriter, error)
+
+// GZIPEncoder is the gzip compression encoder. Writers are pooled, and returned to the pool by the response writer on close.
+func GZIPEncoder(w io.Writer) (CompressionWriter, error) {
+	gzipWriter := gzipWriterPool.Get().(*gzip.Writer)
+	gzipWriter.Reset(w)
+	return gzipWriter, nil
+}
+
+// DeflateEncoder is the deflate compression encoder. Writers are pooled, and returned to the pool by the response writer on close.
+// The http `deflate` encoding is the zlib format, not a raw deflate stream.
+func DeflateEncoder(w io.Writer) (CompressionWriter, error) {
+	zlibWriter := zlibWriterPool.Get().(*zlib.Writer)
+	zlibWriter.Reset(w)
+	return zlibWriter, nil
+}
+
+// releaseCompressionWriter returns a closed compression writer to its pool.
+func releaseCompressionWriter(compressor CompressionWriter) {
+	switch typed := compressor.(type) {
+	case *gzip.Writer:
+		typed.Reset(nil)
+		gzipWriterPool.Put(typed)
+	case *zlib.Writer:
+		typed.Reset(nil)
+		zlibWriterPool.Put(typed)
+	}
+}
+
+// NewCompression returns a new compression config with the gzip and deflate encoders registered.
+// There is no brotli encoder in the standard library; to serve `br`, register an encoder for it with `RegisterEncoder`
+// and list it in `SetPreference`.
+func NewCompression() *Compression {
+	return &Compression{
+		encoders: map[string]CompressionEncoder{
+			ContentEncodingGZIP:    GZIPEncoder,
+			ContentEncodingDeflate: DeflateEncoder,
+		},
+		preference:           append([]string{}, DefaultCompressionPreference...),
+		minSize:              DefaultCompressionMinSize,
+		excludedContentTypes: append([]string{}, DefaultCompressionExcludedContentTypes...),
+	}
+}
+
+// Compression negotiates and configures response compression.
+type Compression struct {
+	encoders             map[string]CompressionEncoder
+	preference           []string
+	minSize              int
+	excludedContentTypes []string
+}
+
+// RegisterEncoder registers an encoder for a content encoding.
+// Encodings not in the preference list are preferred last.
+func (c *Compression) RegisterEncoder(encoding string, encoder CompressionEncoder) {
+	encoding = strings.ToLower(encoding)
+	c.encoders[encoding] = encoder
+	for _, preferred := range c.preference {
+		if preferred == encoding {
+			return
+		}
+	}
+	c.preference = append(c.preference, encoding)
+}
+
+// Encoder returns the encoder for a content encoding, if registered.
+func (c *Compression) Encoder(encoding string) (CompressionEncoder, bool) {
+	encoder, hasEncoder := c.encoders[encoding]
+	return encoder, hasEncoder
+}
+
+// Preference returns the order encodings are preferred in.
+func (c *Compression) Preference() []string {
+	return c.preference
+}
+
+// SetPreference sets the order encodings are preferred in.
+func (c *Compression) SetPreference(encodings ...string) {
+	c.preference = encodings
+}
+
+// MinSize returns the size in bytes a response body must reach before it is compressed.
+func (c *Compression) MinSize() int {
+	return c.minSize
+}
+
+// SetMinSize sets the size in bytes a response body must reach before it is compressed.
+func (c *Compression) SetMinSize(minSize int) {
+	c.minSize = minSize
+}
+
+// ExcludedContentTypes returns the content types that are not compressed.
+func (c *Compression) ExcludedContentTypes() []string {
+	return c.excludedContentTypes
+}
+
+// SetExcludedContentTypes sets the content types that are not compressed.
+// Entries ending in `/` match any subtype.
+func (c *Compression) SetExcludedContentTypes(contentTypes ...string) {
+	c.excludedContentTypes = contentTypes
+}
+
+// Negotiate returns the registered encoding the client most prefers, by `Accept-Encoding` q-value
+// and then by preference order. It returns an empty string if the response should not be encoded.
+func (c *Compression) Negotiate(acceptEncoding string) string {
+	if len(strings.TrimSpace(acceptEncoding)) == 0 {
+		return ""
+	}
+
+	qualities := parseAcceptEncoding(acceptEncoding)
+	wildcard, hasWildcard := qualities["*"]
+
+	var best string
+	var bestQuality float64
+	for _, encoding := range c.preference {
+		if _, hasEncoder := c.encoders[encoding]; !hasEncoder {
+			continue
+		}
+		quality, hasQuality := qualities[encoding]
+		if !hasQuality && hasWildcard {
+			quality = wildcard
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// ShouldCompress returns if a response with the given headers and status code is eligible for compression.
+// Responses without bodies, partial responses, responses that are already encoded, excluded content types
+// and responses with a known length below the minimum size are not compressed.
+func (c *Compression) ShouldCompress(header http.Header, statusCode int) bool {
+	if statusCode < http.StatusOK || statusCode == http.StatusNoContent || statusCode == http.StatusNotModified || statusCode == http.StatusPartialContent {
+		return false
+	}
+	if len(header.Get(HeaderContentEncoding)) > 0 || len(header.Get(HeaderContentRange)) > 0 {
+		return false
+	}
+	if contentLength := header.Get(HeaderContentLength); len(contentLength) > 0 {
+		if parsed, err := strconv.Atoi(contentLength); err == nil && parsed < c.minSize {
+			return false
+		}
+	}
+	return !c.IsExcludedContentType(header.Get(HeaderContentType))
+}
+
+// IsExcludedContentType returns if a content type should not be compressed.
+func (c *Compression) IsExcludedContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(contentType))
+	if index := strings.Index(mediaType, ";"); index >= 0 {
+		mediaType = strings.TrimSpace(mediaType[:index])
+	}
+	if len(mediaType) == 0 {
+		return false
+	}
+	for _, excluded := range c.excludedContentTypes {
+		if strings.HasSuffix(excluded, "/") {
+			if strings.HasPrefix(mediaType, excluded) {
+				return true
+			}
+		} else if mediaType == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// NewResponseWriter returns a response writer that compresses with the given encoding if the response is eligible.
+func (c *Compression) NewResponseWriter(w http.ResponseWriter, encoding string) *CompressedResponseWriter {
+	encoder, _ := c.Encoder(encoding)
+	return &CompressedResponseWriter{
+		innerResponse: w,
+		encoding:      encoding,
+		encoder:       encoder,
+		compression:   c,
+	}
+}
+
+// NewBufferedResponseWriter returns a compressing response writer that also retains the uncompressed response.
+func (c *Compression) NewBufferedResponseWriter(w http.ResponseWriter, encoding string) *CompressedResponseWriter {
+	crw := c.NewResponseWriter(w, encoding)
+	crw.responseBuffer = bytes.NewBuffer([]byte{})
+	return crw
+}
+
+// parseAcceptEncoding parses an `Accept-Encoding` header into q-values by encoding.
+func parseAcceptEncoding(header string) map[string]float64 {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		pieces := strings.Split(part, ";")
+		encoding := strings.ToLower(strings.TrimSpace(pieces[0]))
+		if len(encoding) == 0 {
+			continue
+		}
+		if encoding == "x-gzip" {
+			encoding = ContentEncodingGZIP
+		}
+
+		quality := 1.0
+		for _, param := range pieces[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") || strings.HasPrefix(param, "Q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		qualities[encoding] = quality
+	}
+	return qualities
+}
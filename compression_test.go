@@ -0,0 +1,193 @@
+package web
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestCompressionNegotiate(t *testing.T) {
+	assert := assert.New(t)
+
+	compression := NewCompression()
+	assert.Empty(compression.Negotiate(""))
+	assert.Equal(ContentEncodingGZIP, compression.Negotiate("gzip"))
+	assert.Equal(ContentEncodingGZIP, compression.Negotiate("x-gzip"))
+	assert.Equal(ContentEncodingGZIP, compression.Negotiate("br, gzip, deflate"), "brotli should not be chosen without an encoder")
+	assert.Equal([]string{ContentEncodingGZIP, ContentEncodingDeflate}, compression.Preference())
+	assert.Equal(ContentEncodingDeflate, compression.Negotiate("gzip;q=0.5, deflate"))
+	assert.Equal(ContentEncodingGZIP, compression.Negotiate("*"))
+	assert.Equal(ContentEncodingDeflate, compression.Negotiate("gzip;q=0, *;q=0.1"))
+	assert.Empty(compression.Negotiate("identity"))
+	assert.Empty(compression.Negotiate("gzip;q=0"))
+
+	compression.RegisterEncoder(compressionTestEncoding, compressionTestEncoder)
+	assert.Equal(ContentEncodingGZIP, compression.Negotiate("gzip, "+compressionTestEncoding), "registered encodings are preferred last")
+	compression.SetPreference(compressionTestEncoding, ContentEncodingGZIP, ContentEncodingDeflate)
+	assert.Equal(compressionTestEncoding, compression.Negotiate("gzip, "+compressionTestEncoding))
+	assert.Equal(ContentEncodingGZIP, compression.Negotiate("br, gzip"))
+}
+
+// compressionTestEncoding is a made up encoding, so the test encoder is not mistaken for a real one.
+const compressionTestEncoding = "x-test"
+
+// compressionTestEncoder passes contents through unchanged.
+func compressionTestEncoder(w io.Writer) (CompressionWriter, error) {
+	return compressionTestWriter{w}, nil
+}
+
+type compressionTestWriter struct {
+	io.Writer
+}
+
+func (compressionTestWriter) Flush() error { return nil }
+func (compressionTestWriter) Close() error { return nil }
+
+func TestCompressionShouldCompress(t *testing.T) {
+	assert := assert.New(t)
+
+	compression := NewCompression()
+	assert.True(compression.ShouldCompress(http.Header{HeaderContentType: {ContentTypeHTML}}, http.StatusOK))
+	assert.False(compression.ShouldCompress(http.Header{HeaderContentType: {"image/jpeg"}}, http.StatusOK))
+	assert.False(compression.ShouldCompress(http.Header{HeaderContentType: {"video/mp4"}}, http.StatusOK))
+	assert.True(compression.ShouldCompress(http.Header{HeaderContentType: {"image/svg+xml"}}, http.StatusOK))
+	assert.False(compression.ShouldCompress(http.Header{}, http.StatusNoContent))
+	assert.False(compression.ShouldCompress(http.Header{}, http.StatusNotModified))
+	assert.False(compression.ShouldCompress(http.Header{HeaderContentEncoding: {ContentEncodingGZIP}}, http.StatusOK))
+	assert.False(compression.ShouldCompress(http.Header{HeaderContentLength: {"10"}}, http.StatusOK))
+}
+
+func TestAppCompression(t *testing.T) {
+	assert := assert.New(t)
+
+	large := strings.Repeat("hello world ", 200)
+	app := New()
+	app.GET("/large", func(ctx *Ctx) Result {
+		return ctx.Text().Result(large)
+	})
+	app.GET("/small", func(ctx *Ctx) Result {
+		return ctx.Text().Result("hello")
+	})
+	app.GET("/image", func(ctx *Ctx) Result {
+		return &RawResult{ContentType: "image/png", Body: []byte(large)}
+	})
+	app.GET("/empty", func(ctx *Ctx) Result {
+		return ctx.NoContent()
+	})
+
+	serve := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(HeaderAcceptEncoding, acceptEncoding)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+	decode := func(encoding string, body io.Reader) string {
+		var reader io.Reader
+		var err error
+		if encoding == ContentEncodingGZIP {
+			reader, err = gzip.NewReader(body)
+		} else {
+			reader, err = zlib.NewReader(body)
+		}
+		assert.Nil(err)
+		contents, err := ioutil.ReadAll(reader)
+		assert.Nil(err)
+		return string(contents)
+	}
+
+	res := serve("/large", "gzip")
+	assert.Equal(ContentEncodingGZIP, res.Header().Get(HeaderContentEncoding))
+	assert.Equal(HeaderAcceptEncoding, res.Header().Get(HeaderVary))
+	assert.Equal(ContentTypeText, res.Header().Get(HeaderContentType))
+	assert.Equal(large, decode(ContentEncodingGZIP, res.Body))
+
+	res = serve("/large", "gzip;q=0.2, deflate;q=0.8")
+	assert.Equal(ContentEncodingDeflate, res.Header().Get(HeaderContentEncoding))
+	assert.Equal(large, decode(ContentEncodingDeflate, res.Body))
+
+	res = serve("/small", "gzip")
+	assert.Empty(res.Header().Get(HeaderContentEncoding))
+	assert.Equal(HeaderAcceptEncoding, res.Header().Get(HeaderVary))
+	assert.Equal("hello", res.Body.String())
+
+	res = serve("/image", "gzip")
+	assert.Empty(res.Header().Get(HeaderContentEncoding))
+	assert.Equal(large, res.Body.String())
+
+	res = serve("/empty", "gzip")
+	assert.Equal(http.StatusNoContent, res.Code)
+	assert.Empty(res.Header().Get(HeaderContentEncoding))
+
+	res = serve("/large", "")
+	assert.Empty(res.Header().Get(HeaderContentEncoding))
+	assert.Equal(large, res.Body.String())
+}
+
+func TestAppCompressionHead(t *testing.T) {
+	assert := assert.New(t)
+
+	large := strings.Repeat("hello world ", 200)
+	app := New()
+	app.GET("/large", func(ctx *Ctx) Result {
+		return ctx.Text().Result(large)
+	})
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	serve := func(method string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+"/large", nil)
+		assert.Nil(err)
+		req.Header.Set(HeaderAcceptEncoding, ContentEncodingGZIP)
+		res, err := client.Do(req)
+		assert.Nil(err)
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		return res
+	}
+
+	get := serve("GET")
+	head := serve("HEAD")
+	assert.Equal(http.StatusOK, head.StatusCode)
+	assert.Equal(ContentEncodingGZIP, head.Header.Get(HeaderContentEncoding))
+	assert.Equal(get.Header.Get(HeaderContentEncoding), head.Header.Get(HeaderContentEncoding))
+	assert.Equal(get.Header.Get(HeaderVary), head.Header.Get(HeaderVary))
+	assert.Equal(get.Header.Get(HeaderContentLength), head.Header.Get(HeaderContentLength))
+
+	// uncompressed, HEAD reports the length GET would send.
+	req, err := http.NewRequest("HEAD", server.URL+"/large", nil)
+	assert.Nil(err)
+	head, err = client.Do(req)
+	assert.Nil(err)
+	head.Body.Close()
+	assert.Empty(head.Header.Get(HeaderContentEncoding))
+	assert.Equal(strconv.Itoa(len(large)), head.Header.Get(HeaderContentLength))
+}
+
+func TestCompressedResponseWriterBuffered(t *testing.T) {
+	assert := assert.New(t)
+
+	res := httptest.NewRecorder()
+	compression := NewCompression()
+	compression.SetMinSize(4)
+	crw := compression.NewBufferedResponseWriter(res, ContentEncodingGZIP)
+	crw.Write([]byte("hello "))
+	crw.Write([]byte("world"))
+	assert.Nil(crw.FlushError())
+	assert.Equal("hello world", string(crw.Bytes()))
+	assert.Nil(crw.Close())
+
+	assert.NotEqual("hello world", res.Body.String())
+	assert.Equal(11, crw.ContentLength())
+	assert.Equal(http.StatusOK, crw.StatusCode())
+	assert.Equal(ContentEncodingGZIP, res.Header().Get(HeaderContentEncoding))
+}
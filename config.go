@@ -27,7 +27,20 @@ type Initialized interface {
 }
 
 // ReadConfigFromEnvironment reads a config from the environment.
+// The config is checked against its `validate` struct tags with the `DefaultValidator` before it is initialized;
+// use `App.ReadConfigFromEnvironment` to use the rules registered on an app.
 func ReadConfigFromEnvironment(reference interface{}) (interface{}, error) {
+	return ReadConfigFromEnvironmentWithValidator(reference, DefaultValidator)
+}
+
+// ReadConfigFromEnvironment reads a config from the environment, checking it with the app validator.
+func (a *App) ReadConfigFromEnvironment(reference interface{}) (interface{}, error) {
+	return ReadConfigFromEnvironmentWithValidator(reference, a.validator)
+}
+
+// ReadConfigFromEnvironmentWithValidator reads a config from the environment, checking it with a given validator
+// (if set) before it is initialized.
+func ReadConfigFromEnvironmentWithValidator(reference interface{}, validator *Validator) (interface{}, error) {
 	objectMeta := reflectType(reference)
 
 	var field reflect.StructField
@@ -58,6 +71,12 @@ func ReadConfigFromEnvironment(reference interface{}) (interface{}, error) {
 		}
 	}
 
+	if validator != nil {
+		if err = validator.Validate(reference); err != nil {
+			return reference, err
+		}
+	}
+
 	if typed, isTyped := reference.(Initialized); isTyped {
 		return typed, typed.Initialize()
 	}
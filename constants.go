@@ -4,6 +4,10 @@ const (
 	// PackageName is the full name of this package.
 	PackageName = "github.com/blendlabs/go-web"
 
+	// HeaderAccept is the "Accept" header.
+	// It indicates what content types the request will accept responses as, with optional quality (q) values.
+	HeaderAccept = "Accept"
+
 	// HeaderAcceptEncoding is the "Accept-Encoding" header.
 	// It indicates what types of encodings the request will accept responses as.
 	// It typically enables or disables compressed (gzipped) responses.
@@ -31,6 +35,10 @@ const (
 	// Typical values are "gzip", "deflate", "compress", "br", and "identity" indicating no compression.
 	HeaderContentEncoding = "Content-Encoding"
 
+	// HeaderContentRange is the "Content-Range" header.
+	// It indicates where in a full body a partial response belongs.
+	HeaderContentRange = "Content-Range"
+
 	// HeaderContentLength is the "Content-Length" header.
 	// If provided, it specifies the size of the request or response.
 	HeaderContentLength = "Content-Length"
@@ -39,6 +47,18 @@ const (
 	// It specifies the MIME-type of the request or response.
 	HeaderContentType = "Content-Type"
 
+	// HeaderForwarded is the "Forwarded" header.
+	// It is set by proxies with the client address, protocol and host (RFC 7239).
+	HeaderForwarded = "Forwarded"
+
+	// HeaderLastEventID is the "Last-Event-ID" header.
+	// It is sent by clients reconnecting to an event stream with the id of the last event they received.
+	HeaderLastEventID = "Last-Event-ID"
+
+	// HeaderRetryAfter is the "Retry-After" header.
+	// It indicates how many seconds the client should wait before making another request.
+	HeaderRetryAfter = "Retry-After"
+
 	// HeaderServer is the "Server" header.
 	// It is an informational header to tell the client what server software was used.
 	HeaderServer = "Server"
@@ -47,10 +67,38 @@ const (
 	// It is used to indicate what fields should be used by the client as cache keys.
 	HeaderVary = "Vary"
 
+	// HeaderXRequestID is the "X-Request-Id" header.
+	// It carries an id that correlates a request across services and log lines.
+	HeaderXRequestID = "X-Request-Id"
+
+	// HeaderTraceparent is the W3C "traceparent" header.
+	// It carries the trace id, parent span id and trace flags of a request.
+	HeaderTraceparent = "traceparent"
+
+	// HeaderTracestate is the W3C "tracestate" header.
+	// It carries vendor specific trace state alongside "traceparent".
+	HeaderTracestate = "tracestate"
+
 	// HeaderXServedBy is the "X-Served-By" header.
 	// It is an informational header that indicates what software was used to generate the response.
 	HeaderXServedBy = "X-Served-By"
 
+	// HeaderXForwardedFor is the "X-Forwarded-For" header.
+	// It is set by proxies with the list of client and proxy addresses a request passed through.
+	HeaderXForwardedFor = "X-Forwarded-For"
+
+	// HeaderXForwardedHost is the "X-Forwarded-Host" header.
+	// It is set by proxies with the host the client requested.
+	HeaderXForwardedHost = "X-Forwarded-Host"
+
+	// HeaderXForwardedProto is the "X-Forwarded-Proto" header.
+	// It is set by proxies with the protocol the client used.
+	HeaderXForwardedProto = "X-Forwarded-Proto"
+
+	// HeaderXRealIP is the "X-Real-Ip" header.
+	// It is set by some proxies with the client address.
+	HeaderXRealIP = "X-Real-Ip"
+
 	// HeaderXFrameOptions is the "X-Frame-Options" header.
 	// It indicates if a browser is allowed to render the response in a <frame> element or not.
 	HeaderXFrameOptions = "X-Frame-Options"
@@ -63,6 +111,18 @@ const (
 	// HeaderXContentTypeOptions is the "X-Content-Type-Options" header.
 	HeaderXContentTypeOptions = "X-Content-Type-Options"
 
+	// HeaderXRateLimitLimit is the "X-RateLimit-Limit" header.
+	// It is the number of requests allowed by a rate limiter.
+	HeaderXRateLimitLimit = "X-RateLimit-Limit"
+
+	// HeaderXRateLimitRemaining is the "X-RateLimit-Remaining" header.
+	// It is the number of requests remaining before the rate limiter rejects requests.
+	HeaderXRateLimitRemaining = "X-RateLimit-Remaining"
+
+	// HeaderXRateLimitReset is the "X-RateLimit-Reset" header.
+	// It is the unix time, in seconds, when the rate limiter allows the full limit again.
+	HeaderXRateLimitReset = "X-RateLimit-Reset"
+
 	// ContentTypeApplicationJSON is a content type for JSON responses.
 	// We specify chartset=utf-8 so that clients know to use the UTF-8 string encoding.
 	ContentTypeApplicationJSON = "application/json; charset=UTF-8"
@@ -79,6 +139,25 @@ const (
 	// We specify chartset=utf-8 so that clients know to use the UTF-8 string encoding.
 	ContentTypeText = "text/plain; charset=utf-8"
 
+	// ContentTypeEventStream is a content type for server-sent event streams.
+	ContentTypeEventStream = "text/event-stream"
+
+	// ContentTypeProblemJSON is a content type for RFC 7807 problem details responses.
+	ContentTypeProblemJSON = "application/problem+json; charset=utf-8"
+
+	// MediaTypeJSON is the media type for JSON, used in content negotiation.
+	MediaTypeJSON = "application/json"
+	// MediaTypeHTML is the media type for html, used in content negotiation.
+	MediaTypeHTML = "text/html"
+	// MediaTypeXML is the media type for XML, used in content negotiation.
+	MediaTypeXML = "application/xml"
+	// MediaTypeTextXML is the alternate media type for XML, used in content negotiation.
+	MediaTypeTextXML = "text/xml"
+	// MediaTypeProblemJSON is the media type for problem details, used in content negotiation.
+	MediaTypeProblemJSON = "application/problem+json"
+	// MediaTypeText is the media type for plain text, used in content negotiation.
+	MediaTypeText = "text/plain"
+
 	// ConnectionKeepAlive is a value for the "Connection" header and
 	// indicates the server should keep the tcp connection open
 	// after the last byte of the response is sent.
@@ -88,4 +167,8 @@ const (
 	ContentEncodingIdentity = "identity"
 	// ContentEncodingGZIP is the gzip (compressed) content encoding.
 	ContentEncodingGZIP = "gzip"
+	// ContentEncodingDeflate is the deflate (zlib compressed) content encoding.
+	ContentEncodingDeflate = "deflate"
+	// ContentEncodingBrotli is the brotli (compressed) content encoding.
+	ContentEncodingBrotli = "br"
 )
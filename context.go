@@ -0,0 +1,108 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"time"
+)
+
+// contextKey is the type of the keys this package stores in request contexts.
+type contextKey int
+
+const (
+	contextKeyTx contextKey = iota
+)
+
+// WithContextTx returns a copy of a context that carries a transaction.
+func WithContextTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, contextKeyTx, tx)
+}
+
+// ContextTx returns the transaction carried by a context, if any.
+func ContextTx(ctx context.Context) *sql.Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(contextKeyTx).(*sql.Tx)
+	return tx
+}
+
+// Context returns the request context.
+// The server cancels it when the client disconnects or the request completes, so it should be passed
+// to database calls (i.e. `QueryContext`) and other work that should stop when the client goes away.
+// The ctx keeps it once it is released to the app ctx pool, but work that outlives the action
+// should take the context before it starts rather than hold on to the ctx, which is reused.
+func (rc *Ctx) Context() context.Context {
+	if rc.requestContext != nil {
+		return rc.requestContext
+	}
+	if rc.Request != nil {
+		return rc.Request.Context()
+	}
+	return context.Background()
+}
+
+// WithContext replaces the request context.
+func (rc *Ctx) WithContext(ctx context.Context) *Ctx {
+	rc.requestContext = ctx
+	if rc.Request != nil {
+		rc.Request = rc.Request.WithContext(ctx)
+	}
+	return rc
+}
+
+// WithValue adds a request scoped value to the request context.
+func (rc *Ctx) WithValue(key, value interface{}) *Ctx {
+	return rc.WithContext(context.WithValue(rc.Context(), key, value))
+}
+
+// WithTimeout sets a deadline on the request context, a timeout from now.
+// The cancel func should be called to release resources once the work it bounds is done.
+func (rc *Ctx) WithTimeout(timeout time.Duration) context.CancelFunc {
+	ctx, cancel := context.WithTimeout(rc.Context(), timeout)
+	rc.WithContext(ctx)
+	return cancel
+}
+
+// WithDeadline sets a deadline on the request context.
+// The cancel func should be called to release resources once the work it bounds is done.
+func (rc *Ctx) WithDeadline(deadline time.Time) context.CancelFunc {
+	ctx, cancel := context.WithDeadline(rc.Context(), deadline)
+	rc.WithContext(ctx)
+	return cancel
+}
+
+// Deadline implements context.Context with the request context.
+func (rc *Ctx) Deadline() (time.Time, bool) {
+	return rc.Context().Deadline()
+}
+
+// Done implements context.Context with the request context.
+func (rc *Ctx) Done() <-chan struct{} {
+	return rc.Context().Done()
+}
+
+// Err implements context.Context with the request context.
+func (rc *Ctx) Err() error {
+	return rc.Context().Err()
+}
+
+// Value implements context.Context with the request context.
+func (rc *Ctx) Value(key interface{}) interface{} {
+	return rc.Context().Value(key)
+}
+
+// contextWriter is a writer that fails once its context is done, which stops template execution.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+// Write writes to the inner writer if the context is not done.
+func (cw contextWriter) Write(contents []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(contents)
+}
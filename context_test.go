@@ -0,0 +1,115 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"html/template"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type contextTestKey string
+
+func TestCtxImplementsContext(t *testing.T) {
+	assert := assert.New(t)
+
+	var _ context.Context = &Ctx{}
+
+	ctx := NewCtx(NewMockResponseWriter(nil), httptest.NewRequest("GET", "/", nil), nil)
+	assert.Nil(ctx.Err())
+	_, hasDeadline := ctx.Deadline()
+	assert.False(hasDeadline)
+
+	ctx.WithValue(contextTestKey("user"), "bailey")
+	assert.Equal("bailey", ctx.Value(contextTestKey("user")))
+	assert.Equal("bailey", ctx.Request.Context().Value(contextTestKey("user")))
+
+	cancel := ctx.WithTimeout(time.Minute)
+	deadline, hasDeadline := ctx.Deadline()
+	assert.True(hasDeadline)
+	assert.True(deadline.After(time.Now()))
+	cancel()
+	<-ctx.Done()
+	assert.Equal(context.Canceled, ctx.Err())
+
+	assert.NotNil(NewCtx(nil, nil, nil).Context())
+}
+
+func TestCtxContextAfterRelease(t *testing.T) {
+	assert := assert.New(t)
+
+	start := make(chan struct{})
+	stopped := make(chan error, 1)
+	app := New()
+	app.SetCtxPool(NewCtxPool())
+	app.GET("/", func(r *Ctx) Result {
+		go func() {
+			<-start
+			<-r.Done()
+			stopped <- r.Err()
+		}()
+		return r.Text().Result("ok")
+	})
+
+	requestContext, cancel := context.WithCancel(context.Background())
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(requestContext))
+	cancel()
+	close(start)
+
+	select {
+	case err := <-stopped:
+		assert.Equal(context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("a released ctx should still report its request as done")
+	}
+}
+
+func TestCtxTxFromContext(t *testing.T) {
+	assert := assert.New(t)
+
+	tx := &sql.Tx{}
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(WithContextTx(req.Context(), tx))
+
+	ctx := NewCtx(NewMockResponseWriter(nil), req, nil)
+	assert.True(ctx.Tx() == tx)
+	assert.True(ContextTx(ctx) == tx)
+
+	other := &sql.Tx{}
+	assert.True(ctx.WithTx(other).Tx() == other)
+	assert.Nil(ContextTx(context.Background()))
+}
+
+func TestViewResultStopsWhenCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.ViewCache().SetTemplates(template.Must(template.New("").Parse(`{{ define "slow" }}{{ range .ViewModel }}{{ . }}{{ end }}{{ end }}`)))
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	app.GET("/", func(r *Ctx) Result {
+		items := make(chan int)
+		go func() {
+			defer close(items)
+			for index := 0; index < 1000; index++ {
+				if index == 10 {
+					// the client goes away part way through rendering.
+					cancel()
+				}
+				select {
+				case items <- index:
+				case <-reqCtx.Done():
+					return
+				}
+			}
+		}()
+		return r.View().View("slow", items)
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil).WithContext(reqCtx))
+	assert.Empty(res.Body.String())
+}
@@ -1,6 +1,13 @@
 package web
 
-// Controller is an interface for controller objects.
+// Controller is an interface for controller objects that register directly with the app.
+// Use `RegistrarController` for controllers that should also be mountable on a route group.
 type Controller interface {
 	Register(app *App)
 }
+
+// RegistrarController is an interface for controller objects that can be mounted
+// on either the app or a route group (e.g. under a versioned prefix).
+type RegistrarController interface {
+	Register(r Registrar)
+}
@@ -0,0 +1,212 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderOrigin is the "Origin" request header.
+	HeaderOrigin = "Origin"
+	// HeaderAccessControlRequestMethod is the "Access-Control-Request-Method" preflight request header.
+	HeaderAccessControlRequestMethod = "Access-Control-Request-Method"
+	// HeaderAccessControlRequestHeaders is the "Access-Control-Request-Headers" preflight request header.
+	HeaderAccessControlRequestHeaders = "Access-Control-Request-Headers"
+	// HeaderAccessControlAllowOrigin is the "Access-Control-Allow-Origin" response header.
+	HeaderAccessControlAllowOrigin = "Access-Control-Allow-Origin"
+	// HeaderAccessControlAllowMethods is the "Access-Control-Allow-Methods" response header.
+	HeaderAccessControlAllowMethods = "Access-Control-Allow-Methods"
+	// HeaderAccessControlAllowHeaders is the "Access-Control-Allow-Headers" response header.
+	HeaderAccessControlAllowHeaders = "Access-Control-Allow-Headers"
+	// HeaderAccessControlAllowCredentials is the "Access-Control-Allow-Credentials" response header.
+	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	// HeaderAccessControlExposeHeaders is the "Access-Control-Expose-Headers" response header.
+	HeaderAccessControlExposeHeaders = "Access-Control-Expose-Headers"
+	// HeaderAccessControlMaxAge is the "Access-Control-Max-Age" response header.
+	HeaderAccessControlMaxAge = "Access-Control-Max-Age"
+)
+
+// NewCORS returns a new CORS config that allows the given origins.
+// Origins can be exact (`https://example.com`), contain a wildcard (`https://*.example.com`) or be `*` for any origin.
+func NewCORS(allowedOrigins ...string) *CORS {
+	return &CORS{AllowedOrigins: allowedOrigins}
+}
+
+// CORS is a cross-origin resource sharing config, set app wide with `App.SetCORS` or per route with `Route.WithCORS`.
+type CORS struct {
+	// AllowedOrigins are the origins allowed to make requests.
+	AllowedOrigins []string
+	// AllowOriginFunc, if set, is checked for origins not matched by `AllowedOrigins`.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods are the methods allowed in preflight requests. If empty, the methods routed for the path are allowed.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in preflight requests. If empty, the requested headers are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts are allowed to read.
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and auth headers to be sent.
+	AllowCredentials bool
+	// MaxAge is how long preflight responses can be cached for.
+	MaxAge time.Duration
+}
+
+// WithAllowOriginFunc sets the func used to allow origins not matched by `AllowedOrigins`.
+func (c *CORS) WithAllowOriginFunc(allowOrigin func(origin string) bool) *CORS {
+	c.AllowOriginFunc = allowOrigin
+	return c
+}
+
+// WithAllowedMethods sets the methods allowed in preflight requests.
+func (c *CORS) WithAllowedMethods(methods ...string) *CORS {
+	c.AllowedMethods = methods
+	return c
+}
+
+// WithAllowedHeaders sets the request headers allowed in preflight requests.
+func (c *CORS) WithAllowedHeaders(headers ...string) *CORS {
+	c.AllowedHeaders = headers
+	return c
+}
+
+// WithExposedHeaders sets the response headers scripts are allowed to read.
+func (c *CORS) WithExposedHeaders(headers ...string) *CORS {
+	c.ExposedHeaders = headers
+	return c
+}
+
+// WithAllowCredentials sets if cookies and auth headers can be sent.
+func (c *CORS) WithAllowCredentials(allowCredentials bool) *CORS {
+	c.AllowCredentials = allowCredentials
+	return c
+}
+
+// WithMaxAge sets how long preflight responses can be cached for.
+func (c *CORS) WithMaxAge(maxAge time.Duration) *CORS {
+	c.MaxAge = maxAge
+	return c
+}
+
+// IsOriginAllowed returns if an origin is allowed.
+func (c *CORS) IsOriginAllowed(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if matchesOriginPattern(allowed, origin) {
+			return true
+		}
+	}
+	return c.AllowOriginFunc != nil && c.AllowOriginFunc(origin)
+}
+
+// ApplyHeaders sets the CORS headers for an actual (non-preflight) request.
+// It returns false if the request has no origin or the origin is not allowed.
+func (c *CORS) ApplyHeaders(header http.Header, r *http.Request) bool {
+	origin := r.Header.Get(HeaderOrigin)
+	addVaryHeader(header, HeaderOrigin)
+	if len(origin) == 0 || !c.IsOriginAllowed(origin) {
+		return false
+	}
+
+	c.setAllowOrigin(header, origin)
+	if len(c.ExposedHeaders) > 0 {
+		header.Set(HeaderAccessControlExposeHeaders, strings.Join(c.ExposedHeaders, ", "))
+	}
+	return true
+}
+
+// ServePreflight answers a preflight request; `allowed` is the list of methods routed for the path.
+// Disallowed preflight requests are answered with `403 Forbidden` and no CORS headers.
+func (c *CORS) ServePreflight(w http.ResponseWriter, r *http.Request, allowed string) {
+	header := w.Header()
+	addVaryHeader(header, HeaderOrigin)
+	addVaryHeader(header, HeaderAccessControlRequestMethod)
+	addVaryHeader(header, HeaderAccessControlRequestHeaders)
+
+	origin := r.Header.Get(HeaderOrigin)
+	method := strings.ToUpper(r.Header.Get(HeaderAccessControlRequestMethod))
+	methods := c.AllowedMethods
+	if len(methods) == 0 {
+		methods = splitHeaderList(allowed)
+	}
+	requestedHeaders := headerTokens(r.Header, HeaderAccessControlRequestHeaders)
+
+	if !c.IsOriginAllowed(origin) || !containsFold(methods, method) || !c.areHeadersAllowed(requestedHeaders) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	c.setAllowOrigin(header, origin)
+	header.Set(HeaderAccessControlAllowMethods, strings.Join(methods, ", "))
+	if len(requestedHeaders) > 0 {
+		if len(c.AllowedHeaders) > 0 {
+			header.Set(HeaderAccessControlAllowHeaders, strings.Join(c.AllowedHeaders, ", "))
+		} else {
+			header.Set(HeaderAccessControlAllowHeaders, strings.Join(requestedHeaders, ", "))
+		}
+	}
+	if c.MaxAge > 0 {
+		header.Set(HeaderAccessControlMaxAge, strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *CORS) setAllowOrigin(header http.Header, origin string) {
+	if c.AllowCredentials {
+		header.Set(HeaderAccessControlAllowOrigin, origin)
+		header.Set(HeaderAccessControlAllowCredentials, "true")
+	} else if containsFold(c.AllowedOrigins, "*") {
+		header.Set(HeaderAccessControlAllowOrigin, "*")
+	} else {
+		header.Set(HeaderAccessControlAllowOrigin, origin)
+	}
+}
+
+func (c *CORS) areHeadersAllowed(requested []string) bool {
+	if len(c.AllowedHeaders) == 0 {
+		return true
+	}
+	for _, header := range requested {
+		if !containsFold(c.AllowedHeaders, header) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPreflightRequest returns if a request is a CORS preflight request.
+func isPreflightRequest(r *http.Request) bool {
+	return r.Method == "OPTIONS" && len(r.Header.Get(HeaderOrigin)) > 0 && len(r.Header.Get(HeaderAccessControlRequestMethod)) > 0
+}
+
+// matchesOriginPattern returns if an origin matches an allowed origin, which may contain a single `*` wildcard.
+func matchesOriginPattern(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+	index := strings.Index(pattern, "*")
+	if index < 0 {
+		return strings.EqualFold(pattern, origin)
+	}
+	prefix, suffix := strings.ToLower(pattern[:index]), strings.ToLower(pattern[index+1:])
+	origin = strings.ToLower(origin)
+	return len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+func splitHeaderList(value string) []string {
+	var values []string
+	for _, piece := range strings.Split(value, ",") {
+		if piece = strings.TrimSpace(piece); len(piece) > 0 {
+			values = append(values, piece)
+		}
+	}
+	return values
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
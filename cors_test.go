@@ -0,0 +1,153 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func corsTestRequest(app *App, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	return res
+}
+
+func TestCORSIsOriginAllowed(t *testing.T) {
+	assert := assert.New(t)
+
+	cors := NewCORS("https://example.com", "https://*.example.org")
+	assert.True(cors.IsOriginAllowed("https://example.com"))
+	assert.True(cors.IsOriginAllowed("https://EXAMPLE.com"))
+	assert.True(cors.IsOriginAllowed("https://api.example.org"))
+	assert.False(cors.IsOriginAllowed("https://example.org"))
+	assert.False(cors.IsOriginAllowed("https://example.com.evil.net"))
+	assert.False(cors.IsOriginAllowed("http://api.example.org"))
+
+	cors.WithAllowOriginFunc(func(origin string) bool { return strings.HasSuffix(origin, ".test") })
+	assert.True(cors.IsOriginAllowed("http://local.test"))
+
+	assert.True(NewCORS("*").IsOriginAllowed("https://anything.net"))
+}
+
+func TestAppCORSPreflight(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetCORS(NewCORS("https://example.com").WithAllowCredentials(true).WithMaxAge(time.Hour))
+	app.GET("/widgets", func(r *Ctx) Result { return r.Text().Result("ok") })
+	app.POST("/widgets", func(r *Ctx) Result { return r.Text().Result("ok") })
+
+	res := corsTestRequest(app, "OPTIONS", "/widgets", map[string]string{
+		HeaderOrigin:                      "https://example.com",
+		HeaderAccessControlRequestMethod:  "POST",
+		HeaderAccessControlRequestHeaders: "X-Custom, Content-Type",
+	})
+	assert.Equal(http.StatusNoContent, res.Code)
+	assert.Equal("https://example.com", res.Header().Get(HeaderAccessControlAllowOrigin))
+	assert.Equal("true", res.Header().Get(HeaderAccessControlAllowCredentials))
+	assert.Equal("3600", res.Header().Get(HeaderAccessControlMaxAge))
+	assert.Equal("X-Custom, Content-Type", res.Header().Get(HeaderAccessControlAllowHeaders))
+	methods := res.Header().Get(HeaderAccessControlAllowMethods)
+	assert.Contains("GET", methods)
+	assert.Contains("HEAD", methods)
+	assert.Contains("POST", methods)
+	assert.Contains("OPTIONS", methods)
+	assert.Contains(HeaderOrigin, strings.Join(res.Header()[HeaderVary], ", "))
+
+	res = corsTestRequest(app, "OPTIONS", "/widgets", map[string]string{
+		HeaderOrigin:                     "https://example.com",
+		HeaderAccessControlRequestMethod: "DELETE",
+	})
+	assert.Equal(http.StatusForbidden, res.Code)
+	assert.Empty(res.Header().Get(HeaderAccessControlAllowOrigin))
+
+	res = corsTestRequest(app, "OPTIONS", "/widgets", map[string]string{
+		HeaderOrigin:                     "https://evil.net",
+		HeaderAccessControlRequestMethod: "GET",
+	})
+	assert.Equal(http.StatusForbidden, res.Code)
+	assert.Empty(res.Header().Get(HeaderAccessControlAllowOrigin))
+
+	res = corsTestRequest(app, "OPTIONS", "/missing", map[string]string{
+		HeaderOrigin:                     "https://example.com",
+		HeaderAccessControlRequestMethod: "GET",
+	})
+	assert.Equal(http.StatusNotFound, res.Code)
+}
+
+func TestAppCORSActualRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetCORS(NewCORS("*").WithExposedHeaders("X-Total"))
+	app.GET("/widgets", func(r *Ctx) Result { return r.Text().Result("ok") })
+
+	res := corsTestRequest(app, "GET", "/widgets", map[string]string{HeaderOrigin: "https://example.com"})
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("*", res.Header().Get(HeaderAccessControlAllowOrigin))
+	assert.Equal("X-Total", res.Header().Get(HeaderAccessControlExposeHeaders))
+
+	res = corsTestRequest(app, "GET", "/widgets", nil)
+	assert.Empty(res.Header().Get(HeaderAccessControlAllowOrigin))
+}
+
+func TestAppCORSPerRoute(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetCORS(NewCORS("https://example.com"))
+	app.GET("/public", func(r *Ctx) Result { return r.Text().Result("ok") }).
+		WithCORS(NewCORS("*").WithAllowedMethods("GET").WithAllowedHeaders("X-Custom"))
+	app.GET("/private", func(r *Ctx) Result { return r.Text().Result("ok") })
+
+	res := corsTestRequest(app, "OPTIONS", "/public", map[string]string{
+		HeaderOrigin:                      "https://other.net",
+		HeaderAccessControlRequestMethod:  "GET",
+		HeaderAccessControlRequestHeaders: "x-custom",
+	})
+	assert.Equal(http.StatusNoContent, res.Code)
+	assert.Equal("*", res.Header().Get(HeaderAccessControlAllowOrigin))
+	assert.Equal("GET", res.Header().Get(HeaderAccessControlAllowMethods))
+	assert.Equal("X-Custom", res.Header().Get(HeaderAccessControlAllowHeaders))
+
+	res = corsTestRequest(app, "OPTIONS", "/public", map[string]string{
+		HeaderOrigin:                      "https://other.net",
+		HeaderAccessControlRequestMethod:  "GET",
+		HeaderAccessControlRequestHeaders: "X-Other",
+	})
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	res = corsTestRequest(app, "GET", "/private", map[string]string{HeaderOrigin: "https://other.net"})
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Empty(res.Header().Get(HeaderAccessControlAllowOrigin))
+}
+
+func TestAppHandleOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/widgets", func(r *Ctx) Result { return r.Text().Result("ok") })
+
+	assert.False(app.HandleOptions())
+	res := corsTestRequest(app, "OPTIONS", "/widgets", nil)
+	assert.Equal(http.StatusNotFound, res.Code)
+
+	app.SetHandleOptions(true)
+	assert.True(app.HandleOptions())
+	res = corsTestRequest(app, "OPTIONS", "/widgets", nil)
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("GET, HEAD, OPTIONS", res.Header().Get("Allow"))
+
+	app.SetHandleMethodNotAllowed(true)
+	assert.True(app.HandleMethodNotAllowed())
+	res = corsTestRequest(app, "DELETE", "/widgets", nil)
+	assert.Equal(http.StatusMethodNotAllowed, res.Code)
+}
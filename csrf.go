@@ -0,0 +1,263 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultCSRFFieldName is the default name of the form field that carries the csrf token.
+	DefaultCSRFFieldName = "csrf_token"
+
+	// DefaultCSRFHeaderName is the default name of the header that carries the csrf token.
+	DefaultCSRFHeaderName = "X-CSRF-Token"
+
+	// DefaultCSRFCookieName is the default name of the cookie that carries the double submit csrf token.
+	DefaultCSRFCookieName = "CSRF"
+
+	// SessionStateCSRFToken is the `Session.State` key the synchronizer csrf token is stored under.
+	SessionStateCSRFToken = "csrf_token"
+
+	// ctxStateCSRFToken is the `Ctx` state key a newly issued double submit token is kept under.
+	ctxStateCSRFToken = "csrf_token"
+)
+
+const (
+	// CSRFModeSynchronizer stores a random token in the session state.
+	CSRFModeSynchronizer CSRFMode = iota
+	// CSRFModeDoubleSubmit issues a token in a cookie signed with the auth manager secret, which the request must echo.
+	CSRFModeDoubleSubmit
+)
+
+const (
+	// ErrCSRFTokenMissing is returned when an unsafe request does not carry a csrf token.
+	ErrCSRFTokenMissing = Error("csrf token is missing")
+
+	// ErrCSRFTokenInvalid is returned when an unsafe request carries a csrf token that does not match.
+	ErrCSRFTokenInvalid = Error("csrf token is invalid")
+
+	// ErrCSRFNotConfigured is returned when a csrf token is requested without an auth manager csrf config.
+	ErrCSRFNotConfigured = Error("csrf is not configured on the auth manager")
+
+	// ErrCSRFSessionRequired is returned when a synchronizer csrf token is requested without a session.
+	ErrCSRFSessionRequired = Error("csrf synchronizer tokens require a session")
+
+	// ErrCSRFSecretRequired is returned when a double submit csrf token is requested without an auth manager secret.
+	ErrCSRFSecretRequired = Error("csrf double submit tokens require an auth manager secret")
+)
+
+// CSRFMode is how csrf tokens are issued and verified.
+type CSRFMode int
+
+// NewCSRF returns a new csrf config that uses synchronizer tokens.
+func NewCSRF() *CSRF {
+	return &CSRF{
+		mode:       CSRFModeSynchronizer,
+		fieldName:  DefaultCSRFFieldName,
+		headerName: DefaultCSRFHeaderName,
+		cookieName: DefaultCSRFCookieName,
+	}
+}
+
+// CSRF issues and verifies csrf tokens for an auth manager's sessions.
+type CSRF struct {
+	mode        CSRFMode
+	fieldName   string
+	headerName  string
+	cookieName  string
+	exemptPaths []string
+	exemptFunc  func(*Ctx) bool
+}
+
+// Mode returns the csrf mode.
+func (c *CSRF) Mode() CSRFMode {
+	return c.mode
+}
+
+// WithMode sets the csrf mode.
+func (c *CSRF) WithMode(mode CSRFMode) *CSRF {
+	c.mode = mode
+	return c
+}
+
+// FieldName returns the form field name the token is read from.
+func (c *CSRF) FieldName() string {
+	return c.fieldName
+}
+
+// WithFieldName sets the form field name the token is read from.
+func (c *CSRF) WithFieldName(fieldName string) *CSRF {
+	c.fieldName = fieldName
+	return c
+}
+
+// HeaderName returns the header name the token is read from.
+func (c *CSRF) HeaderName() string {
+	return c.headerName
+}
+
+// WithHeaderName sets the header name the token is read from.
+func (c *CSRF) WithHeaderName(headerName string) *CSRF {
+	c.headerName = headerName
+	return c
+}
+
+// CookieName returns the name of the double submit cookie.
+func (c *CSRF) CookieName() string {
+	return c.cookieName
+}
+
+// WithCookieName sets the name of the double submit cookie.
+func (c *CSRF) WithCookieName(cookieName string) *CSRF {
+	c.cookieName = cookieName
+	return c
+}
+
+// ExemptPaths returns the path prefixes that are not checked, typically api routes that use header auth.
+func (c *CSRF) ExemptPaths() []string {
+	return c.exemptPaths
+}
+
+// WithExemptPaths adds path prefixes that are not checked.
+func (c *CSRF) WithExemptPaths(pathPrefixes ...string) *CSRF {
+	c.exemptPaths = append(c.exemptPaths, pathPrefixes...)
+	return c
+}
+
+// WithExemptFunc sets a func that can exempt requests from being checked.
+func (c *CSRF) WithExemptFunc(exempt func(*Ctx) bool) *CSRF {
+	c.exemptFunc = exempt
+	return c
+}
+
+// IsExempt returns if a request is not checked.
+func (c *CSRF) IsExempt(context *Ctx) bool {
+	for _, prefix := range c.exemptPaths {
+		if strings.HasPrefix(context.Request.URL.Path, prefix) {
+			return true
+		}
+	}
+	return c.exemptFunc != nil && c.exemptFunc(context)
+}
+
+// Token returns the csrf token for a request, issuing one if the request does not have one yet.
+func (c *CSRF) Token(context *Ctx) (string, error) {
+	if c.mode == CSRFModeDoubleSubmit {
+		return c.doubleSubmitToken(context)
+	}
+	return c.synchronizerToken(context)
+}
+
+// Verify checks the token carried by a request in the csrf header or form field.
+// Multipart bodies are not parsed, so the action can still stream them with `Ctx.MultipartReader`;
+// multipart requests must carry the token in the header.
+func (c *CSRF) Verify(context *Ctx) error {
+	submitted := context.Request.Header.Get(c.headerName)
+	if len(submitted) == 0 && !isMultipartRequest(context.Request) {
+		submitted = context.Request.FormValue(c.fieldName)
+	}
+	if len(submitted) == 0 {
+		return ErrCSRFTokenMissing
+	}
+
+	var expected string
+	if c.mode == CSRFModeDoubleSubmit {
+		cookie := context.GetCookie(c.cookieName)
+		if cookie == nil || !c.isSigned(context, cookie.Value) {
+			return ErrCSRFTokenInvalid
+		}
+		expected = cookie.Value
+	} else {
+		session := context.Session()
+		if session == nil {
+			return ErrCSRFTokenInvalid
+		}
+		session.csrfLock.Lock()
+		expected, _ = session.State[SessionStateCSRFToken].(string)
+		session.csrfLock.Unlock()
+	}
+
+	if len(expected) == 0 || subtle.ConstantTimeCompare([]byte(expected), []byte(submitted)) != 1 {
+		return ErrCSRFTokenInvalid
+	}
+	return nil
+}
+
+func (c *CSRF) synchronizerToken(context *Ctx) (string, error) {
+	session := context.Session()
+	if session == nil {
+		return "", ErrCSRFSessionRequired
+	}
+
+	session.csrfLock.Lock()
+	if token, hasToken := session.State[SessionStateCSRFToken].(string); hasToken && len(token) > 0 {
+		session.csrfLock.Unlock()
+		return token, nil
+	}
+	token := String.SecureRandom(32)
+	if session.State == nil {
+		session.State = map[string]interface{}{}
+	}
+	session.State[SessionStateCSRFToken] = token
+	session.csrfLock.Unlock()
+
+	// the token is in the session, so the store write does not need to hold the lock.
+	if auth := context.Auth(); auth != nil && auth.SessionStore() != nil {
+		if err := auth.SessionStore().Put(session); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+func (c *CSRF) doubleSubmitToken(context *Ctx) (string, error) {
+	auth := context.Auth()
+	if auth == nil || len(auth.Secret()) == 0 {
+		return "", ErrCSRFSecretRequired
+	}
+	if cookie := context.GetCookie(c.cookieName); cookie != nil && c.isSigned(context, cookie.Value) {
+		return cookie.Value, nil
+	}
+	if token, hasToken := context.State(ctxStateCSRFToken).(string); hasToken {
+		return token, nil
+	}
+
+	nonce := String.SecureRandom(32)
+	token := nonce + "." + c.sign(auth.Secret(), nonce, auth.readSessionID(context))
+	context.WriteNewCookie(c.cookieName, token, nil, DefaultSessionCookiePath, auth.isCookieSecure(context))
+	// the request cookie is not updated, so keep the issued token for the rest of the request.
+	context.SetState(ctxStateCSRFToken, token)
+	return token, nil
+}
+
+// isSigned returns if a double submit token was signed with the auth manager secret for the request's session.
+func (c *CSRF) isSigned(context *Ctx, token string) bool {
+	auth := context.Auth()
+	if auth == nil || len(auth.Secret()) == 0 {
+		return false
+	}
+	pieces := strings.SplitN(token, ".", 2)
+	if len(pieces) != 2 {
+		return false
+	}
+	expected := c.sign(auth.Secret(), pieces[0], auth.readSessionID(context))
+	return hmac.Equal([]byte(expected), []byte(pieces[1]))
+}
+
+// isMultipartRequest returns if a request has a multipart body.
+func isMultipartRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get(HeaderContentType))
+	return err == nil && strings.HasPrefix(mediaType, "multipart/")
+}
+
+// sign binds a double submit nonce to a session id, so a token issued for one session is not valid for another.
+func (c *CSRF) sign(secret []byte, nonce, sessionID string) string {
+	mac := hmac.New(sha512.New, secret)
+	mac.Write([]byte(nonce + "." + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,224 @@
+package web
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func newCSRFTestApp() *App {
+	app := New()
+	app.GET("/form", func(r *Ctx) Result {
+		token, err := r.CSRFToken()
+		if err != nil {
+			return r.Text().InternalError(err)
+		}
+		return r.Text().Result(token)
+	}, SessionAware)
+	app.POST("/submit", func(r *Ctx) Result {
+		return r.Text().Result("ok")
+	}, CSRFProtected, SessionAware)
+	app.POST("/api/submit", func(r *Ctx) Result {
+		return r.Text().Result("ok")
+	}, CSRFProtected, SessionAware)
+	return app
+}
+
+func csrfTestRequest(app *App, method, path string, form url.Values, headers map[string]string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	if form != nil {
+		req.Header.Set(HeaderContentType, "application/x-www-form-urlencoded")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	return res
+}
+
+func TestCSRFSynchronizer(t *testing.T) {
+	assert := assert.New(t)
+
+	app := newCSRFTestApp()
+	sessionID := NewSessionID()
+	app.Auth().SessionStore().Put(&Session{UserID: 1, SessionID: sessionID})
+	sessionHeader := map[string]string{app.Auth().SessionParamName(): sessionID}
+
+	res := csrfTestRequest(app, "GET", "/form", nil, sessionHeader)
+	assert.Equal(http.StatusOK, res.Code)
+	token := res.Body.String()
+	assert.NotEmpty(token)
+
+	res = csrfTestRequest(app, "GET", "/form", nil, sessionHeader)
+	assert.Equal(token, res.Body.String(), "the token should be stable for the session")
+
+	res = csrfTestRequest(app, "POST", "/submit", url.Values{DefaultCSRFFieldName: {token}}, sessionHeader)
+	assert.Equal(http.StatusOK, res.Code)
+
+	sessionHeader[DefaultCSRFHeaderName] = token
+	res = csrfTestRequest(app, "POST", "/submit", nil, sessionHeader)
+	assert.Equal(http.StatusOK, res.Code)
+
+	sessionHeader[DefaultCSRFHeaderName] = "not-the-token"
+	res = csrfTestRequest(app, "POST", "/submit", nil, sessionHeader)
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	delete(sessionHeader, DefaultCSRFHeaderName)
+	res = csrfTestRequest(app, "POST", "/submit", nil, sessionHeader)
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	res = csrfTestRequest(app, "GET", "/form", nil, nil)
+	assert.Equal(http.StatusInternalServerError, res.Code)
+}
+
+func TestCSRFSynchronizerConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	app := newCSRFTestApp()
+	session := NewSession(1, NewSessionID())
+	assert.Nil(app.Auth().SessionStore().Put(session))
+
+	tokens := make(chan string, 8)
+	wg := sync.WaitGroup{}
+	for index := 0; index < cap(tokens); index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, err := app.Mock().Ctx(nil)
+			assert.Nil(err)
+			ctx.SetSession(session)
+			token, err := app.Auth().CSRF().Token(ctx)
+			assert.Nil(err)
+			tokens <- token
+		}()
+	}
+	wg.Wait()
+	close(tokens)
+
+	expected := session.State[SessionStateCSRFToken]
+	assert.NotNil(expected)
+	for token := range tokens {
+		assert.Equal(expected, token, "concurrent requests should share one token")
+	}
+}
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	assert := assert.New(t)
+
+	app := newCSRFTestApp()
+	app.Auth().SetSecret(GenerateSHA512Key())
+	app.Auth().CSRF().WithMode(CSRFModeDoubleSubmit)
+
+	res := csrfTestRequest(app, "GET", "/form", nil, nil)
+	assert.Equal(http.StatusOK, res.Code)
+	token := res.Body.String()
+	cookies := (&http.Response{Header: res.Header()}).Cookies()
+	assert.Len(cookies, 1)
+	assert.Equal(DefaultCSRFCookieName, cookies[0].Name)
+	assert.Equal(token, cookies[0].Value)
+
+	res = csrfTestRequest(app, "POST", "/submit", url.Values{DefaultCSRFFieldName: {token}}, nil, cookies[0])
+	assert.Equal(http.StatusOK, res.Code)
+
+	res = csrfTestRequest(app, "POST", "/submit", url.Values{DefaultCSRFFieldName: {token}}, nil)
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	forged := &http.Cookie{Name: DefaultCSRFCookieName, Value: "nonce.signature"}
+	res = csrfTestRequest(app, "POST", "/submit", url.Values{DefaultCSRFFieldName: {forged.Value}}, nil, forged)
+	assert.Equal(http.StatusForbidden, res.Code)
+
+	// a token issued without a session is not valid once the request carries one.
+	res = csrfTestRequest(app, "POST", "/submit", url.Values{DefaultCSRFFieldName: {token}}, nil, cookies[0], &http.Cookie{Name: app.Auth().SessionParamName(), Value: NewSessionID()})
+	assert.Equal(http.StatusForbidden, res.Code)
+}
+
+func TestCSRFMultipart(t *testing.T) {
+	assert := assert.New(t)
+
+	app := newCSRFTestApp()
+	sessionID := NewSessionID()
+	app.Auth().SessionStore().Put(&Session{UserID: 1, SessionID: sessionID})
+	app.POST("/upload", func(r *Ctx) Result {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return r.Text().BadRequest(err.Error())
+		}
+		defer reader.Close()
+		part, err := reader.NextPart()
+		if err != nil {
+			return r.Text().BadRequest(err.Error())
+		}
+		return r.Text().Result(part.FormName())
+	}, CSRFProtected, SessionAware)
+
+	res := csrfTestRequest(app, "GET", "/form", nil, map[string]string{app.Auth().SessionParamName(): sessionID})
+	token := res.Body.String()
+
+	upload := func(headers map[string]string) *httptest.ResponseRecorder {
+		body := bytes.NewBuffer(nil)
+		writer := multipart.NewWriter(body)
+		writer.WriteField(DefaultCSRFFieldName, token)
+		writer.WriteField("file", "contents")
+		writer.Close()
+
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.Header.Set(HeaderContentType, writer.FormDataContentType())
+		req.Header.Set(app.Auth().SessionParamName(), sessionID)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	res = upload(nil)
+	assert.Equal(http.StatusForbidden, res.Code, "multipart bodies should not be parsed for the token")
+
+	res = upload(map[string]string{DefaultCSRFHeaderName: token})
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal(DefaultCSRFFieldName, res.Body.String(), "the body should still be readable as a stream")
+}
+
+func TestCSRFExemptPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	app := newCSRFTestApp()
+	app.Auth().CSRF().WithExemptPaths("/api/")
+
+	res := csrfTestRequest(app, "POST", "/api/submit", nil, nil)
+	assert.Equal(http.StatusOK, res.Code)
+
+	res = csrfTestRequest(app, "POST", "/submit", nil, nil)
+	assert.Equal(http.StatusForbidden, res.Code)
+}
+
+func TestCSRFViewFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.Auth().SetSecret(GenerateSHA512Key())
+	app.Auth().CSRF().WithMode(CSRFModeDoubleSubmit)
+	_, err := app.ViewCache().Templates().New("form").Funcs(app.ViewCache().FuncMap()).Parse(`{{ csrf_token .Ctx }}`)
+	assert.Nil(err)
+	app.GET("/", func(r *Ctx) Result {
+		return r.View().View("form", nil)
+	})
+
+	res := csrfTestRequest(app, "GET", "/", nil, nil)
+	assert.Equal(http.StatusOK, res.Code)
+	cookies := (&http.Response{Header: res.Header()}).Cookies()
+	assert.Len(cookies, 1)
+	assert.Equal(cookies[0].Value, res.Body.String())
+}
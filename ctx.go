@@ -1,17 +1,21 @@
 package web
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	"strings"
 
+	exception "github.com/blendlabs/go-exception"
 	logger "github.com/blendlabs/go-logger"
 )
 
@@ -34,6 +38,9 @@ func NewCtx(w ResponseWriter, r *http.Request, p RouteParameters) *Ctx {
 		routeParameters: p,
 		state:           State{},
 	}
+	if r != nil {
+		ctx.requestContext = r.Context()
+	}
 
 	return ctx
 }
@@ -49,6 +56,7 @@ type Ctx struct {
 	auth   *AuthManager
 
 	postBody []byte
+	body     *limitedBody
 
 	//Private fields
 	view                  *ViewResultProvider
@@ -56,6 +64,8 @@ type Ctx struct {
 	json                  *JSONResultProvider
 	xml                   *XMLResultProvider
 	text                  *TextResultProvider
+	negotiated            *NegotiatingResultProvider
+	problem               *ProblemResultProvider
 	defaultResultProvider ResultProvider
 
 	state            State
@@ -67,8 +77,16 @@ type Ctx struct {
 	requestEnd       time.Time
 	requestLogFormat string
 	session          *Session
+	requestID        string
+	requestContext   context.Context
+	span             *Span
+	requestSpan      *Span
+	onHijack         func()
 
 	tx *sql.Tx
+
+	retained bool
+	released bool
 }
 
 // WithTx sets a transaction on the context.
@@ -77,9 +95,12 @@ func (rc *Ctx) WithTx(tx *sql.Tx) *Ctx {
 	return rc
 }
 
-// Tx returns the transaction for the request.
+// Tx returns the transaction for the request, either set with `WithTx` or carried by the request context.
 func (rc *Ctx) Tx() *sql.Tx {
-	return rc.tx
+	if rc.tx != nil {
+		return rc.tx
+	}
+	return ContextTx(rc.Context())
 }
 
 // WithApp sets the app reference for the ctx.
@@ -90,9 +111,16 @@ func (rc *Ctx) WithApp(app *App) *Ctx {
 
 // App returns the app reference.
 func (rc *Ctx) App() *App {
+	rc.ensureNotReleased()
 	return rc.app
 }
 
+// RequestID returns the request id, taken from the incoming `X-Request-Id` header or generated.
+// It is echoed in the response headers and can be used to correlate log lines for the request.
+func (rc *Ctx) RequestID() string {
+	return rc.requestID
+}
+
 // Auth returns the AuthManager for the request.
 func (rc *Ctx) Auth() *AuthManager {
 	return rc.auth
@@ -103,8 +131,17 @@ func (rc *Ctx) SetAuth(authManager *AuthManager) {
 	rc.auth = authManager
 }
 
+// CSRFToken returns the csrf token for the request, issuing one if needed.
+func (rc *Ctx) CSRFToken() (string, error) {
+	if rc.auth == nil || rc.auth.CSRF() == nil {
+		return "", ErrCSRFNotConfigured
+	}
+	return rc.auth.CSRF().Token(rc)
+}
+
 // Session returns the session (if any) on the request.
 func (rc *Ctx) Session() *Session {
+	rc.ensureNotReleased()
 	if rc.session != nil {
 		return rc.session
 	}
@@ -157,6 +194,22 @@ func (rc *Ctx) Text() *TextResultProvider {
 	return rc.text
 }
 
+// Problem returns the RFC 7807 problem details result provider.
+func (rc *Ctx) Problem() *ProblemResultProvider {
+	if rc.problem == nil {
+		rc.problem = NewProblemResultProvider(rc)
+	}
+	return rc.problem
+}
+
+// Negotiated returns the result provider that picks a provider based on the request "Accept" header.
+func (rc *Ctx) Negotiated() *NegotiatingResultProvider {
+	if rc.negotiated == nil {
+		rc.negotiated = NewNegotiatingResultProvider(rc)
+	}
+	return rc.negotiated
+}
+
 // DefaultResultProvider returns the current result provider for the context. This is
 // set by calling SetDefaultResultProvider or using one of the pre-built middleware
 // steps that set it for you.
@@ -174,6 +227,7 @@ func (rc *Ctx) SetDefaultResultProvider(provider ResultProvider) {
 
 // State returns an object in the state cache.
 func (rc *Ctx) State(key string) interface{} {
+	rc.ensureNotReleased()
 	if item, hasItem := rc.state[key]; hasItem {
 		return item
 	}
@@ -182,11 +236,13 @@ func (rc *Ctx) State(key string) interface{} {
 
 // SetState sets the state for a key to an object.
 func (rc *Ctx) SetState(key string, value interface{}) {
+	rc.ensureNotReleased()
 	rc.state[key] = value
 }
 
 // Param returns a parameter from the request.
 func (rc *Ctx) Param(name string) string {
+	rc.ensureNotReleased()
 	if rc.routeParameters != nil {
 		routeValue := rc.routeParameters.Get(name)
 		if len(routeValue) > 0 {
@@ -269,12 +325,16 @@ func (rc *Ctx) ParamBool(name string) (bool, error) {
 
 func (rc *Ctx) onPostBody(bodyContents []byte) {
 	if rc.logger != nil {
-		rc.logger.OnEvent(logger.EventWebRequestPostBody, rc.postBody)
+		if rc.logger.IsEnabled(logger.EventWebRequestPostBody) {
+			rc.Retain()
+		}
+		rc.logger.OnEvent(logger.EventWebRequestPostBody, rc.postBody, rc)
 	}
 }
 
 // PostBody returns the bytes in a post body.
 func (rc *Ctx) PostBody() ([]byte, error) {
+	rc.ensureNotReleased()
 	var err error
 	if len(rc.postBody) == 0 {
 		defer rc.Request.Body.Close()
@@ -315,11 +375,15 @@ func (rc *Ctx) PostBodyAsXML(response interface{}) error {
 	return xml.Unmarshal(body, response)
 }
 
-// PostedFiles returns any files posted
+// PostedFiles returns any files posted, read fully into memory.
+// Use `MultipartReader` to stream large uploads instead.
 func (rc *Ctx) PostedFiles() ([]PostedFile, error) {
 	var files []PostedFile
 
 	err := rc.Request.ParseMultipartForm(PostBodySize)
+	if rc.IsBodyTooLarge() {
+		return nil, ErrRequestBodyTooLarge
+	}
 	if err == nil {
 		for key := range rc.Request.MultipartForm.File {
 			fileReader, fileHeader, err := rc.Request.FormFile(key)
@@ -379,6 +443,7 @@ func (rc *Ctx) RouteParamFloat64(key string) (float64, error) {
 
 // RouteParam returns a string route parameter
 func (rc *Ctx) RouteParam(key string) (string, error) {
+	rc.ensureNotReleased()
 	if value, hasKey := rc.routeParameters[key]; hasKey {
 		return value, nil
 	}
@@ -387,6 +452,7 @@ func (rc *Ctx) RouteParam(key string) (string, error) {
 
 // QueryParam returns a query parameter.
 func (rc *Ctx) QueryParam(key string) (string, error) {
+	rc.ensureNotReleased()
 	if value := rc.Request.URL.Query().Get(key); len(value) > 0 {
 		return value, nil
 	}
@@ -427,6 +493,7 @@ func (rc *Ctx) QueryParamTime(key, format string) (time.Time, error) {
 
 // HeaderParam returns a header parameter value.
 func (rc *Ctx) HeaderParam(key string) (string, error) {
+	rc.ensureNotReleased()
 	if value := rc.Request.Header.Get(key); len(value) > 0 {
 		return value, nil
 	}
@@ -481,9 +548,38 @@ func (rc *Ctx) WriteCookie(cookie *http.Cookie) {
 
 func (rc *Ctx) getCookieDomain() string {
 	if rc.app != nil && rc.app.baseURL != nil {
-		return rc.app.baseURL.Host
+		return hostWithoutPort(rc.app.baseURL.Host)
 	}
-	return rc.Request.Host
+	return hostWithoutPort(rc.Host())
+}
+
+// RemoteIP returns the client ip, honoring the `Forwarded` and `X-Forwarded-For` headers set by trusted proxies.
+func (rc *Ctx) RemoteIP() string {
+	return rc.app.resolveClient(rc.Request).IP
+}
+
+// Scheme returns the scheme the client used, `http` or `https`, honoring headers set by trusted proxies.
+func (rc *Ctx) Scheme() string {
+	return rc.app.resolveClient(rc.Request).Scheme
+}
+
+// Host returns the host the client requested, honoring headers set by trusted proxies.
+func (rc *Ctx) Host() string {
+	return rc.app.resolveClient(rc.Request).Host
+}
+
+// URL returns the url the client requested, with the scheme and host honoring headers set by trusted proxies.
+func (rc *Ctx) URL() *url.URL {
+	client := rc.app.resolveClient(rc.Request)
+	requestURL := *rc.Request.URL
+	requestURL.Scheme = client.Scheme
+	requestURL.Host = client.Host
+	return &requestURL
+}
+
+// IsSecure returns if the client used https.
+func (rc *Ctx) IsSecure() bool {
+	return rc.Scheme() == "https"
 }
 
 // WriteNewCookie is a helper method for WriteCookie.
@@ -502,27 +598,44 @@ func (rc *Ctx) WriteNewCookie(name string, value string, expires *time.Time, pat
 }
 
 // ExtendCookieByDuration extends a cookie by a time duration (on the order of nanoseconds to hours).
+// Request cookies do not carry their expiry, so the new expiry is relative to now.
 func (rc *Ctx) ExtendCookieByDuration(name string, path string, duration time.Duration) {
 	c := rc.GetCookie(name)
 	if c == nil {
 		return
 	}
-	c.Path = path
-	c.Domain = rc.getCookieDomain()
-	c.Expires = c.Expires.Add(duration)
+	rc.prepareExtendedCookie(c, path)
+	c.Expires = rc.cookieExpiresBase(c).Add(duration)
 	rc.WriteCookie(c)
 }
 
 // ExtendCookie extends a cookie by years, months or days.
+// Request cookies do not carry their expiry, so the new expiry is relative to now.
 func (rc *Ctx) ExtendCookie(name string, path string, years, months, days int) {
 	c := rc.GetCookie(name)
 	if c == nil {
 		return
 	}
+	rc.prepareExtendedCookie(c, path)
+	c.Expires = rc.cookieExpiresBase(c).AddDate(years, months, days)
+	rc.WriteCookie(c)
+}
+
+// prepareExtendedCookie restores the attributes `WriteNewCookie` sets, as they are not sent back on request cookies.
+func (rc *Ctx) prepareExtendedCookie(c *http.Cookie, path string) {
 	c.Path = path
 	c.Domain = rc.getCookieDomain()
-	c.Expires.AddDate(years, months, days)
-	rc.WriteCookie(c)
+	c.HttpOnly = true
+	if rc.auth != nil {
+		c.Secure = rc.auth.isCookieSecure(rc)
+	}
+}
+
+func (rc *Ctx) cookieExpiresBase(c *http.Cookie) time.Time {
+	if c.Expires.IsZero() {
+		return time.Now().UTC()
+	}
+	return c.Expires
 }
 
 // ExpireCookie expires a cookie.
@@ -538,6 +651,13 @@ func (rc *Ctx) ExpireCookie(name string, path string) {
 	rc.WriteCookie(c)
 }
 
+// hijacked tells the app the connection was hijacked, so the request is no longer counted as in flight.
+func (rc *Ctx) hijacked() {
+	if rc.onHijack != nil {
+		rc.onHijack()
+	}
+}
+
 // --------------------------------------------------------------------------------
 // Diagnostics
 // --------------------------------------------------------------------------------
@@ -548,9 +668,22 @@ func (rc *Ctx) Logger() *logger.Agent {
 }
 
 func (rc *Ctx) logFatal(err error) {
-	if rc.logger != nil {
-		rc.logger.ErrorEventWithState(logger.EventFatalError, logger.ColorRed, err, rc)
+	rc.logErrorEvent(logger.EventFatalError, logger.ColorRed, err)
+}
+
+func (rc *Ctx) logError(err error) {
+	rc.logErrorEvent(logger.EventError, logger.ColorRed, err)
+}
+
+// logErrorEvent fires an error event with the ctx as its state, so it is logged with the request id.
+func (rc *Ctx) logErrorEvent(event logger.EventFlag, color logger.AnsiColorCode, err error) {
+	if rc.logger == nil || err == nil {
+		return
+	}
+	if rc.logger.IsEnabled(event) {
+		rc.Retain()
 	}
+	rc.logger.ErrorEventWithState(event, color, err, rc)
 }
 
 // --------------------------------------------------------------------------------
@@ -589,6 +722,33 @@ func (rc *Ctx) NoContent() *NoContentResult {
 	return &NoContentResult{}
 }
 
+// Stream returns a result that writes the response incrementally with a stream func.
+// Call `Flush` on the stream writer to push what has been written to the client.
+func (rc *Ctx) Stream(stream StreamFunc) *StreamResult {
+	return &StreamResult{Stream: stream}
+}
+
+// StreamReader returns a result that copies the response from a reader, flushing as it goes.
+func (rc *Ctx) StreamReader(contentType string, reader io.Reader) *StreamResult {
+	return &StreamResult{ContentType: contentType, Reader: reader}
+}
+
+// SSE returns a server-sent events result that writes events from a channel until it is closed.
+// The ctx is retained, so the goroutine producing the events can stop on `Done()` once the client disconnects.
+// The result's `LastEventID` is set from the request, so a reconnecting client can be resumed with `Replay`.
+func (rc *Ctx) SSE(events <-chan SSEEvent) *SSEResult {
+	rc.Retain()
+	return &SSEResult{Events: events, LastEventID: rc.LastEventID()}
+}
+
+// LastEventID returns the id of the last server-sent event a reconnecting client received, if any.
+func (rc *Ctx) LastEventID() string {
+	if rc.Request == nil {
+		return ""
+	}
+	return rc.Request.Header.Get(HeaderLastEventID)
+}
+
 // Static returns a static result.
 func (rc *Ctx) Static(filePath string) *StaticResult {
 	return NewStaticResultForSingleFile(filePath)
@@ -608,6 +768,19 @@ func (rc *Ctx) Redirectf(format string, args ...interface{}) *RedirectResult {
 	}
 }
 
+// RedirectToRoute returns a redirect result to a named route.
+// If the route cannot be resolved it returns an internal error from the default result provider.
+func (rc *Ctx) RedirectToRoute(name string, params RouteParameters) Result {
+	if rc.app == nil {
+		return rc.DefaultResultProvider().InternalError(exception.New("ctx has no app reference"))
+	}
+	path, err := rc.app.URLFor(name, params)
+	if err != nil {
+		return rc.DefaultResultProvider().InternalError(err)
+	}
+	return rc.Redirect(path)
+}
+
 // RedirectWithMethodf returns a redirect result with a given method.
 func (rc *Ctx) RedirectWithMethodf(method, format string, args ...interface{}) *RedirectResult {
 	return &RedirectResult{
@@ -665,24 +838,41 @@ func (rc *Ctx) Elapsed() time.Duration {
 
 // Route returns the original route match for the request.
 func (rc *Ctx) Route() *Route {
+	rc.ensureNotReleased()
 	return rc.route
 }
 
-// Reset resets the context after handling a request.
+// Reset resets the context after handling a request so it can be reused.
+// The state map is cleared and kept to save an allocation.
 func (rc *Ctx) Reset() {
-	rc.app = nil
-	rc.Request = nil
-	rc.Response = nil
-	rc.postBody = nil
-	rc.route = nil
-	rc.routeParameters = nil
-	rc.session = nil
-	rc.state = nil
-	rc.statusCode = 0
-	rc.contentLength = 0
-	rc.requestStart = time.Time{}
-	rc.requestEnd = time.Time{}
-	rc.tx = nil
+	state := rc.state
+	for key := range state {
+		delete(state, key)
+	}
+	if state == nil {
+		state = State{}
+	}
+	// the request context is kept, so a released ctx still reports its request as done until it is reused.
+	*rc = Ctx{state: state, requestContext: rc.requestContext}
+}
+
+// Retain marks the ctx so that it is not released to the app ctx pool when the request completes.
+// Call it before handing the ctx to anything that may use it after the action returns, like a goroutine.
+func (rc *Ctx) Retain() {
+	rc.retained = true
+}
+
+// IsReleased returns if the ctx has been released back to the app ctx pool.
+// A released ctx must not be used; in safety mode, using one panics with `ErrCtxReleased`.
+func (rc *Ctx) IsReleased() bool {
+	return rc.released
+}
+
+// ensureNotReleased panics if the ctx is used after it was released in safety mode.
+func (rc *Ctx) ensureNotReleased() {
+	if rc.released {
+		panic(ErrCtxReleased)
+	}
 }
 
 // PostedFile is a file that has been posted to an hc endpoint.
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"net/http"
+	"sync"
+)
+
+const (
+	// ErrCtxReleased is the panic value when a ctx is used after it was released back to the app ctx pool.
+	ErrCtxReleased Error = "ctx used after release; do not retain a *Ctx beyond the request that created it"
+)
+
+// NewCtxPool returns a new ctx pool.
+func NewCtxPool() *CtxPool {
+	return &CtxPool{
+		pool: sync.Pool{
+			New: func() interface{} {
+				return &Ctx{state: State{}}
+			},
+		},
+	}
+}
+
+// CtxPool is a pool of reusable contexts.
+type CtxPool struct {
+	pool sync.Pool
+}
+
+// Get returns a reset ctx from the pool.
+func (cp *CtxPool) Get() *Ctx {
+	return cp.pool.Get().(*Ctx)
+}
+
+// Put resets a ctx and returns it to the pool.
+func (cp *CtxPool) Put(ctx *Ctx) {
+	ctx.Reset()
+	cp.pool.Put(ctx)
+}
+
+// --------------------------------------------------------------------------------
+// released response writer
+// --------------------------------------------------------------------------------
+
+// releasedResponseWriter is set as the response of a ctx released in safety mode; any use panics.
+type releasedResponseWriter struct{}
+
+func (releasedResponseWriter) Header() http.Header                { panic(ErrCtxReleased) }
+func (releasedResponseWriter) Write([]byte) (int, error)          { panic(ErrCtxReleased) }
+func (releasedResponseWriter) WriteHeader(int)                    { panic(ErrCtxReleased) }
+func (releasedResponseWriter) InnerResponse() http.ResponseWriter { panic(ErrCtxReleased) }
+func (releasedResponseWriter) StatusCode() int                    { panic(ErrCtxReleased) }
+func (releasedResponseWriter) ContentLength() int                 { panic(ErrCtxReleased) }
+func (releasedResponseWriter) Bytes() []byte                      { panic(ErrCtxReleased) }
+func (releasedResponseWriter) Flush()                             { panic(ErrCtxReleased) }
+func (releasedResponseWriter) FlushError() error                  { panic(ErrCtxReleased) }
+func (releasedResponseWriter) Close() error                       { panic(ErrCtxReleased) }
+
+// --------------------------------------------------------------------------------
+// response writer pools
+// --------------------------------------------------------------------------------
+
+var (
+	responseWriterPool = sync.Pool{
+		New: func() interface{} {
+			return &UncompressedResponseWriter{}
+		},
+	}
+	compressedResponseWriterPool = sync.Pool{
+		New: func() interface{} {
+			return &CompressedResponseWriter{}
+		},
+	}
+)
+
+// acquireResponseWriter returns a pooled uncompressed response writer.
+func acquireResponseWriter(w http.ResponseWriter) *UncompressedResponseWriter {
+	rw := responseWriterPool.Get().(*UncompressedResponseWriter)
+	rw.innerResponse = w
+	return rw
+}
+
+// acquireResponseWriter returns a pooled compressed response writer.
+func (c *Compression) acquireResponseWriter(w http.ResponseWriter, encoding string) *CompressedResponseWriter {
+	crw := compressedResponseWriterPool.Get().(*CompressedResponseWriter)
+	crw.innerResponse = w
+	crw.encoding = encoding
+	crw.encoder, _ = c.Encoder(encoding)
+	crw.compression = c
+	return crw
+}
+
+// releaseResponseWriter resets a response writer and returns it to its pool.
+// Buffered writers are not pooled, as their bytes may still be referenced.
+func releaseResponseWriter(w ResponseWriter) {
+	switch typed := w.(type) {
+	case *UncompressedResponseWriter:
+		if typed.responseBuffer == nil {
+			typed.reset()
+			responseWriterPool.Put(typed)
+		}
+	case *CompressedResponseWriter:
+		if typed.responseBuffer == nil {
+			typed.reset()
+			compressedResponseWriterPool.Put(typed)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestCtxReset(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := NewCtx(nil, &http.Request{}, RouteParameters{"id": "1"})
+	ctx.SetState("foo", "bar")
+	ctx.WithApp(New())
+	ctx.API()
+	ctx.Retain()
+
+	ctx.Reset()
+	assert.Nil(ctx.Request)
+	assert.Nil(ctx.App())
+	assert.Nil(ctx.State("foo"))
+	assert.Nil(ctx.api)
+	assert.False(ctx.retained)
+	assert.Empty(ctx.Param("id"))
+
+	ctx.SetState("foo", "baz")
+	assert.Equal("baz", ctx.State("foo"))
+}
+
+func TestAppCtxPoolReusesCtx(t *testing.T) {
+	assert := assert.New(t)
+
+	var contexts []*Ctx
+	app := New()
+	assert.Nil(app.CtxPool(), "pooling should be opt-in")
+	app.SetCtxPool(NewCtxPool())
+	app.GET("/", func(ctx *Ctx) Result {
+		assert.Nil(ctx.State("foo"), "state should not leak between requests")
+		ctx.SetState("foo", "bar")
+		contexts = append(contexts, ctx)
+		return ctx.NoContent()
+	})
+
+	for x := 0; x < 2; x++ {
+		assert.Nil(app.Mock().Execute())
+	}
+	assert.Len(contexts, 2)
+	assert.Nil(contexts[0].Request, "released contexts should be reset")
+}
+
+func TestAppCtxPoolRetain(t *testing.T) {
+	assert := assert.New(t)
+
+	var retained *Ctx
+	app := New()
+	app.SetCtxPool(NewCtxPool())
+	app.GET("/", func(ctx *Ctx) Result {
+		ctx.Retain()
+		retained = ctx
+		return ctx.NoContent()
+	})
+
+	assert.Nil(app.Mock().Execute())
+	assert.NotNil(retained.Request)
+	assert.False(retained.IsReleased())
+}
+
+func TestAppCtxSafetyMode(t *testing.T) {
+	assert := assert.New(t)
+
+	var released *Ctx
+	app := New()
+	app.SetCtxPool(NewCtxPool())
+	app.SetCtxSafetyMode(true)
+	app.GET("/", func(ctx *Ctx) Result {
+		released = ctx
+		return ctx.NoContent()
+	})
+
+	assert.Nil(app.Mock().Execute())
+	assert.True(released.IsReleased())
+
+	func() {
+		defer func() {
+			assert.Equal(ErrCtxReleased, recover())
+		}()
+		released.State("foo")
+	}()
+
+	func() {
+		defer func() {
+			assert.Equal(ErrCtxReleased, recover())
+		}()
+		released.Response.Header()
+	}()
+}
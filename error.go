@@ -1,5 +1,16 @@
 package web
 
+const (
+	// ErrHijackNotSupported is returned when hijacking a response that does not wrap an `http.Hijacker`.
+	ErrHijackNotSupported Error = "response writer does not support hijacking"
+	// ErrHijackAfterWrite is returned when hijacking a response that has already been written to.
+	ErrHijackAfterWrite Error = "response writer cannot be hijacked after it has been written to"
+	// ErrRequestTimeout is logged when an action does not finish within its timeout, and returned by later writes.
+	ErrRequestTimeout Error = "request timed out"
+	// ErrRequestBodyTooLarge is returned when reading more of a request body than its size limit allows.
+	ErrRequestBodyTooLarge Error = "request body too large"
+)
+
 // Error is a simple wrapper for strings to help with constant errors.
 type Error string
 
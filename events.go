@@ -15,6 +15,9 @@ const (
 	// EventWebRequestPostBody is an aliased event flag.
 	EventWebRequestPostBody = logger.EventWebRequestPostBody
 
+	// EventWebRequestTimeout fires when an action does not finish within its timeout.
+	EventWebRequestTimeout = logger.EventFlag("web.request.timeout")
+
 	// EventAppStart fires when the app is starting.
 	EventAppStart = logger.EventFlag("web.app.start")
 
@@ -23,6 +26,9 @@ const (
 
 	// EventAppExit fires when an app exits.
 	EventAppExit = logger.EventFlag("web.app.exit")
+
+	// EventAppShutdown fires when an app begins a graceful shutdown.
+	EventAppShutdown = logger.EventFlag("web.app.shutdown")
 )
 
 // RequestListener is a listener for `EventRequestStart` and `EventRequest` events.
@@ -0,0 +1,219 @@
+package web
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// FileSessionStoreExtension is the file extension for session files.
+	FileSessionStoreExtension = ".session"
+)
+
+var (
+	fileSessionStoreIDExpr = regexp.MustCompile(`^[A-Za-z0-9_=\-]+$`)
+)
+
+// NewFileSessionStore returns a new file session store rooted at a given directory, creating it if needed.
+func NewFileSessionStore(path string) (*FileSessionStore, error) {
+	err := os.MkdirAll(path, 0700)
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	return &FileSessionStore{
+		path: path,
+	}, nil
+}
+
+// FileSessionStore is a session store that persists sessions as json files in a directory.
+// Multiple app instances can share sessions by pointing at the same (shared) directory.
+// Session state values are round-tripped through json, so numbers are read back as float64.
+// With a ttl set, expired session files are swept at most once per ttl when a session is put, or by calling `Sweep`.
+type FileSessionStore struct {
+	sync.RWMutex
+	path      string
+	ttl       time.Duration
+	nextSweep time.Time
+}
+
+// Path returns the directory sessions are stored in.
+func (fss *FileSessionStore) Path() string {
+	return fss.path
+}
+
+// TTL returns the time a session is kept after it was last put or touched.
+func (fss *FileSessionStore) TTL() time.Duration {
+	return fss.ttl
+}
+
+// SetTTL sets the time a session is kept after it was last put or touched.
+// A value of 0 means sessions are kept until they are removed.
+func (fss *FileSessionStore) SetTTL(ttl time.Duration) {
+	fss.ttl = ttl
+}
+
+// Get returns a session by id.
+func (fss *FileSessionStore) Get(sessionID string) (*Session, error) {
+	sessionPath, err := fss.sessionPath(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	fss.RLock()
+	info, err := os.Stat(sessionPath)
+	if os.IsNotExist(err) {
+		fss.RUnlock()
+		return nil, nil
+	}
+	if err != nil {
+		fss.RUnlock()
+		return nil, exception.Wrap(err)
+	}
+	if fss.ttl > 0 && time.Now().After(info.ModTime().Add(fss.ttl)) {
+		fss.RUnlock()
+		return nil, fss.Remove(sessionID)
+	}
+
+	contents, err := ioutil.ReadFile(sessionPath)
+	fss.RUnlock()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+
+	session := &Session{}
+	err = json.Unmarshal(contents, session)
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	if session.State == nil {
+		session.State = map[string]interface{}{}
+	}
+	// the file is touched when the session is used, so its modification time is when it was last seen.
+	if modTime := info.ModTime().UTC(); modTime.After(session.LastSeenUTC) {
+		session.LastSeenUTC = modTime
+	}
+	return session, nil
+}
+
+// Put writes a session to disk.
+func (fss *FileSessionStore) Put(session *Session) error {
+	sessionPath, err := fss.sessionPath(session.SessionID)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(session)
+	if err != nil {
+		return exception.Wrap(err)
+	}
+
+	fss.Lock()
+	defer fss.Unlock()
+
+	if now := time.Now(); fss.ttl > 0 && now.After(fss.nextSweep) {
+		// a failed sweep is retried next time, and should not fail the put.
+		if fss.sweep(now) == nil {
+			fss.nextSweep = now.Add(fss.ttl)
+		}
+	}
+
+	// write to a temp file and rename so readers never see a partial session.
+	temp, err := ioutil.TempFile(fss.path, "tmp-")
+	if err != nil {
+		return exception.Wrap(err)
+	}
+	_, err = temp.Write(contents)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return exception.Wrap(err)
+	}
+	return exception.Wrap(os.Rename(temp.Name(), sessionPath))
+}
+
+// Remove deletes a session from disk.
+func (fss *FileSessionStore) Remove(sessionID string) error {
+	sessionPath, err := fss.sessionPath(sessionID)
+	if err != nil {
+		return err
+	}
+
+	fss.Lock()
+	defer fss.Unlock()
+
+	err = os.Remove(sessionPath)
+	if err != nil && !os.IsNotExist(err) {
+		return exception.Wrap(err)
+	}
+	return nil
+}
+
+// Touch updates the modification time of a session file, which is read back as its `LastSeenUTC`, resetting its ttl.
+func (fss *FileSessionStore) Touch(sessionID string) error {
+	sessionPath, err := fss.sessionPath(sessionID)
+	if err != nil {
+		return err
+	}
+
+	fss.Lock()
+	defer fss.Unlock()
+
+	now := time.Now()
+	err = os.Chtimes(sessionPath, now, now)
+	if err != nil && !os.IsNotExist(err) {
+		return exception.Wrap(err)
+	}
+	return nil
+}
+
+// Sweep deletes session files that have not been put or touched within the ttl.
+// It does nothing if there is no ttl.
+func (fss *FileSessionStore) Sweep() error {
+	if fss.ttl <= 0 {
+		return nil
+	}
+
+	fss.Lock()
+	defer fss.Unlock()
+	return fss.sweep(time.Now())
+}
+
+// sweep deletes expired session files; the caller must hold the lock.
+func (fss *FileSessionStore) sweep(now time.Time) error {
+	files, err := ioutil.ReadDir(fss.path)
+	if err != nil {
+		return exception.Wrap(err)
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != FileSessionStoreExtension {
+			continue
+		}
+		if now.After(file.ModTime().Add(fss.ttl)) {
+			err = os.Remove(filepath.Join(fss.path, file.Name()))
+			if err != nil && !os.IsNotExist(err) {
+				return exception.Wrap(err)
+			}
+		}
+	}
+	return nil
+}
+
+// sessionPath returns the file path for a session, guarding against ids that would escape the store directory.
+func (fss *FileSessionStore) sessionPath(sessionID string) (string, error) {
+	if !fileSessionStoreIDExpr.MatchString(sessionID) {
+		return "", ErrSessionIDInvalid
+	}
+	return filepath.Join(fss.path, sessionID+FileSessionStoreExtension), nil
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestFileSessionStore(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := ioutil.TempDir("", "sessions")
+	assert.Nil(err)
+	defer os.RemoveAll(path)
+
+	store, err := NewFileSessionStore(path)
+	assert.Nil(err)
+
+	session := NewSession(1, NewSessionID())
+	session.State["foo"] = "bar"
+	assert.Nil(store.Put(session))
+
+	// a second store on the same path sees the same sessions.
+	other, err := NewFileSessionStore(path)
+	assert.Nil(err)
+
+	fetched, err := other.Get(session.SessionID)
+	assert.Nil(err)
+	assert.NotNil(fetched)
+	assert.Equal(session.UserID, fetched.UserID)
+	assert.Equal("bar", fetched.State["foo"])
+	assert.True(session.CreatedUTC.Equal(fetched.CreatedUTC))
+
+	assert.Nil(other.Remove(session.SessionID))
+	fetched, err = store.Get(session.SessionID)
+	assert.Nil(err)
+	assert.Nil(fetched)
+}
+
+func TestFileSessionStoreTTL(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := ioutil.TempDir("", "sessions")
+	assert.Nil(err)
+	defer os.RemoveAll(path)
+
+	store, err := NewFileSessionStore(path)
+	assert.Nil(err)
+	store.SetTTL(time.Minute)
+
+	session := NewSession(1, NewSessionID())
+	assert.Nil(store.Put(session))
+
+	sessionPath, err := store.sessionPath(session.SessionID)
+	assert.Nil(err)
+	old := time.Now().Add(-2 * time.Minute)
+	assert.Nil(os.Chtimes(sessionPath, old, old))
+
+	fetched, err := store.Get(session.SessionID)
+	assert.Nil(err)
+	assert.Nil(fetched)
+}
+
+func TestFileSessionStoreSweep(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := ioutil.TempDir("", "sessions")
+	assert.Nil(err)
+	defer os.RemoveAll(path)
+
+	store, err := NewFileSessionStore(path)
+	assert.Nil(err)
+	store.SetTTL(time.Minute)
+
+	expired := NewSession(1, NewSessionID())
+	active := NewSession(2, NewSessionID())
+	assert.Nil(store.Put(expired))
+	assert.Nil(store.Put(active))
+
+	expiredPath, err := store.sessionPath(expired.SessionID)
+	assert.Nil(err)
+	old := time.Now().Add(-2 * time.Minute)
+	assert.Nil(os.Chtimes(expiredPath, old, old))
+
+	assert.Nil(store.Sweep())
+	_, err = os.Stat(expiredPath)
+	assert.True(os.IsNotExist(err), "expired session files should be deleted")
+
+	fetched, err := store.Get(active.SessionID)
+	assert.Nil(err)
+	assert.NotNil(fetched)
+
+	// puts sweep at most once per ttl.
+	assert.Nil(store.Put(expired))
+	assert.Nil(os.Chtimes(expiredPath, old, old))
+	store.nextSweep = time.Time{}
+	assert.Nil(store.Put(active))
+	_, err = os.Stat(expiredPath)
+	assert.True(os.IsNotExist(err), "put should sweep expired session files")
+
+	assert.Nil(store.Put(expired))
+	assert.Nil(os.Chtimes(expiredPath, old, old))
+	assert.Nil(store.Put(active))
+	_, err = os.Stat(expiredPath)
+	assert.Nil(err, "put should not sweep again within the ttl")
+}
+
+func TestFileSessionStoreInvalidID(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := ioutil.TempDir("", "sessions")
+	assert.Nil(err)
+	defer os.RemoveAll(path)
+
+	store, err := NewFileSessionStore(path)
+	assert.Nil(err)
+
+	_, err = store.Get("../../etc/passwd")
+	assert.Equal(ErrSessionIDInvalid, err)
+}
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// --------------------------------------------------------------------------------
+// HeadResponseWriter
+// --------------------------------------------------------------------------------
+
+// NewHeadResponseWriter returns a response writer that discards the response body
+// but still tracks the content length. It is used to serve HEAD requests from GET routes.
+func NewHeadResponseWriter(w http.ResponseWriter) *HeadResponseWriter {
+	return &HeadResponseWriter{
+		innerResponse: w,
+	}
+}
+
+// HeadResponseWriter is a response writer that discards the body.
+// The status code is held until `Flush` or `Close` so the `Content-Length`
+// header can reflect the size of the body that would have been written.
+type HeadResponseWriter struct {
+	innerResponse http.ResponseWriter
+	statusCode    int
+	contentLength int
+	wroteHeader   bool
+}
+
+// Write discards the data but adds to ContentLength.
+func (hrw *HeadResponseWriter) Write(b []byte) (int, error) {
+	hrw.contentLength += len(b)
+	return len(b), nil
+}
+
+// Header returns the headers for the response.
+func (hrw *HeadResponseWriter) Header() http.Header {
+	return hrw.innerResponse.Header()
+}
+
+// WriteHeader sets the status code; it is written to the inner response on `Flush`.
+func (hrw *HeadResponseWriter) WriteHeader(code int) {
+	if hrw.statusCode == 0 {
+		hrw.statusCode = code
+	}
+}
+
+// InnerResponse returns the backing http response.
+func (hrw *HeadResponseWriter) InnerResponse() http.ResponseWriter {
+	return hrw.innerResponse
+}
+
+// StatusCode returns the status code for the request.
+func (hrw *HeadResponseWriter) StatusCode() int {
+	return hrw.statusCode
+}
+
+// ContentLength returns the length of the body that would have been written.
+func (hrw *HeadResponseWriter) ContentLength() int {
+	return hrw.contentLength
+}
+
+// Bytes returns an empty slice, as the body is discarded.
+func (hrw *HeadResponseWriter) Bytes() []byte {
+	return []byte{}
+}
+
+// Flush implements `http.Flusher`; see `FlushError`.
+func (hrw *HeadResponseWriter) Flush() {
+	hrw.FlushError()
+}
+
+// FlushError writes the headers (including `Content-Length`) and status code to the inner response.
+func (hrw *HeadResponseWriter) FlushError() error {
+	if hrw.wroteHeader {
+		return nil
+	}
+	hrw.wroteHeader = true
+
+	if hrw.statusCode == 0 {
+		hrw.statusCode = http.StatusOK
+	}
+	// compressed responses are streamed without a length, so a HEAD for one does not report one either.
+	if hrw.bodyAllowed() && len(hrw.Header().Get(HeaderContentLength)) == 0 && len(hrw.Header().Get(HeaderContentEncoding)) == 0 {
+		hrw.Header().Set(HeaderContentLength, strconv.Itoa(hrw.contentLength))
+	}
+	hrw.innerResponse.WriteHeader(hrw.statusCode)
+	return nil
+}
+
+// Close flushes the headers if they have not been written.
+func (hrw *HeadResponseWriter) Close() error {
+	return hrw.FlushError()
+}
+
+func (hrw *HeadResponseWriter) bodyAllowed() bool {
+	return hrw.statusCode >= http.StatusOK && hrw.statusCode != http.StatusNoContent && hrw.statusCode != http.StatusNotModified
+}
@@ -48,6 +48,26 @@ func (jrp *JSONResultProvider) BadRequest(message string) Result {
 	}
 }
 
+// BadRequestError returns a service response for an error.
+// Validation and bind errors are serialized with their field failures.
+func (jrp *JSONResultProvider) BadRequestError(err error) Result {
+	if details := badRequestDetails(err); details != nil {
+		return &JSONResult{
+			StatusCode: http.StatusBadRequest,
+			Response:   details,
+		}
+	}
+	return jrp.BadRequest(err.Error())
+}
+
+// Status returns a service response for a status code.
+func (jrp *JSONResultProvider) Status(statusCode int, message string) Result {
+	return &JSONResult{
+		StatusCode: statusCode,
+		Response:   statusMessage(statusCode, message),
+	}
+}
+
 // OK returns a service response.
 func (jrp *JSONResultProvider) OK() Result {
 	return &JSONResult{
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMemoryRateLimitStoreShards is the default number of shards for the memory rate limit store.
+	DefaultMemoryRateLimitStoreShards = 32
+)
+
+// NewMemoryRateLimitStore returns a new in memory rate limit store with the default number of shards.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return NewMemoryRateLimitStoreWithShards(DefaultMemoryRateLimitStoreShards)
+}
+
+// NewMemoryRateLimitStoreWithShards returns a new in memory rate limit store.
+// Keys are spread over the shards so requests for different keys rarely wait on the same lock.
+func NewMemoryRateLimitStoreWithShards(shards int) *MemoryRateLimitStore {
+	if shards < 1 {
+		shards = 1
+	}
+	store := &MemoryRateLimitStore{shards: make([]*memoryRateLimitShard, shards)}
+	for index := range store.shards {
+		store.shards[index] = &memoryRateLimitShard{entries: map[string]*memoryRateLimitEntry{}}
+	}
+	return store
+}
+
+// MemoryRateLimitStore is an in memory, sharded rate limit store.
+// Expired state is swept from a shard at most once per ttl, when the shard is updated.
+type MemoryRateLimitStore struct {
+	shards []*memoryRateLimitShard
+}
+
+type memoryRateLimitShard struct {
+	sync.Mutex
+	entries   map[string]*memoryRateLimitEntry
+	nextSweep time.Time
+}
+
+type memoryRateLimitEntry struct {
+	state   RateLimitState
+	expires time.Time
+}
+
+// Update applies an update to the state for a key as of `now`.
+func (mrls *MemoryRateLimitStore) Update(key string, now time.Time, ttl time.Duration, update func(state *RateLimitState)) error {
+	shard := mrls.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	if now.After(shard.nextSweep) {
+		for entryKey, entry := range shard.entries {
+			if now.After(entry.expires) {
+				delete(shard.entries, entryKey)
+			}
+		}
+		shard.nextSweep = now.Add(ttl)
+	}
+
+	entry, hasEntry := shard.entries[key]
+	if !hasEntry || now.After(entry.expires) {
+		entry = &memoryRateLimitEntry{}
+		shard.entries[key] = entry
+	}
+	update(&entry.state)
+	entry.expires = now.Add(ttl)
+	return nil
+}
+
+// Len returns the number of keys in the store, including expired keys that have not been swept.
+func (mrls *MemoryRateLimitStore) Len() (count int) {
+	for _, shard := range mrls.shards {
+		shard.Lock()
+		count += len(shard.entries)
+		shard.Unlock()
+	}
+	return
+}
+
+func (mrls *MemoryRateLimitStore) shard(key string) *memoryRateLimitShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return mrls.shards[hash.Sum32()%uint32(len(mrls.shards))]
+}
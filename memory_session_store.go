@@ -0,0 +1,163 @@
+package web
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMemorySessionStoreMaxSessions is the default maximum number of sessions held by a memory session store.
+	DefaultMemorySessionStoreMaxSessions = 1 << 16
+)
+
+// NewMemorySessionStore returns a new memory session store.
+// By default sessions do not expire and the store holds `DefaultMemorySessionStoreMaxSessions` sessions.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		maxSessions: DefaultMemorySessionStoreMaxSessions,
+		sessions:    map[string]*list.Element{},
+		lru:         list.New(),
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// MemorySessionStore is an in-memory session store with optional ttl and least-recently-used eviction.
+type MemorySessionStore struct {
+	sync.Mutex
+	ttl         time.Duration
+	maxSessions int
+	sessions    map[string]*list.Element
+	lru         *list.List
+	now         func() time.Time
+}
+
+type memorySessionStoreEntry struct {
+	session    *Session
+	expiresUTC time.Time
+}
+
+// TTL returns the time a session is kept after it was last put or touched.
+func (mss *MemorySessionStore) TTL() time.Duration {
+	return mss.ttl
+}
+
+// SetTTL sets the time a session is kept after it was last put or touched.
+// A value of 0 means sessions are kept until evicted or removed.
+func (mss *MemorySessionStore) SetTTL(ttl time.Duration) {
+	mss.Lock()
+	mss.ttl = ttl
+	mss.Unlock()
+}
+
+// MaxSessions returns the maximum number of sessions held by the store.
+func (mss *MemorySessionStore) MaxSessions() int {
+	return mss.maxSessions
+}
+
+// SetMaxSessions sets the maximum number of sessions held by the store.
+// When the limit is reached the least recently used session is evicted.
+// A value of 0 means the store is unbounded.
+func (mss *MemorySessionStore) SetMaxSessions(maxSessions int) {
+	mss.Lock()
+	mss.maxSessions = maxSessions
+	mss.evict()
+	mss.Unlock()
+}
+
+// Len returns the number of sessions in the store, including any that have expired but not been collected.
+func (mss *MemorySessionStore) Len() int {
+	mss.Lock()
+	defer mss.Unlock()
+	return mss.lru.Len()
+}
+
+// Get returns a session by id.
+func (mss *MemorySessionStore) Get(sessionID string) (*Session, error) {
+	mss.Lock()
+	defer mss.Unlock()
+
+	element, hasElement := mss.sessions[sessionID]
+	if !hasElement {
+		return nil, nil
+	}
+	entry := element.Value.(*memorySessionStoreEntry)
+	if mss.isExpired(entry) {
+		mss.remove(element)
+		return nil, nil
+	}
+	mss.lru.MoveToFront(element)
+	return entry.session, nil
+}
+
+// Put adds or replaces a session.
+func (mss *MemorySessionStore) Put(session *Session) error {
+	mss.Lock()
+	defer mss.Unlock()
+
+	if element, hasElement := mss.sessions[session.SessionID]; hasElement {
+		entry := element.Value.(*memorySessionStoreEntry)
+		entry.session = session
+		entry.expiresUTC = mss.expires()
+		mss.lru.MoveToFront(element)
+		return nil
+	}
+
+	mss.sessions[session.SessionID] = mss.lru.PushFront(&memorySessionStoreEntry{
+		session:    session,
+		expiresUTC: mss.expires(),
+	})
+	mss.evict()
+	return nil
+}
+
+// Remove removes a session by id.
+func (mss *MemorySessionStore) Remove(sessionID string) error {
+	mss.Lock()
+	defer mss.Unlock()
+
+	if element, hasElement := mss.sessions[sessionID]; hasElement {
+		mss.remove(element)
+	}
+	return nil
+}
+
+// Touch marks a session as recently used, setting its `LastSeenUTC` and resetting its ttl.
+func (mss *MemorySessionStore) Touch(sessionID string) error {
+	mss.Lock()
+	defer mss.Unlock()
+
+	if element, hasElement := mss.sessions[sessionID]; hasElement {
+		entry := element.Value.(*memorySessionStoreEntry)
+		entry.session.MarkSeen(mss.now())
+		entry.expiresUTC = mss.expires()
+		mss.lru.MoveToFront(element)
+	}
+	return nil
+}
+
+func (mss *MemorySessionStore) expires() time.Time {
+	if mss.ttl == 0 {
+		return time.Time{}
+	}
+	return mss.now().Add(mss.ttl)
+}
+
+func (mss *MemorySessionStore) isExpired(entry *memorySessionStoreEntry) bool {
+	return !entry.expiresUTC.IsZero() && mss.now().After(entry.expiresUTC)
+}
+
+func (mss *MemorySessionStore) remove(element *list.Element) {
+	entry := mss.lru.Remove(element).(*memorySessionStoreEntry)
+	delete(mss.sessions, entry.session.SessionID)
+}
+
+// evict removes the least recently used sessions until the store is within its size limit.
+func (mss *MemorySessionStore) evict() {
+	if mss.maxSessions <= 0 {
+		return
+	}
+	for mss.lru.Len() > mss.maxSessions {
+		mss.remove(mss.lru.Back())
+	}
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestMemorySessionStore(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewMemorySessionStore()
+	session := NewSession(1, NewSessionID())
+	assert.Nil(store.Put(session))
+
+	fetched, err := store.Get(session.SessionID)
+	assert.Nil(err)
+	assert.Equal(session, fetched)
+
+	assert.Nil(store.Remove(session.SessionID))
+	fetched, err = store.Get(session.SessionID)
+	assert.Nil(err)
+	assert.Nil(fetched)
+}
+
+func TestMemorySessionStoreTTL(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2017, 04, 01, 12, 0, 0, 0, time.UTC)
+	store := NewMemorySessionStore()
+	store.now = func() time.Time { return now }
+	store.SetTTL(time.Minute)
+
+	session := NewSession(1, NewSessionID())
+	assert.Nil(store.Put(session))
+
+	now = now.Add(45 * time.Second)
+	assert.Nil(store.Touch(session.SessionID))
+
+	now = now.Add(45 * time.Second)
+	fetched, err := store.Get(session.SessionID)
+	assert.Nil(err)
+	assert.NotNil(fetched, "touch should have reset the ttl")
+
+	now = now.Add(2 * time.Minute)
+	fetched, err = store.Get(session.SessionID)
+	assert.Nil(err)
+	assert.Nil(fetched)
+	assert.Zero(store.Len())
+}
+
+func TestMemorySessionStoreLRU(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewMemorySessionStore()
+	store.SetMaxSessions(2)
+
+	first := NewSession(1, NewSessionID())
+	second := NewSession(2, NewSessionID())
+	third := NewSession(3, NewSessionID())
+
+	assert.Nil(store.Put(first))
+	assert.Nil(store.Put(second))
+
+	// using the first session makes the second the least recently used.
+	fetched, err := store.Get(first.SessionID)
+	assert.Nil(err)
+	assert.NotNil(fetched)
+
+	assert.Nil(store.Put(third))
+	assert.Equal(2, store.Len())
+
+	fetched, err = store.Get(second.SessionID)
+	assert.Nil(err)
+	assert.Nil(fetched)
+
+	fetched, err = store.Get(first.SessionID)
+	assert.Nil(err)
+	assert.NotNil(fetched)
+}
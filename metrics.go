@@ -0,0 +1,292 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ContentTypePrometheus is the content type for the prometheus text exposition format.
+	ContentTypePrometheus = "text/plain; version=0.0.4; charset=utf-8"
+
+	// MetricRequestsTotal is the counter of requests served.
+	MetricRequestsTotal = "web_requests_total"
+	// MetricRequestDurationSeconds is the histogram of request latency.
+	MetricRequestDurationSeconds = "web_request_duration_seconds"
+	// MetricRequestsInFlight is the gauge of requests being served.
+	MetricRequestsInFlight = "web_requests_in_flight"
+	// MetricResponseSizeBytes is the summary of response sizes.
+	MetricResponseSizeBytes = "web_response_size_bytes"
+	// MetricPanicsTotal is the counter of panics raised while serving requests.
+	MetricPanicsTotal = "web_panics_total"
+)
+
+var (
+	// DefaultMetricsBuckets are the default latency histogram buckets, in seconds.
+	DefaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+// Metrics returns an action that renders the app metrics in the prometheus text exposition format.
+// Metrics are off by default; enable them with `app.SetMetricsCollector(web.NewMetrics())`
+// and register the action on a route, e.g. `app.GET("/metrics", app.Metrics())`.
+func (a *App) Metrics() Action {
+	return func(ctx *Ctx) Result {
+		buffer := bytes.NewBuffer(nil)
+		if a.metrics != nil {
+			a.metrics.WriteTo(buffer)
+		}
+		return &RawResult{ContentType: ContentTypePrometheus, Body: buffer.Bytes()}
+	}
+}
+
+// MetricsCollector returns the app metrics collector, or nil if metrics are disabled.
+func (a *App) MetricsCollector() *Metrics {
+	return a.metrics
+}
+
+// SetMetricsCollector sets the app metrics collector, enabling metrics; set it to nil to disable them.
+func (a *App) SetMetricsCollector(metrics *Metrics) {
+	a.metrics = metrics
+}
+
+// NewMetrics returns a new metrics collector.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		buckets:  DefaultMetricsBuckets,
+		requests: map[metricLabels]*requestMetrics{},
+		inFlight: map[metricLabels]int64{},
+		panics:   map[metricLabels]uint64{},
+	}
+}
+
+// Metrics collects request metrics labeled by route (`Route.String()`), method and status.
+type Metrics struct {
+	sync.Mutex
+	buckets  []float64
+	requests map[metricLabels]*requestMetrics
+	inFlight map[metricLabels]int64
+	panics   map[metricLabels]uint64
+}
+
+// WithBuckets sets the latency histogram buckets, in seconds; request series recorded so far are reset.
+func (m *Metrics) WithBuckets(buckets ...float64) *Metrics {
+	m.Lock()
+	defer m.Unlock()
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	m.buckets = sorted
+	m.requests = map[metricLabels]*requestMetrics{}
+	return m
+}
+
+// Buckets returns the latency histogram buckets, in seconds.
+func (m *Metrics) Buckets() []float64 {
+	return m.buckets
+}
+
+// RequestStarted records a request starting.
+func (m *Metrics) RequestStarted(route *Route, method string) {
+	labels := newMetricLabels(route, method, 0)
+	m.Lock()
+	m.inFlight[labels]++
+	m.Unlock()
+}
+
+// RequestCompleted records a request completing; a zero status code is recorded as `200`, which is what the client is sent.
+func (m *Metrics) RequestCompleted(route *Route, method string, statusCode int, elapsed time.Duration, contentLength int) {
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.requestFinished(newMetricLabels(route, method, 0))
+
+	labels := newMetricLabels(route, method, statusCode)
+	request, hasRequest := m.requests[labels]
+	if !hasRequest {
+		request = &requestMetrics{buckets: make([]uint64, len(m.buckets))}
+		m.requests[labels] = request
+	}
+	seconds := elapsed.Seconds()
+	request.count++
+	request.durationSum += seconds
+	request.sizeSum += float64(contentLength)
+	for index, bucket := range m.buckets {
+		if seconds <= bucket {
+			request.buckets[index]++
+		}
+	}
+}
+
+// RequestPanicked records a request panicking.
+func (m *Metrics) RequestPanicked(route *Route, method string) {
+	labels := newMetricLabels(route, method, 0)
+	m.Lock()
+	defer m.Unlock()
+	m.requestFinished(labels)
+	m.panics[labels]++
+}
+
+// requestFinished decrements the in flight gauge, dropping the series once it is idle
+// so that routes with no requests being served are not exported forever.
+func (m *Metrics) requestFinished(labels metricLabels) {
+	if m.inFlight[labels] <= 1 {
+		delete(m.inFlight, labels)
+		return
+	}
+	m.inFlight[labels]--
+}
+
+// WriteTo writes the metrics in the prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	output := &countingWriter{Writer: w}
+	requestLabels := make([]metricLabels, 0, len(m.requests))
+	for labels := range m.requests {
+		requestLabels = append(requestLabels, labels)
+	}
+	sortMetricLabels(requestLabels)
+
+	writeMetricHeader(output, MetricRequestsTotal, "counter", "The total number of requests served.")
+	for _, labels := range requestLabels {
+		fmt.Fprintf(output, "%s{%s} %d\n", MetricRequestsTotal, labels, m.requests[labels].count)
+	}
+
+	writeMetricHeader(output, MetricRequestDurationSeconds, "histogram", "The request latency in seconds.")
+	for _, labels := range requestLabels {
+		request := m.requests[labels]
+		for index, bucket := range m.buckets {
+			fmt.Fprintf(output, "%s_bucket{%s,le=\"%s\"} %d\n", MetricRequestDurationSeconds, labels, formatMetricValue(bucket), request.buckets[index])
+		}
+		fmt.Fprintf(output, "%s_bucket{%s,le=\"+Inf\"} %d\n", MetricRequestDurationSeconds, labels, request.count)
+		fmt.Fprintf(output, "%s_sum{%s} %s\n", MetricRequestDurationSeconds, labels, formatMetricValue(request.durationSum))
+		fmt.Fprintf(output, "%s_count{%s} %d\n", MetricRequestDurationSeconds, labels, request.count)
+	}
+
+	writeMetricHeader(output, MetricResponseSizeBytes, "summary", "The response size in bytes.")
+	for _, labels := range requestLabels {
+		request := m.requests[labels]
+		fmt.Fprintf(output, "%s_sum{%s} %s\n", MetricResponseSizeBytes, labels, formatMetricValue(request.sizeSum))
+		fmt.Fprintf(output, "%s_count{%s} %d\n", MetricResponseSizeBytes, labels, request.count)
+	}
+
+	inFlightLabels := make([]metricLabels, 0, len(m.inFlight))
+	for labels := range m.inFlight {
+		inFlightLabels = append(inFlightLabels, labels)
+	}
+	sortMetricLabels(inFlightLabels)
+	writeMetricHeader(output, MetricRequestsInFlight, "gauge", "The number of requests being served.")
+	for _, labels := range inFlightLabels {
+		fmt.Fprintf(output, "%s{%s} %d\n", MetricRequestsInFlight, labels, m.inFlight[labels])
+	}
+
+	panicLabels := make([]metricLabels, 0, len(m.panics))
+	for labels := range m.panics {
+		panicLabels = append(panicLabels, labels)
+	}
+	sortMetricLabels(panicLabels)
+	writeMetricHeader(output, MetricPanicsTotal, "counter", "The total number of panics raised while serving requests.")
+	for _, labels := range panicLabels {
+		fmt.Fprintf(output, "%s{%s} %d\n", MetricPanicsTotal, labels, m.panics[labels])
+	}
+	return output.written, output.err
+}
+
+// ServeHTTP serves the metrics in the prometheus text exposition format, for use outside of an app route.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(HeaderContentType, ContentTypePrometheus)
+	m.WriteTo(w)
+}
+
+// requestMetrics are the metrics for a route, method and status.
+type requestMetrics struct {
+	count       uint64
+	buckets     []uint64
+	durationSum float64
+	sizeSum     float64
+}
+
+// newMetricLabels returns the labels for a route, method and status; a zero status is omitted.
+func newMetricLabels(route *Route, method string, statusCode int) metricLabels {
+	labels := metricLabels{method: method}
+	if route != nil {
+		labels.route = route.String()
+	}
+	if statusCode > 0 {
+		labels.status = strconv.Itoa(statusCode)
+	}
+	return labels
+}
+
+// metricLabels are the labels of a series.
+type metricLabels struct {
+	route  string
+	method string
+	status string
+}
+
+// String returns the labels in the exposition format.
+func (ml metricLabels) String() string {
+	labels := fmt.Sprintf("route=\"%s\",method=\"%s\"", escapeMetricLabel(ml.route), escapeMetricLabel(ml.method))
+	if len(ml.status) > 0 {
+		labels = labels + fmt.Sprintf(",status=\"%s\"", ml.status)
+	}
+	return labels
+}
+
+func sortMetricLabels(labels []metricLabels) {
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].route != labels[j].route {
+			return labels[i].route < labels[j].route
+		}
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		return labels[i].status < labels[j].status
+	})
+}
+
+func writeMetricHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+var metricLabelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func escapeMetricLabel(value string) string {
+	return metricLabelEscaper.Replace(value)
+}
+
+func formatMetricValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written and keeps the first error.
+type countingWriter struct {
+	io.Writer
+	written int64
+	err     error
+}
+
+// Write writes to the inner writer, unless a previous write failed.
+func (cw *countingWriter) Write(contents []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	written, err := cw.Writer.Write(contents)
+	cw.written += int64(written)
+	cw.err = err
+	return written, err
+}
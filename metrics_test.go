@@ -0,0 +1,102 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestMetricsCollected(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetMetricsCollector(NewMetrics())
+	app.SetPanicHandler(func(r *Ctx, err interface{}) Result {
+		return r.Text().InternalError(nil)
+	})
+	app.GET("/users/:id", func(r *Ctx) Result {
+		return r.Text().Result("user")
+	})
+	app.GET("/panic", func(r *Ctx) Result {
+		panic("boom")
+	})
+	app.GET("/metrics", app.Metrics())
+
+	for _, path := range []string{"/users/1", "/users/2", "/panic"} {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal(ContentTypePrometheus, res.Header().Get(HeaderContentType))
+
+	body := res.Body.String()
+	assert.Contains("# TYPE web_requests_total counter", body)
+	assert.Contains(`web_requests_total{route="GET_/users/:id",method="GET",status="200"} 2`, body)
+	assert.False(strings.Contains(body, "/users/1"))
+	assert.Contains(`web_request_duration_seconds_bucket{route="GET_/users/:id",method="GET",status="200",le="+Inf"} 2`, body)
+	assert.Contains(`web_request_duration_seconds_count{route="GET_/users/:id",method="GET",status="200"} 2`, body)
+	assert.Contains(`web_response_size_bytes_sum{route="GET_/users/:id",method="GET",status="200"} 8`, body)
+	assert.False(strings.Contains(body, `web_requests_in_flight{route="GET_/users/:id"`))
+	assert.Contains(`web_requests_in_flight{route="GET_/metrics",method="GET"} 1`, body)
+	assert.False(strings.Contains(body, `web_requests_in_flight{route="GET_/panic"`))
+	assert.Contains(`web_panics_total{route="GET_/panic",method="GET"} 1`, body)
+}
+
+func TestMetricsDisabledByDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	assert.Nil(app.MetricsCollector())
+	app.GET("/", func(r *Ctx) Result {
+		return r.NoContent()
+	})
+	app.GET("/metrics", app.Metrics())
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(http.StatusNoContent, res.Code)
+
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Zero(res.Body.Len())
+}
+
+func TestMetricsHistogram(t *testing.T) {
+	assert := assert.New(t)
+
+	metrics := NewMetrics().WithBuckets(1, 0.1)
+	assert.Equal([]float64{0.1, 1}, metrics.Buckets())
+
+	route := &Route{Method: "POST", Path: "/"}
+	metrics.RequestStarted(route, "POST")
+	metrics.RequestCompleted(route, "POST", 0, 50*time.Millisecond, 10)
+	metrics.RequestStarted(route, "POST")
+	metrics.RequestCompleted(route, "POST", http.StatusOK, 500*time.Millisecond, 20)
+
+	buffer := bytes.NewBuffer(nil)
+	written, err := metrics.WriteTo(buffer)
+	assert.Nil(err)
+	assert.Equal(buffer.Len(), written)
+
+	body := buffer.String()
+	assert.Contains(`web_request_duration_seconds_bucket{route="POST_/",method="POST",status="200",le="0.1"} 1`, body)
+	assert.Contains(`web_request_duration_seconds_bucket{route="POST_/",method="POST",status="200",le="1"} 2`, body)
+	assert.Contains(`web_request_duration_seconds_sum{route="POST_/",method="POST",status="200"} 0.55`, body)
+	assert.Contains(`web_response_size_bytes_count{route="POST_/",method="POST",status="200"} 2`, body)
+}
+
+func TestMetricLabelsEscaped(t *testing.T) {
+	assert := assert.New(t)
+
+	labels := newMetricLabels(&Route{Method: "GET", Path: "/\"quoted\"\\"}, "GET", 404)
+	assert.Equal(`route="GET_/\"quoted\"\\",method="GET",status="404"`, labels.String())
+	assert.Equal(`route="",method="GET"`, newMetricLabels(nil, "GET", 0).String())
+}
@@ -31,3 +31,24 @@ func XMLProviderAsDefault(action Action) Action {
 		return action(context)
 	}
 }
+
+// ProblemProviderAsDefault sets the context.CurrrentProvider() equal to context.Problem().
+func ProblemProviderAsDefault(action Action) Action {
+	return func(context *Ctx) Result {
+		context.SetDefaultResultProvider(context.Problem())
+		return action(context)
+	}
+}
+
+// NegotiatedProviderAsDefault sets the context.CurrrentProvider() equal to context.Negotiated().
+// The provider that was the default before is used when the request accepts anything.
+func NegotiatedProviderAsDefault(action Action) Action {
+	return func(context *Ctx) Result {
+		negotiated := context.Negotiated()
+		if current := context.DefaultResultProvider(); current != ResultProvider(negotiated) {
+			negotiated.WithDefault(current)
+		}
+		context.SetDefaultResultProvider(negotiated)
+		return action(context)
+	}
+}
@@ -64,6 +64,11 @@ func (mrb *MockRequestBuilder) Post(pathFormat string, args ...interface{}) *Moc
 	return mrb.WithVerb("POST").WithPathf(pathFormat, args...)
 }
 
+// Head is a shortcut for WithVerb("HEAD") WithPathf(pathFormat, args...)
+func (mrb *MockRequestBuilder) Head(pathFormat string, args ...interface{}) *MockRequestBuilder {
+	return mrb.WithVerb("HEAD").WithPathf(pathFormat, args...)
+}
+
 // Put is a shortcut for WithVerb("PUT") WithPathf(pathFormat, args...)
 func (mrb *MockRequestBuilder) Put(pathFormat string, args ...interface{}) *MockRequestBuilder {
 	return mrb.WithVerb("PUT").WithPathf(pathFormat, args...)
@@ -212,6 +217,9 @@ func (mrb *MockRequestBuilder) Request() (*http.Request, error) {
 		req.Body = ioutil.NopCloser(bytes.NewBuffer(mrb.postBody))
 	}
 
+	if mrb.tx != nil {
+		req = req.WithContext(WithContextTx(req.Context(), mrb.tx))
+	}
 	return req, nil
 }
 
@@ -318,10 +326,16 @@ func (mrb *MockRequestBuilder) Response() (res *http.Response, err error) {
 	}
 
 	w := NewMockResponseWriter(buffer)
-	route.Handler(w, req, route, params, mrb.tx)
+	contentLength := 0
+	if mrb.verb == "HEAD" && route.Method == "GET" {
+		contentLength = mrb.app.serveHeadAsGet(w, req, route, params).ContentLength()
+	} else {
+		route.Handler(w, req, route, params)
+		contentLength = w.ContentLength()
+	}
 	res = &http.Response{
 		Body:          ioutil.NopCloser(bytes.NewBuffer(buffer.Bytes())),
-		ContentLength: int64(w.ContentLength()),
+		ContentLength: int64(contentLength),
 		Header:        http.Header{},
 		StatusCode:    w.statusCode,
 		Proto:         "http",
@@ -69,7 +69,10 @@ func (res *MockResponseWriter) Bytes() []byte {
 }
 
 // Flush is a no-op.
-func (res *MockResponseWriter) Flush() error {
+func (res *MockResponseWriter) Flush() {}
+
+// FlushError is a no-op.
+func (res *MockResponseWriter) FlushError() error {
 	return nil
 }
 
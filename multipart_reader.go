@@ -0,0 +1,191 @@
+package web
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// DefaultMultipartMaxMemory is the default number of bytes of a part kept in memory before it is spilled to a temp file.
+	DefaultMultipartMaxMemory = int64(1 << 20) //1mb
+
+	// ErrMultipartValueTooLarge is returned when a form value part is larger than the max memory.
+	ErrMultipartValueTooLarge Error = "multipart form value too large"
+)
+
+// MultipartReader returns a streaming reader over a `multipart/form-data` request body.
+// Unlike `PostedFiles`, parts are read one at a time, so uploads do not have to fit in memory.
+func (rc *Ctx) MultipartReader() (*MultipartReader, error) {
+	reader, err := rc.Request.MultipartReader()
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	return NewMultipartReader(reader), nil
+}
+
+// NewMultipartReader returns a new multipart reader.
+func NewMultipartReader(reader *multipart.Reader) *MultipartReader {
+	return &MultipartReader{
+		reader:    reader,
+		maxMemory: DefaultMultipartMaxMemory,
+	}
+}
+
+// MultipartReader reads the parts of a multipart body as a stream.
+// Call `Close` when done to remove any temp files parts were spilled to.
+type MultipartReader struct {
+	reader    *multipart.Reader
+	maxMemory int64
+	tempDir   string
+	tempFiles []string
+}
+
+// WithMaxMemory sets the number of bytes of a part kept in memory before it is spilled to a temp file.
+func (mr *MultipartReader) WithMaxMemory(maxMemory int64) *MultipartReader {
+	mr.maxMemory = maxMemory
+	return mr
+}
+
+// MaxMemory returns the number of bytes of a part kept in memory before it is spilled to a temp file.
+func (mr *MultipartReader) MaxMemory() int64 {
+	return mr.maxMemory
+}
+
+// WithTempDir sets the directory parts are spilled to; it defaults to `os.TempDir()`.
+func (mr *MultipartReader) WithTempDir(tempDir string) *MultipartReader {
+	mr.tempDir = tempDir
+	return mr
+}
+
+// TempDir returns the directory parts are spilled to.
+func (mr *MultipartReader) TempDir() string {
+	return mr.tempDir
+}
+
+// NextPart returns the next part, or `io.EOF` when there are no more.
+// A part must be read before moving on to the next; whatever is left of it is skipped.
+func (mr *MultipartReader) NextPart() (*MultipartPart, error) {
+	part, err := mr.reader.NextPart()
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	return &MultipartPart{Part: part, reader: mr}, nil
+}
+
+// Close removes any temp files parts were spilled to.
+func (mr *MultipartReader) Close() error {
+	var err error
+	for _, path := range mr.tempFiles {
+		if removeErr := os.Remove(path); removeErr != nil && !os.IsNotExist(removeErr) && err == nil {
+			err = exception.Wrap(removeErr)
+		}
+	}
+	mr.tempFiles = nil
+	return err
+}
+
+// MultipartPart is a part of a multipart body; read it like any `io.Reader`.
+type MultipartPart struct {
+	*multipart.Part
+	reader *MultipartReader
+}
+
+// Key returns the form field name of the part.
+func (mp *MultipartPart) Key() string {
+	return mp.FormName()
+}
+
+// IsFile returns if the part is a file upload.
+func (mp *MultipartPart) IsFile() bool {
+	return len(mp.FileName()) > 0
+}
+
+// Value reads the part as a form value, failing with `ErrMultipartValueTooLarge` if it is over the max memory.
+func (mp *MultipartPart) Value() (string, error) {
+	contents, err := ioutil.ReadAll(io.LimitReader(mp, mp.reader.maxMemory+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(contents)) > mp.reader.maxMemory {
+		return "", ErrMultipartValueTooLarge
+	}
+	return string(contents), nil
+}
+
+// Buffer reads the rest of the part, keeping it in memory up to the max memory and spilling it to a temp file past that.
+func (mp *MultipartPart) Buffer() (*MultipartFile, error) {
+	file := &MultipartFile{
+		Key:      mp.Key(),
+		FileName: mp.FileName(),
+		Header:   mp.Header,
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	size, err := io.CopyN(buffer, mp, mp.reader.maxMemory+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if size <= mp.reader.maxMemory {
+		file.Size = size
+		file.contents = buffer.Bytes()
+		return file, nil
+	}
+
+	tempFile, err := ioutil.TempFile(mp.reader.tempDir, "multipart-")
+	if err != nil {
+		return nil, exception.Wrap(err)
+	}
+	defer tempFile.Close()
+	mp.reader.tempFiles = append(mp.reader.tempFiles, tempFile.Name())
+
+	size, err = io.Copy(tempFile, io.MultiReader(buffer, mp))
+	if err != nil {
+		return nil, err
+	}
+	file.Size = size
+	file.path = tempFile.Name()
+	return file, nil
+}
+
+// MultipartFile is a buffered part, held either in memory or in a temp file.
+type MultipartFile struct {
+	Key      string
+	FileName string
+	Header   textproto.MIMEHeader
+	Size     int64
+
+	contents []byte
+	path     string
+}
+
+// IsSpilled returns if the file was spilled to a temp file.
+func (mf *MultipartFile) IsSpilled() bool {
+	return len(mf.path) > 0
+}
+
+// Path returns the temp file path, if the file was spilled to one.
+// The file is removed when the reader is closed; move it elsewhere to keep it.
+func (mf *MultipartFile) Path() string {
+	return mf.path
+}
+
+// Open returns a reader over the file contents.
+func (mf *MultipartFile) Open() (io.ReadCloser, error) {
+	if mf.IsSpilled() {
+		file, err := os.Open(mf.path)
+		if err != nil {
+			return nil, exception.Wrap(err)
+		}
+		return file, nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(mf.contents)), nil
+}
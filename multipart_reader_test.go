@@ -0,0 +1,90 @@
+package web
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestMultipartReader(t *testing.T) {
+	assert := assert.New(t)
+
+	body := bytes.NewBuffer(nil)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", "bailey")
+	small, _ := writer.CreateFormFile("small", "small.txt")
+	small.Write([]byte("small file"))
+	large, _ := writer.CreateFormFile("large", "large.txt")
+	large.Write([]byte(strings.Repeat("large file ", 10)))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx := NewCtx(NewMockResponseWriter(nil), req, nil)
+
+	reader, err := ctx.MultipartReader()
+	assert.Nil(err)
+	reader.WithMaxMemory(16).WithTempDir(os.TempDir())
+	assert.Equal(16, reader.MaxMemory())
+	assert.Equal(os.TempDir(), reader.TempDir())
+
+	part, err := reader.NextPart()
+	assert.Nil(err)
+	assert.Equal("name", part.Key())
+	assert.False(part.IsFile())
+	value, err := part.Value()
+	assert.Nil(err)
+	assert.Equal("bailey", value)
+
+	part, err = reader.NextPart()
+	assert.Nil(err)
+	assert.True(part.IsFile())
+	smallFile, err := part.Buffer()
+	assert.Nil(err)
+	assert.Equal("small", smallFile.Key)
+	assert.Equal("small.txt", smallFile.FileName)
+	assert.False(smallFile.IsSpilled())
+	assert.Equal(10, smallFile.Size)
+
+	part, err = reader.NextPart()
+	assert.Nil(err)
+	largeFile, err := part.Buffer()
+	assert.Nil(err)
+	assert.True(largeFile.IsSpilled())
+	assert.Equal(110, largeFile.Size)
+	opened, err := largeFile.Open()
+	assert.Nil(err)
+	contents, err := ioutil.ReadAll(opened)
+	opened.Close()
+	assert.Nil(err)
+	assert.Equal(strings.Repeat("large file ", 10), string(contents))
+
+	_, err = reader.NextPart()
+	assert.Equal(io.EOF, err)
+
+	assert.Nil(reader.Close())
+	_, err = os.Stat(largeFile.Path())
+	assert.True(os.IsNotExist(err))
+}
+
+func TestMultipartPartValueTooLarge(t *testing.T) {
+	assert := assert.New(t)
+
+	body := bytes.NewBuffer(nil)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", "a value longer than the max memory")
+	writer.Close()
+
+	reader := NewMultipartReader(multipart.NewReader(body, writer.Boundary())).WithMaxMemory(8)
+	part, err := reader.NextPart()
+	assert.Nil(err)
+	_, err = part.Value()
+	assert.Equal(ErrMultipartValueTooLarge, err)
+}
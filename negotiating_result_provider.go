@@ -0,0 +1,276 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NewNegotiatingResultProvider returns a new result provider that picks a provider based on the "Accept" header.
+// JSON is served by the API provider, problem details by the Problem provider, html by the View provider (if the ctx has an app, and only for error and status results), xml by the XML provider
+// and plain text by the Text provider. The default provider, used when the request accepts anything, is the Text provider.
+func NewNegotiatingResultProvider(ctx *Ctx) *NegotiatingResultProvider {
+	nrp := &NegotiatingResultProvider{ctx: ctx}
+	if ctx == nil {
+		return nrp
+	}
+
+	nrp.WithProvider(MediaTypeJSON, ctx.API())
+	nrp.WithProvider(MediaTypeProblemJSON, ctx.Problem())
+	if ctx.app != nil && ctx.app.viewCache != nil {
+		nrp.WithProvider(MediaTypeHTML, ctx.View())
+	}
+	nrp.WithProvider(MediaTypeXML, ctx.XML())
+	nrp.WithProvider(MediaTypeTextXML, ctx.XML())
+	nrp.WithProvider(MediaTypeText, ctx.Text())
+	nrp.WithDefault(ctx.Text())
+	return nrp
+}
+
+// negotiatedProvider is a media type and the provider that serves it.
+type negotiatedProvider struct {
+	MediaType string
+	Provider  ResultProvider
+}
+
+// NegotiatingResultProvider dispatches results to the provider that matches the request "Accept" header.
+// If nothing acceptable is registered, results are `406 Not Acceptable`.
+type NegotiatingResultProvider struct {
+	ctx             *Ctx
+	providers       []negotiatedProvider
+	defaultProvider ResultProvider
+
+	negotiated   ResultProvider
+	isNegotiated bool
+}
+
+// WithProvider registers a provider for a media type, e.g. `application/json`.
+// Providers registered earlier win when a media range (like `text/*`) matches more than one.
+func (nrp *NegotiatingResultProvider) WithProvider(mediaType string, provider ResultProvider) *NegotiatingResultProvider {
+	mediaType = strings.ToLower(mediaType)
+	for index := range nrp.providers {
+		if nrp.providers[index].MediaType == mediaType {
+			nrp.providers[index].Provider = provider
+			nrp.isNegotiated = false
+			return nrp
+		}
+	}
+	nrp.providers = append(nrp.providers, negotiatedProvider{MediaType: mediaType, Provider: provider})
+	nrp.isNegotiated = false
+	return nrp
+}
+
+// WithDefault sets the provider used when the request has no "Accept" header or accepts `*/*`.
+func (nrp *NegotiatingResultProvider) WithDefault(provider ResultProvider) *NegotiatingResultProvider {
+	nrp.defaultProvider = provider
+	nrp.isNegotiated = false
+	return nrp
+}
+
+// Default returns the default provider.
+func (nrp *NegotiatingResultProvider) Default() ResultProvider {
+	return nrp.defaultProvider
+}
+
+// Provider returns the negotiated provider, or nil if nothing acceptable is registered.
+func (nrp *NegotiatingResultProvider) Provider() ResultProvider {
+	if !nrp.isNegotiated {
+		nrp.negotiated = nrp.negotiate(nil)
+		nrp.isNegotiated = true
+		if nrp.ctx != nil && nrp.ctx.Response != nil {
+			addVaryHeader(nrp.ctx.Response.Header(), HeaderAccept)
+		}
+	}
+	return nrp.negotiated
+}
+
+// NotFound returns a not found result from the negotiated provider.
+func (nrp *NegotiatingResultProvider) NotFound() Result {
+	if provider := nrp.Provider(); provider != nil {
+		return provider.NotFound()
+	}
+	return nrp.NotAcceptable()
+}
+
+// NotAuthorized returns a not authorized result from the negotiated provider.
+func (nrp *NegotiatingResultProvider) NotAuthorized() Result {
+	if provider := nrp.Provider(); provider != nil {
+		return provider.NotAuthorized()
+	}
+	return nrp.NotAcceptable()
+}
+
+// InternalError returns an internal error result from the negotiated provider.
+func (nrp *NegotiatingResultProvider) InternalError(err error) Result {
+	if provider := nrp.Provider(); provider != nil {
+		return provider.InternalError(err)
+	}
+	if nrp.ctx != nil {
+		nrp.ctx.logFatal(err)
+	}
+	return nrp.NotAcceptable()
+}
+
+// BadRequest returns a bad request result from the negotiated provider.
+func (nrp *NegotiatingResultProvider) BadRequest(message string) Result {
+	if provider := nrp.Provider(); provider != nil {
+		return provider.BadRequest(message)
+	}
+	return nrp.NotAcceptable()
+}
+
+// BadRequestError returns a bad request result for an error from the negotiated provider.
+// Providers that do not serialize errors are given the error message.
+func (nrp *NegotiatingResultProvider) BadRequestError(err error) Result {
+	if provider := nrp.Provider(); provider != nil {
+		return BadRequestErrorResult(provider, err)
+	}
+	return nrp.NotAcceptable()
+}
+
+// Status returns a result for a status code from the negotiated provider.
+func (nrp *NegotiatingResultProvider) Status(statusCode int, message string) Result {
+	if provider := nrp.Provider(); provider != nil {
+		return StatusResult(provider, statusCode, message)
+	}
+	return nrp.NotAcceptable()
+}
+
+// Result returns a result from the negotiated provider.
+// Views can only render error and status results, so the View provider is passed over for the next acceptable provider.
+func (nrp *NegotiatingResultProvider) Result(response interface{}) Result {
+	provider := nrp.Provider()
+	if !canRenderResult(provider) {
+		provider = nrp.negotiate(canRenderResult)
+	}
+	if provider != nil {
+		return provider.Result(response)
+	}
+	return nrp.NotAcceptable()
+}
+
+// canRenderResult returns if a provider can render arbitrary objects with `Result`.
+func canRenderResult(provider ResultProvider) bool {
+	_, isView := provider.(*ViewResultProvider)
+	return !isView
+}
+
+// NotAcceptable returns a `406 Not Acceptable` result.
+func (nrp *NegotiatingResultProvider) NotAcceptable() Result {
+	return &RawResult{
+		StatusCode:  http.StatusNotAcceptable,
+		ContentType: ContentTypeText,
+		Body:        []byte("Not Acceptable"),
+	}
+}
+
+// negotiate returns the provider that best matches the "Accept" header, considering only providers the filter allows (if set).
+func (nrp *NegotiatingResultProvider) negotiate(filter func(ResultProvider) bool) ResultProvider {
+	allowed := func(provider ResultProvider) bool {
+		return provider != nil && (filter == nil || filter(provider))
+	}
+
+	var accept string
+	if nrp.ctx != nil && nrp.ctx.Request != nil {
+		accept = nrp.ctx.Request.Header.Get(HeaderAccept)
+	}
+	if len(strings.TrimSpace(accept)) == 0 {
+		if allowed(nrp.defaultProvider) {
+			return nrp.defaultProvider
+		}
+		return nil
+	}
+
+	ranges := parseAcceptHeader(accept)
+	for _, mediaRange := range ranges {
+		if mediaRange.Quality <= 0 {
+			continue
+		}
+		if mediaRange.MediaType == "*/*" && allowed(nrp.defaultProvider) {
+			return nrp.defaultProvider
+		}
+		for _, provider := range nrp.providers {
+			if allowed(provider.Provider) && mediaRange.Matches(provider.MediaType) && !isMediaTypeRejected(ranges, provider.MediaType) {
+				return provider.Provider
+			}
+		}
+	}
+	return nil
+}
+
+// mediaRange is a parsed element of an "Accept" header.
+type mediaRange struct {
+	MediaType string
+	Quality   float64
+	index     int
+}
+
+// Specificity returns 2 for `type/subtype`, 1 for `type/*` and 0 for `*/*`.
+func (mr mediaRange) Specificity() int {
+	if mr.MediaType == "*/*" {
+		return 0
+	}
+	if strings.HasSuffix(mr.MediaType, "/*") {
+		return 1
+	}
+	return 2
+}
+
+// Matches returns if the range matches a media type.
+func (mr mediaRange) Matches(mediaType string) bool {
+	switch mr.Specificity() {
+	case 0:
+		return true
+	case 1:
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mr.MediaType, "*"))
+	}
+	return mr.MediaType == mediaType
+}
+
+// isMediaTypeRejected returns if a media type is explicitly given `q=0`.
+func isMediaTypeRejected(ranges []mediaRange, mediaType string) bool {
+	for _, mediaRange := range ranges {
+		if mediaRange.MediaType == mediaType {
+			return mediaRange.Quality <= 0
+		}
+	}
+	return false
+}
+
+// parseAcceptHeader parses an "Accept" header into media ranges, ordered by quality and then specificity.
+func parseAcceptHeader(header string) []mediaRange {
+	var ranges []mediaRange
+	for index, part := range strings.Split(header, ",") {
+		pieces := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(pieces[0]))
+		if len(mediaType) == 0 {
+			continue
+		}
+		if mediaType == "*" {
+			mediaType = "*/*"
+		}
+
+		quality := 1.0
+		for _, param := range pieces[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") || strings.HasPrefix(param, "Q=") {
+				if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{MediaType: mediaType, Quality: quality, index: index})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Quality != ranges[j].Quality {
+			return ranges[i].Quality > ranges[j].Quality
+		}
+		if ranges[i].Specificity() != ranges[j].Specificity() {
+			return ranges[i].Specificity() > ranges[j].Specificity()
+		}
+		return ranges[i].index < ranges[j].index
+	})
+	return ranges
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestParseAcceptHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	ranges := parseAcceptHeader("text/*;q=0.5, */*;q=0.1, application/json, text/html;level=1, application/xml;q=0.9")
+	assert.Len(ranges, 5)
+	assert.Equal("application/json", ranges[0].MediaType)
+	assert.Equal("text/html", ranges[1].MediaType)
+	assert.Equal("application/xml", ranges[2].MediaType)
+	assert.Equal("text/*", ranges[3].MediaType)
+	assert.Equal("*/*", ranges[4].MediaType)
+}
+
+func TestNegotiatingResultProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/", func(ctx *Ctx) Result {
+		return ctx.Negotiated().Result(map[string]string{"foo": "bar"})
+	})
+	app.GET("/missing", func(ctx *Ctx) Result {
+		return ctx.Negotiated().NotFound()
+	})
+
+	contents, meta, err := app.Mock().WithHeader(HeaderAccept, "application/json").BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal(ContentTypeApplicationJSON, meta.Headers.Get(HeaderContentType))
+	assert.Equal([]string{HeaderAcceptEncoding, HeaderAccept}, meta.Headers[HeaderVary])
+	assert.Contains(`"foo":"bar"`, string(contents))
+
+	_, meta, err = app.Mock().WithHeader(HeaderAccept, "text/html;q=0.2, application/xml;q=0.8").BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(ContentTypeXML, meta.Headers.Get(HeaderContentType))
+
+	_, meta, err = app.Mock().WithPathf("/missing").WithHeader(HeaderAccept, "application/*").BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusNotFound, meta.StatusCode)
+	assert.Equal(ContentTypeApplicationJSON, meta.Headers.Get(HeaderContentType))
+
+	_, meta, err = app.Mock().WithHeader(HeaderAccept, "image/png").BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusNotAcceptable, meta.StatusCode)
+
+	_, meta, err = app.Mock().WithHeader(HeaderAccept, "application/json;q=0, text/plain;q=0").BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusNotAcceptable, meta.StatusCode)
+}
+
+func TestNegotiatedProviderAsDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/", func(ctx *Ctx) Result {
+		return ctx.DefaultResultProvider().BadRequest("bad")
+	}, NegotiatedProviderAsDefault, JSONProviderAsDefault)
+
+	contents, meta, err := app.Mock().WithHeader(HeaderAccept, "*/*").BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusBadRequest, meta.StatusCode)
+	assert.Equal(ContentTypeApplicationJSON, meta.Headers.Get(HeaderContentType))
+	assert.Equal("\"bad\"\n", string(contents))
+
+	contents, meta, err = app.Mock().WithHeader(HeaderAccept, "text/plain").BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(ContentTypeText, meta.Headers.Get(HeaderContentType))
+	assert.Equal("Bad Request: bad", string(contents))
+}
+
+type negotiatedTestResponse struct {
+	Foo string
+}
+
+func TestNegotiatingResultProviderBrowserAccept(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/", func(ctx *Ctx) Result {
+		return ctx.DefaultResultProvider().Result(negotiatedTestResponse{Foo: "bar"})
+	}, NegotiatedProviderAsDefault)
+
+	// html goes to views, which can't render objects, so the next acceptable provider is used.
+	browserAccept := "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	contents, meta, err := app.Mock().WithHeader(HeaderAccept, browserAccept).BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal(ContentTypeXML, meta.Headers.Get(HeaderContentType))
+	assert.Contains("<Foo>bar</Foo>", string(contents))
+
+	_, meta, err = app.Mock().WithHeader(HeaderAccept, "text/html").BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusNotAcceptable, meta.StatusCode)
+}
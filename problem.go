@@ -0,0 +1,164 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// ProblemTypeDefault is the problem type used when a problem has no more specific type.
+	ProblemTypeDefault = "about:blank"
+)
+
+// NewProblem returns a new problem for a status code, titled with the status text.
+func NewProblem(statusCode int) *Problem {
+	return &Problem{
+		Type:   ProblemTypeDefault,
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+	}
+}
+
+// Problem is an RFC 7807 problem details object.
+// Problems are errors, so actions can return them from helpers and have them rendered as is.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// WithType sets the problem type uri.
+func (p *Problem) WithType(problemType string) *Problem {
+	p.Type = problemType
+	return p
+}
+
+// WithTitle sets the problem title.
+func (p *Problem) WithTitle(title string) *Problem {
+	p.Title = title
+	return p
+}
+
+// WithDetail sets the problem detail.
+func (p *Problem) WithDetail(detail string) *Problem {
+	p.Detail = detail
+	return p
+}
+
+// WithInstance sets the problem instance uri.
+func (p *Problem) WithInstance(instance string) *Problem {
+	p.Instance = instance
+	return p
+}
+
+// WithExtension sets an extension member.
+func (p *Problem) WithExtension(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]interface{}{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// Copy returns a copy of the problem, with its own extensions.
+func (p *Problem) Copy() *Problem {
+	copied := *p
+	if p.Extensions != nil {
+		copied.Extensions = make(map[string]interface{}, len(p.Extensions))
+		for key, value := range p.Extensions {
+			copied.Extensions[key] = value
+		}
+	}
+	return &copied
+}
+
+// Error implements error.
+func (p *Problem) Error() string {
+	if len(p.Detail) > 0 {
+		return fmt.Sprintf("%s: %s", p.Title, p.Detail)
+	}
+	return p.Title
+}
+
+// MarshalJSON marshals the problem with its extension members alongside the standard members.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := map[string]interface{}{}
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	if len(p.Type) > 0 {
+		members["type"] = p.Type
+	}
+	if len(p.Title) > 0 {
+		members["title"] = p.Title
+	}
+	if p.Status > 0 {
+		members["status"] = p.Status
+	}
+	if len(p.Detail) > 0 {
+		members["detail"] = p.Detail
+	}
+	if len(p.Instance) > 0 {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON unmarshals a problem, collecting unknown members as extensions.
+func (p *Problem) UnmarshalJSON(contents []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(contents, &members); err != nil {
+		return err
+	}
+
+	*p = Problem{}
+	for key, raw := range members {
+		var err error
+		switch key {
+		case "type":
+			err = json.Unmarshal(raw, &p.Type)
+		case "title":
+			err = json.Unmarshal(raw, &p.Title)
+		case "status":
+			err = json.Unmarshal(raw, &p.Status)
+		case "detail":
+			err = json.Unmarshal(raw, &p.Detail)
+		case "instance":
+			err = json.Unmarshal(raw, &p.Instance)
+		default:
+			var value interface{}
+			if err = json.Unmarshal(raw, &value); err == nil {
+				p.WithExtension(key, value)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProblemMapper maps an error to a problem. It should return nil for errors it does not handle.
+type ProblemMapper func(err error) *Problem
+
+// ProblemResult is an `application/problem+json` result.
+type ProblemResult struct {
+	Problem *Problem
+}
+
+// Render renders the result.
+func (pr *ProblemResult) Render(ctx *Ctx) error {
+	statusCode := pr.Problem.Status
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	ctx.Response.Header().Set(HeaderContentType, ContentTypeProblemJSON)
+	ctx.Response.WriteHeader(statusCode)
+	return exception.Wrap(json.NewEncoder(ctx.Response).Encode(pr.Problem))
+}
@@ -0,0 +1,138 @@
+package web
+
+import "net/http"
+
+const (
+	// ProblemExtensionErrors is the extension member that lists field errors for bad requests.
+	ProblemExtensionErrors = "errors"
+
+	// ProblemExtensionRequestID is the extension member that carries the request id.
+	ProblemExtensionRequestID = "request_id"
+)
+
+// NewProblemResultProvider returns a new problem result provider.
+// The mapper and production mode are taken from the ctx app, if any.
+func NewProblemResultProvider(ctx *Ctx) *ProblemResultProvider {
+	prp := &ProblemResultProvider{ctx: ctx}
+	if ctx != nil && ctx.app != nil {
+		prp.mapper = ctx.app.problemMapper
+		prp.production = ctx.app.production
+	}
+	return prp
+}
+
+// ProblemResultProvider returns RFC 7807 `application/problem+json` results.
+type ProblemResultProvider struct {
+	ctx        *Ctx
+	mapper     ProblemMapper
+	production bool
+}
+
+// WithMapper sets the mapper used to turn errors into problems.
+func (prp *ProblemResultProvider) WithMapper(mapper ProblemMapper) *ProblemResultProvider {
+	prp.mapper = mapper
+	return prp
+}
+
+// WithProduction sets if internal error details should be redacted.
+func (prp *ProblemResultProvider) WithProduction(production bool) *ProblemResultProvider {
+	prp.production = production
+	return prp
+}
+
+// NotFound returns a problem response.
+func (prp *ProblemResultProvider) NotFound() Result {
+	return prp.Problem(NewProblem(http.StatusNotFound))
+}
+
+// NotAuthorized returns a problem response.
+func (prp *ProblemResultProvider) NotAuthorized() Result {
+	return prp.Problem(NewProblem(http.StatusForbidden))
+}
+
+// InternalError returns a problem response.
+// The error is always logged; in production mode, server error problems omit the detail and extension members.
+func (prp *ProblemResultProvider) InternalError(err error) Result {
+	problem := prp.mapError(err)
+	if problem == nil {
+		problem = NewProblem(http.StatusInternalServerError).WithDetail(err.Error())
+	}
+	if problem.Status == 0 || problem.Status >= http.StatusInternalServerError {
+		if prp.ctx != nil {
+			prp.ctx.logFatal(err)
+		}
+		if prp.production {
+			redacted := *problem
+			redacted.Detail = ""
+			redacted.Extensions = nil
+			problem = &redacted
+		}
+	}
+	return prp.Problem(problem)
+}
+
+// BadRequest returns a problem response.
+func (prp *ProblemResultProvider) BadRequest(message string) Result {
+	return prp.Problem(NewProblem(http.StatusBadRequest).WithDetail(message))
+}
+
+// BadRequestError returns a problem response for an error.
+// Validation and bind errors list their field failures in the `errors` extension member.
+func (prp *ProblemResultProvider) BadRequestError(err error) Result {
+	if problem := prp.mapError(err); problem != nil {
+		return prp.Problem(problem)
+	}
+
+	problem := NewProblem(http.StatusBadRequest).WithDetail(err.Error())
+	switch typed := err.(type) {
+	case *ValidationError:
+		problem.WithExtension(ProblemExtensionErrors, typed.Fields)
+	case *BindError:
+		problem.WithExtension(ProblemExtensionErrors, typed.Fields)
+	}
+	return prp.Problem(problem)
+}
+
+// Status returns a problem response for a status code.
+func (prp *ProblemResultProvider) Status(statusCode int, message string) Result {
+	return prp.Problem(NewProblem(statusCode).WithDetail(message))
+}
+
+// Result returns a json response, or a problem response if the response is a problem.
+func (prp *ProblemResultProvider) Result(response interface{}) Result {
+	if problem, isProblem := response.(*Problem); isProblem {
+		return prp.Problem(problem)
+	}
+	return &JSONResult{
+		StatusCode: http.StatusOK,
+		Response:   response,
+	}
+}
+
+// Problem returns a problem response, defaulting the instance to the request path and adding the request id.
+// The per request members are set on a copy, so shared problems (like package level errors) are left as is.
+func (prp *ProblemResultProvider) Problem(problem *Problem) Result {
+	problem = problem.Copy()
+	if len(problem.Instance) == 0 && prp.ctx != nil && prp.ctx.Request != nil && prp.ctx.Request.URL != nil {
+		problem.Instance = prp.ctx.Request.URL.Path
+	}
+	if prp.ctx != nil && len(prp.ctx.RequestID()) > 0 {
+		if _, hasRequestID := problem.Extensions[ProblemExtensionRequestID]; !hasRequestID {
+			problem.WithExtension(ProblemExtensionRequestID, prp.ctx.RequestID())
+		}
+	}
+	return &ProblemResult{Problem: problem}
+}
+
+// mapError maps an error with the mapper, passing problems through as is.
+func (prp *ProblemResultProvider) mapError(err error) *Problem {
+	if prp.mapper != nil {
+		if problem := prp.mapper(err); problem != nil {
+			return problem
+		}
+	}
+	if problem, isProblem := err.(*Problem); isProblem {
+		return problem
+	}
+	return nil
+}
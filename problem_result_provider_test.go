@@ -0,0 +1,129 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	logger "github.com/blendlabs/go-logger"
+)
+
+var errProblemTestConflict = fmt.Errorf("conflict")
+
+func TestProblemMarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	problem := NewProblem(http.StatusConflict).
+		WithType("https://example.com/probs/conflict").
+		WithDetail("already exists").
+		WithInstance("/users/1").
+		WithExtension("id", 1)
+
+	contents, err := json.Marshal(problem)
+	assert.Nil(err)
+	assert.Equal(`{"detail":"already exists","id":1,"instance":"/users/1","status":409,"title":"Conflict","type":"https://example.com/probs/conflict"}`, string(contents))
+
+	var verify Problem
+	assert.Nil(json.Unmarshal(contents, &verify))
+	assert.Equal(problem.Type, verify.Type)
+	assert.Equal(problem.Title, verify.Title)
+	assert.Equal(problem.Status, verify.Status)
+	assert.Equal(problem.Detail, verify.Detail)
+	assert.Equal(problem.Instance, verify.Instance)
+	assert.Equal(1, verify.Extensions["id"])
+}
+
+func TestProblemResultProvider(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetProblemMapper(func(err error) *Problem {
+		if err == errProblemTestConflict {
+			return NewProblem(http.StatusConflict).WithDetail("already exists")
+		}
+		return nil
+	})
+	app.GET("/conflict", func(ctx *Ctx) Result {
+		return ctx.Problem().InternalError(errProblemTestConflict)
+	})
+	app.GET("/missing", func(ctx *Ctx) Result {
+		return ctx.Problem().NotFound()
+	})
+	app.POST("/invalid", func(ctx *Ctx) Result {
+		return ctx.Problem().BadRequestError(&ValidationError{Fields: []ValidationFieldError{{Field: "name", Rule: ValidationRuleRequired, Message: "is required"}}})
+	})
+
+	var problem Problem
+	meta, err := app.Mock().WithPathf("/conflict").JSONWithMeta(&problem)
+	assert.Nil(err)
+	assert.Equal(http.StatusConflict, meta.StatusCode)
+	assert.Equal(ContentTypeProblemJSON, meta.Headers.Get(HeaderContentType))
+	assert.Equal(ProblemTypeDefault, problem.Type)
+	assert.Equal("Conflict", problem.Title)
+	assert.Equal("already exists", problem.Detail)
+	assert.Equal("/conflict", problem.Instance)
+
+	meta, err = app.Mock().WithPathf("/missing").JSONWithMeta(&problem)
+	assert.Nil(err)
+	assert.Equal(http.StatusNotFound, meta.StatusCode)
+	assert.Equal(http.StatusNotFound, problem.Status)
+
+	meta, err = app.Mock().Post("/invalid").JSONWithMeta(&problem)
+	assert.Nil(err)
+	assert.Equal(http.StatusBadRequest, meta.StatusCode)
+	assert.NotNil(problem.Extensions[ProblemExtensionErrors])
+}
+
+func TestProblemResultProviderProductionRedacts(t *testing.T) {
+	assert := assert.New(t)
+
+	agent := logger.New(logger.NewEventFlagSetWithEvents(logger.EventFatalError))
+	var logged error
+	agent.AddEventListener(logger.EventFatalError, func(wr logger.Logger, ts logger.TimeSource, eventFlag logger.EventFlag, state ...interface{}) {
+		logged, _ = state[0].(error)
+	})
+
+	app := New()
+	app.SetLogger(agent)
+	app.SetProduction(true)
+	app.GET("/", func(ctx *Ctx) Result {
+		return ctx.Problem().InternalError(fmt.Errorf("db password is hunter2"))
+	})
+
+	contents, meta, err := app.Mock().BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusInternalServerError, meta.StatusCode)
+	assert.False(len(contents) == 0)
+	assert.False(strings.Contains(string(contents), "hunter2"))
+
+	agent.Drain()
+	assert.NotNil(logged)
+	assert.Equal("db password is hunter2", logged.Error())
+}
+
+var problemTestSentinel = NewProblem(http.StatusGone).WithExtension("reason", "moved")
+
+func TestProblemResultProviderCopiesProblem(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/:name", func(ctx *Ctx) Result {
+		return ctx.Problem().Problem(problemTestSentinel)
+	})
+
+	for _, path := range []string{"/first", "/second"} {
+		var problem Problem
+		meta, err := app.Mock().WithPathf(path).JSONWithMeta(&problem)
+		assert.Nil(err)
+		assert.Equal(http.StatusGone, meta.StatusCode)
+		assert.Equal(path, problem.Instance)
+		assert.NotNil(problem.Extensions[ProblemExtensionRequestID])
+		assert.Equal("moved", problem.Extensions["reason"])
+	}
+
+	assert.Empty(problemTestSentinel.Instance)
+	assert.Len(problemTestSentinel.Extensions, 1)
+}
@@ -0,0 +1,255 @@
+package web
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitKeyFunc returns the key a request is rate limited by.
+// Requests with an empty key are not rate limited.
+type RateLimitKeyFunc func(ctx *Ctx) string
+
+// RateLimitByIP keys requests by the client ip, as resolved through trusted proxies.
+func RateLimitByIP(ctx *Ctx) string {
+	return ctx.RemoteIP()
+}
+
+// RateLimitByUserID keys requests by the session user id; requests without a session are not rate limited.
+// It should be nested inside a session middleware.
+func RateLimitByUserID(ctx *Ctx) string {
+	if session := ctx.Session(); session != nil {
+		return strconv.FormatInt(session.UserID, 10)
+	}
+	return ""
+}
+
+// RateLimitByHeader keys requests by a header value, typically an api key.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return func(ctx *Ctx) string {
+		return ctx.Request.Header.Get(name)
+	}
+}
+
+// RateLimitStatus is the outcome of taking a request from a rate limiter.
+type RateLimitStatus struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+// RateLimitState is the state a rate limit policy keeps per key.
+type RateLimitState struct {
+	Tokens        float64
+	Count         int
+	PreviousCount int
+	Window        time.Time
+	Updated       time.Time
+}
+
+// RateLimitPolicy is a rate limiting algorithm.
+type RateLimitPolicy interface {
+	// Take records a request against the state and returns if it is allowed.
+	Take(state *RateLimitState, now time.Time) RateLimitStatus
+	// TTL is how long the state for a key must be kept after it is last updated.
+	TTL() time.Duration
+}
+
+// RateLimitStore stores rate limit state by key; implementations must be safe for concurrent use.
+// Stores shared between processes should apply updates atomically, i.e. with a transaction or compare and swap.
+type RateLimitStore interface {
+	// Update applies an update to the state for a key as of `now`, starting from the zero state if there is none or it has expired.
+	// The state expires `ttl` after `now`; stores should use `now` rather than their own clock.
+	Update(key string, now time.Time, ttl time.Duration, update func(state *RateLimitState)) error
+}
+
+// NewTokenBucket returns a token bucket policy that allows bursts of `limit` requests, refilling `limit` tokens every `per`.
+// It panics if the limit or period are not positive.
+func NewTokenBucket(limit int, per time.Duration) *TokenBucket {
+	if limit <= 0 || per <= 0 {
+		panic("token bucket limit and period must be positive")
+	}
+	return &TokenBucket{limit: limit, per: per}
+}
+
+// TokenBucket is a token bucket rate limit policy.
+type TokenBucket struct {
+	limit int
+	per   time.Duration
+}
+
+// Take takes a token from the bucket.
+func (tb *TokenBucket) Take(state *RateLimitState, now time.Time) RateLimitStatus {
+	limit := float64(tb.limit)
+	if state.Updated.IsZero() {
+		state.Tokens = limit
+	} else if elapsed := now.Sub(state.Updated); elapsed > 0 {
+		state.Tokens = math.Min(limit, state.Tokens+limit*float64(elapsed)/float64(tb.per))
+	}
+	state.Updated = now
+
+	status := RateLimitStatus{Limit: tb.limit}
+	if state.Tokens >= 1 {
+		state.Tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = tb.refill(1 - state.Tokens)
+	}
+	status.Remaining = int(state.Tokens)
+	status.Reset = now.Add(tb.refill(limit - state.Tokens))
+	return status
+}
+
+// TTL returns how long it takes an empty bucket to refill.
+func (tb *TokenBucket) TTL() time.Duration {
+	return tb.per
+}
+
+// refill returns how long it takes to refill a number of tokens.
+func (tb *TokenBucket) refill(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens * float64(tb.per) / float64(tb.limit)))
+}
+
+// NewSlidingWindow returns a sliding window policy that allows `limit` requests in any `window`.
+// The count for the window is estimated from the current and previous fixed windows.
+// It panics if the limit or window are not positive.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	if limit <= 0 || window <= 0 {
+		panic("sliding window limit and window must be positive")
+	}
+	return &SlidingWindow{limit: limit, window: window}
+}
+
+// SlidingWindow is a sliding window counter rate limit policy.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+}
+
+// Take counts a request in the window.
+func (sw *SlidingWindow) Take(state *RateLimitState, now time.Time) RateLimitStatus {
+	start := now.Truncate(sw.window)
+	if !state.Window.Equal(start) {
+		if state.Window.Add(sw.window).Equal(start) {
+			state.PreviousCount = state.Count
+		} else {
+			state.PreviousCount = 0
+		}
+		state.Count = 0
+		state.Window = start
+	}
+	state.Updated = now
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(sw.window)
+	estimate := float64(state.PreviousCount)*weight + float64(state.Count)
+
+	status := RateLimitStatus{Limit: sw.limit, Reset: start.Add(sw.window)}
+	if estimate+1 <= float64(sw.limit) {
+		state.Count++
+		estimate++
+		status.Allowed = true
+	} else {
+		status.RetryAfter = sw.retryAfter(state, elapsed)
+	}
+	if remaining := float64(sw.limit) - estimate; remaining > 0 {
+		status.Remaining = int(remaining)
+	}
+	return status
+}
+
+// TTL returns two windows, as the previous window is used for the estimate.
+func (sw *SlidingWindow) TTL() time.Duration {
+	return 2 * sw.window
+}
+
+// retryAfter returns how long until the estimated count leaves room for a request.
+func (sw *SlidingWindow) retryAfter(state *RateLimitState, elapsed time.Duration) time.Duration {
+	window := float64(sw.window)
+	room := float64(sw.limit - 1 - state.Count)
+	if room >= 0 && state.PreviousCount > 0 {
+		// wait for the previous window's weight to decay.
+		return time.Duration(math.Ceil(window*(1-room/float64(state.PreviousCount)))) - elapsed
+	}
+	// wait for the next window, then for this window's weight to decay.
+	var decay float64
+	if state.Count > 0 && sw.limit > 0 {
+		decay = math.Max(0, window*(1-float64(sw.limit-1)/float64(state.Count)))
+	}
+	return sw.window - elapsed + time.Duration(math.Ceil(decay))
+}
+
+// NewRateLimiter returns a new rate limiter for a policy, keyed by remote ip and backed by an in memory store.
+func NewRateLimiter(policy RateLimitPolicy) *RateLimiter {
+	return &RateLimiter{
+		policy: policy,
+		store:  NewMemoryRateLimitStore(),
+		key:    RateLimitByIP,
+		now:    time.Now,
+	}
+}
+
+// RateLimiter limits requests by key.
+type RateLimiter struct {
+	policy RateLimitPolicy
+	store  RateLimitStore
+	key    RateLimitKeyFunc
+	now    func() time.Time
+}
+
+// WithStore sets the store.
+func (rl *RateLimiter) WithStore(store RateLimitStore) *RateLimiter {
+	rl.store = store
+	return rl
+}
+
+// WithKey sets the func that keys requests.
+func (rl *RateLimiter) WithKey(key RateLimitKeyFunc) *RateLimiter {
+	rl.key = key
+	return rl
+}
+
+// Store returns the store.
+func (rl *RateLimiter) Store() RateLimitStore {
+	return rl.store
+}
+
+// Take records a request for a key and returns if it is allowed.
+func (rl *RateLimiter) Take(key string) (status RateLimitStatus, err error) {
+	now := rl.now()
+	err = rl.store.Update(key, now, rl.policy.TTL(), func(state *RateLimitState) {
+		status = rl.policy.Take(state, now)
+	})
+	return
+}
+
+// Middleware rate limits an action, setting the `X-RateLimit-*` headers and returning `429 Too Many Requests`
+// with a `Retry-After` header from the default result provider when the limit is exceeded.
+// Requests are allowed if the store returns an error, which is logged.
+func (rl *RateLimiter) Middleware(action Action) Action {
+	return func(context *Ctx) Result {
+		key := rl.key(context)
+		if len(key) == 0 {
+			return action(context)
+		}
+
+		status, err := rl.Take(key)
+		if err != nil {
+			context.Logger().Error(err)
+			return action(context)
+		}
+
+		header := context.Response.Header()
+		header.Set(HeaderXRateLimitLimit, strconv.Itoa(status.Limit))
+		header.Set(HeaderXRateLimitRemaining, strconv.Itoa(status.Remaining))
+		header.Set(HeaderXRateLimitReset, strconv.FormatInt(status.Reset.Unix(), 10))
+		if !status.Allowed {
+			header.Set(HeaderRetryAfter, strconv.Itoa(int(math.Ceil(status.RetryAfter.Seconds()))))
+			return StatusResult(context.DefaultResultProvider(), http.StatusTooManyRequests, "")
+		}
+		return action(context)
+	}
+}
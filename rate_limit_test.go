@@ -0,0 +1,182 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	assert := assert.New(t)
+
+	bucket := NewTokenBucket(2, time.Second)
+	state := &RateLimitState{}
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	status := bucket.Take(state, now)
+	assert.True(status.Allowed)
+	assert.Equal(1, status.Remaining)
+	assert.True(bucket.Take(state, now).Allowed)
+
+	status = bucket.Take(state, now)
+	assert.False(status.Allowed)
+	assert.Equal(0, status.Remaining)
+	assert.Equal(500*time.Millisecond, status.RetryAfter)
+	assert.Equal(now.Add(time.Second), status.Reset)
+
+	status = bucket.Take(state, now.Add(500*time.Millisecond))
+	assert.True(status.Allowed)
+	assert.False(bucket.Take(state, now.Add(500*time.Millisecond)).Allowed)
+
+	status = bucket.Take(state, now.Add(time.Hour))
+	assert.True(status.Allowed)
+	assert.Equal(1, status.Remaining, "the bucket should not fill past the limit")
+}
+
+func TestSlidingWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	window := NewSlidingWindow(2, time.Minute)
+	state := &RateLimitState{}
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(window.Take(state, start).Allowed)
+	status := window.Take(state, start.Add(10*time.Second))
+	assert.True(status.Allowed)
+	assert.Equal(0, status.Remaining)
+	assert.Equal(start.Add(time.Minute), status.Reset)
+
+	status = window.Take(state, start.Add(20*time.Second))
+	assert.False(status.Allowed)
+	assert.Equal(70*time.Second, status.RetryAfter)
+
+	// halfway through the next window the previous window counts for half.
+	status = window.Take(state, start.Add(90*time.Second))
+	assert.True(status.Allowed)
+	assert.False(window.Take(state, start.Add(90*time.Second)).Allowed)
+
+	// windows that are not adjacent are not counted.
+	assert.True(window.Take(state, start.Add(10*time.Minute)).Allowed)
+	assert.True(window.Take(state, start.Add(10*time.Minute)).Allowed)
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStoreWithShards(4)
+	for index := 0; index < 16; index++ {
+		assert.Nil(store.Update(strconv.Itoa(index), now, time.Minute, func(state *RateLimitState) {
+			state.Count++
+		}))
+	}
+	assert.Equal(16, store.Len())
+
+	var count int
+	store.Update("1", now, time.Minute, func(state *RateLimitState) {
+		state.Count++
+		count = state.Count
+	})
+	assert.Equal(2, count)
+
+	store.Update("1", now.Add(2*time.Minute), time.Minute, func(state *RateLimitState) {
+		count = state.Count
+	})
+	assert.Zero(count, "state should expire relative to the time it was updated as of")
+	assert.True(store.Len() < 16, "expired keys in the shard should be swept")
+}
+
+func TestRateLimiterUsesItsClock(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(NewSlidingWindow(1, time.Minute))
+	limiter.now = func() time.Time { return now }
+
+	status, err := limiter.Take("a")
+	assert.Nil(err)
+	assert.True(status.Allowed)
+	status, err = limiter.Take("a")
+	assert.Nil(err)
+	assert.False(status.Allowed)
+
+	now = now.Add(10 * time.Minute)
+	status, err = limiter.Take("a")
+	assert.Nil(err)
+	assert.True(status.Allowed)
+	assert.Equal(now.Truncate(time.Minute).Add(time.Minute), status.Reset)
+}
+
+func TestRateLimitPoliciesRejectNonPositiveLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	panics := func(constructor func()) (didPanic bool) {
+		defer func() {
+			didPanic = recover() != nil
+		}()
+		constructor()
+		return
+	}
+	assert.True(panics(func() { NewTokenBucket(0, time.Second) }))
+	assert.True(panics(func() { NewTokenBucket(1, 0) }))
+	assert.True(panics(func() { NewSlidingWindow(-1, time.Second) }))
+	assert.True(panics(func() { NewSlidingWindow(1, -time.Second) }))
+	assert.False(panics(func() { NewTokenBucket(1, time.Second) }))
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	assert := assert.New(t)
+
+	limiter := NewRateLimiter(NewTokenBucket(1, time.Minute)).WithKey(RateLimitByHeader("X-API-Key"))
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		return r.API().OK()
+	}, limiter.Middleware, APIProviderAsDefault)
+
+	request := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if len(apiKey) > 0 {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+
+	res := request("a")
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("1", res.Header().Get(HeaderXRateLimitLimit))
+	assert.Equal("0", res.Header().Get(HeaderXRateLimitRemaining))
+	assert.NotEmpty(res.Header().Get(HeaderXRateLimitReset))
+
+	res = request("a")
+	assert.Equal(http.StatusTooManyRequests, res.Code)
+	assert.Equal("60", res.Header().Get(HeaderRetryAfter))
+	assert.Contains("Too Many Requests", res.Body.String())
+	assert.Equal(ContentTypeApplicationJSON, res.Header().Get(HeaderContentType))
+
+	res = request("b")
+	assert.Equal(http.StatusOK, res.Code)
+
+	res = request("")
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Empty(res.Header().Get(HeaderXRateLimitLimit))
+}
+
+func TestStatusResult(t *testing.T) {
+	assert := assert.New(t)
+
+	result := StatusResult(NewTextResultProvider(nil), http.StatusTooManyRequests, "")
+	raw, isRaw := result.(*RawResult)
+	assert.True(isRaw)
+	assert.Equal(http.StatusTooManyRequests, raw.StatusCode)
+	assert.Equal("Too Many Requests", string(raw.Body))
+
+	problem := StatusResult(NewProblemResultProvider(nil), http.StatusServiceUnavailable, "down").(*ProblemResult)
+	assert.Equal(http.StatusServiceUnavailable, problem.Problem.Status)
+	assert.Equal("down", problem.Problem.Detail)
+}
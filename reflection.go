@@ -4,10 +4,21 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	exception "github.com/blendlabs/go-exception"
 )
 
+const (
+	// bindDateLayout is the date only layout accepted for `time.Time` fields, alongside RFC 3339.
+	bindDateLayout = "2006-01-02"
+)
+
+var (
+	reflectTypeTime     = reflect.TypeOf(time.Time{})
+	reflectTypeDuration = reflect.TypeOf(time.Duration(0))
+)
+
 // ReflectValue returns the integral reflect.Value for an object.
 func reflectValue(obj interface{}) reflect.Value {
 	v := reflect.ValueOf(obj)
@@ -83,88 +94,104 @@ func setValueByName(target interface{}, fieldName string, fieldValue interface{}
 		return exception.New("Reflected value is invalid, cannot continue.")
 	}
 
-	if valueReflected.Type().AssignableTo(fieldType) {
-		field.Set(valueReflected)
+	if field.Kind() == reflect.Ptr && !valueReflected.Type().AssignableTo(fieldType) {
+		convertedValue, err := convertValue(valueReflected, fieldType.Elem())
+		if err != nil {
+			return err
+		}
+		pointer := reflect.New(fieldType.Elem())
+		pointer.Elem().Set(convertedValue)
+		field.Set(pointer)
 		return nil
 	}
 
-	if field.Kind() == reflect.Ptr {
-		if valueReflected.CanAddr() {
-			convertedValue := valueReflected.Convert(fieldType.Elem())
-			if convertedValue.CanAddr() {
-				field.Set(convertedValue.Addr())
-				return nil
-			}
-		}
-		return exception.New("Cannot take address of value for assignment to field pointer")
+	convertedValue, err := convertValue(valueReflected, fieldType)
+	if err != nil {
+		return err
+	}
+	field.Set(convertedValue)
+	return nil
+}
+
+// convertValue converts a value to a type, parsing strings for numbers, bools, `time.Time` (RFC 3339 or `2006-01-02`)
+// and `time.Duration`.
+func convertValue(valueReflected reflect.Value, fieldType reflect.Type) (reflect.Value, error) {
+	if valueReflected.Type().AssignableTo(fieldType) {
+		return valueReflected, nil
 	}
 
 	if fieldAsString, isString := valueReflected.Interface().(string); isString {
+		switch fieldType {
+		case reflectTypeTime:
+			for _, layout := range []string{time.RFC3339Nano, bindDateLayout} {
+				if timeValue, err := time.Parse(layout, fieldAsString); err == nil {
+					return reflect.ValueOf(timeValue), nil
+				}
+			}
+			return reflect.Value{}, exception.Newf("cannot parse `%s` as a time", fieldAsString)
+		case reflectTypeDuration:
+			durationValue, err := time.ParseDuration(fieldAsString)
+			if err != nil {
+				return reflect.Value{}, exception.Wrap(err)
+			}
+			return reflect.ValueOf(durationValue), nil
+		}
+
 		var parsedValue reflect.Value
 		handledType := true
 		switch fieldType.Kind() {
 		case reflect.Int:
 			intValue, err := strconv.Atoi(fieldAsString)
 			if err != nil {
-				return exception.Wrap(err)
+				return reflect.Value{}, exception.Wrap(err)
 			}
 			parsedValue = reflect.ValueOf(intValue)
+		case reflect.Int8, reflect.Int16, reflect.Int32:
+			intValue, err := strconv.ParseInt(fieldAsString, 10, fieldType.Bits())
+			if err != nil {
+				return reflect.Value{}, exception.Wrap(err)
+			}
+			parsedValue = reflect.ValueOf(intValue).Convert(fieldType)
 		case reflect.Int64:
 			int64Value, err := strconv.ParseInt(fieldAsString, 10, 64)
 			if err != nil {
-				return exception.Wrap(err)
+				return reflect.Value{}, exception.Wrap(err)
 			}
 			parsedValue = reflect.ValueOf(int64Value)
-		case reflect.Uint16:
-			intValue, err := strconv.Atoi(fieldAsString)
+		case reflect.Bool:
+			boolValue, err := strconv.ParseBool(fieldAsString)
 			if err != nil {
-				return exception.Wrap(err)
+				return reflect.Value{}, exception.Wrap(err)
 			}
-			parsedValue = reflect.ValueOf(uint16(intValue))
-		case reflect.Uint: //a.k.a. uint32
-			intValue, err := strconv.Atoi(fieldAsString)
+			parsedValue = reflect.ValueOf(boolValue)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			uintValue, err := strconv.ParseUint(fieldAsString, 10, fieldType.Bits())
 			if err != nil {
-				return exception.Wrap(err)
+				return reflect.Value{}, exception.Wrap(err)
 			}
-			parsedValue = reflect.ValueOf(uint(intValue))
-		case reflect.Uint32:
-			intValue, err := strconv.Atoi(fieldAsString)
-			if err != nil {
-				return exception.Wrap(err)
-			}
-			parsedValue = reflect.ValueOf(uint32(intValue))
-		case reflect.Uint64:
-			intValue, err := strconv.Atoi(fieldAsString)
-			if err != nil {
-				return exception.Wrap(err)
-			}
-			parsedValue = reflect.ValueOf(uint64(intValue))
+			parsedValue = reflect.ValueOf(uintValue)
 		case reflect.Float32:
 			floatValue, err := strconv.ParseFloat(fieldAsString, 32)
 			if err != nil {
-				return exception.Wrap(err)
+				return reflect.Value{}, exception.Wrap(err)
 			}
-			parsedValue = reflect.ValueOf(floatValue)
+			parsedValue = reflect.ValueOf(float32(floatValue))
 		case reflect.Float64:
 			floatValue, err := strconv.ParseFloat(fieldAsString, 64)
 			if err != nil {
-				return exception.Wrap(err)
+				return reflect.Value{}, exception.Wrap(err)
 			}
 			parsedValue = reflect.ValueOf(floatValue)
 		default:
 			handledType = false
 		}
 		if handledType {
-			field.Set(parsedValue)
-			return nil
+			return parsedValue.Convert(fieldType), nil
 		}
 	}
 
-	convertedValue := valueReflected.Convert(fieldType)
-	if convertedValue.IsValid() && convertedValue.Type().AssignableTo(fieldType) {
-		field.Set(convertedValue)
-		return nil
+	if valueReflected.Type().ConvertibleTo(fieldType) {
+		return valueReflected.Convert(fieldType), nil
 	}
-
-	return exception.New("Couldnt set field %s.%s", targetType.Name(), fieldName)
+	return reflect.Value{}, exception.Newf("cannot convert %v to %v", valueReflected.Type(), fieldType)
 }
@@ -2,6 +2,7 @@ package web
 
 import (
 	"testing"
+	"time"
 
 	assert "github.com/blendlabs/go-assert"
 )
@@ -17,6 +18,11 @@ type testObject struct {
 	UInt64  uint64
 	Float32 float32
 	Float64 float64
+
+	IntPtr   *int
+	Time     time.Time
+	TimePtr  *time.Time
+	Duration time.Duration
 }
 
 func TestSetValueByName(t *testing.T) {
@@ -51,3 +57,38 @@ func TestSetValueByName(t *testing.T) {
 	err = setValueByName(&myObj, "Int", "hello")
 	assert.NotNil(err)
 }
+
+func TestSetValueByNamePointers(t *testing.T) {
+	assert := assert.New(t)
+
+	myObj := testObject{}
+	assert.Nil(setValueByName(&myObj, "Ptr", "hello"))
+	assert.NotNil(myObj.Ptr)
+	assert.Equal("hello", *myObj.Ptr)
+
+	assert.Nil(setValueByName(&myObj, "IntPtr", "42"))
+	assert.NotNil(myObj.IntPtr)
+	assert.Equal(42, *myObj.IntPtr)
+
+	assert.Nil(setValueByName(&myObj, "IntPtr", 7))
+	assert.Equal(7, *myObj.IntPtr)
+
+	assert.NotNil(setValueByName(&myObj, "IntPtr", "not a number"))
+}
+
+func TestSetValueByNameTimes(t *testing.T) {
+	assert := assert.New(t)
+
+	myObj := testObject{}
+	assert.Nil(setValueByName(&myObj, "Time", "2017-06-01T12:30:00Z"))
+	assert.Equal(time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC), myObj.Time)
+
+	assert.Nil(setValueByName(&myObj, "TimePtr", "2017-06-01"))
+	assert.NotNil(myObj.TimePtr)
+	assert.Equal(time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC), *myObj.TimePtr)
+
+	assert.NotNil(setValueByName(&myObj, "Time", "yesterday"))
+
+	assert.Nil(setValueByName(&myObj, "Duration", "1m30s"))
+	assert.Equal(90*time.Second, myObj.Duration)
+}
@@ -0,0 +1,115 @@
+package web
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	logger "github.com/blendlabs/go-logger"
+)
+
+const (
+	// RequestIDMaxLength is the longest incoming request id that is accepted.
+	RequestIDMaxLength = 128
+)
+
+// RequestIDGenerator returns a new request id.
+type RequestIDGenerator func() string
+
+// NewRequestID returns a new random request id, 32 hex characters long.
+func NewRequestID() string {
+	id, err := String.GenerateRandomBytes(16)
+	if err != nil {
+		return String.RandomWithNumbers(32)
+	}
+	return hex.EncodeToString(id)
+}
+
+// IsValidRequestID returns if an incoming request id can be used as is.
+// It must be no longer than `RequestIDMaxLength` and only contain letters, digits, `-`, `_`, `.` or `:`,
+// so it can't be used to forge log lines.
+func IsValidRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > RequestIDMaxLength {
+		return false
+	}
+	for index := 0; index < len(requestID); index++ {
+		switch c := requestID[index]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDLogger prefixes log lines written for a request with its request id.
+type requestIDLogger struct {
+	logger.Logger
+	requestID string
+}
+
+// Printf writes a formatted log line.
+func (ril requestIDLogger) Printf(format string, args ...interface{}) (int64, error) {
+	return ril.Logger.Printf("[%s] "+format, append([]interface{}{ril.requestID}, args...)...)
+}
+
+// PrintfWithTimeSource writes a formatted log line.
+func (ril requestIDLogger) PrintfWithTimeSource(ts logger.TimeSource, format string, args ...interface{}) (int64, error) {
+	return ril.Logger.PrintfWithTimeSource(ts, "[%s] "+format, append([]interface{}{ril.requestID}, args...)...)
+}
+
+// Errorf writes a formatted error log line.
+func (ril requestIDLogger) Errorf(format string, args ...interface{}) (int64, error) {
+	return ril.Logger.Errorf("[%s] "+format, append([]interface{}{ril.requestID}, args...)...)
+}
+
+// withRequestID returns a writer that prefixes lines with the ctx request id, if it has one.
+func withRequestID(writer logger.Logger, ctx *Ctx) logger.Logger {
+	if ctx == nil || len(ctx.requestID) == 0 {
+		return writer
+	}
+	return requestIDLogger{Logger: writer, requestID: ctx.requestID}
+}
+
+// ctxFromEventState returns the ctx an event was emitted with, if any.
+func ctxFromEventState(state []interface{}) *Ctx {
+	for _, value := range state {
+		if ctx, isCtx := value.(*Ctx); isCtx {
+			return ctx
+		}
+	}
+	return nil
+}
+
+// RequestIDHeader returns the header request ids are read from and echoed in.
+func (a *App) RequestIDHeader() string {
+	return a.requestIDHeader
+}
+
+// SetRequestIDHeader sets the header request ids are read from and echoed in.
+func (a *App) SetRequestIDHeader(header string) {
+	a.requestIDHeader = header
+}
+
+// RequestIDGenerator returns the func that generates request ids for requests without a valid one.
+func (a *App) RequestIDGenerator() RequestIDGenerator {
+	return a.requestIDGenerator
+}
+
+// SetRequestIDGenerator sets the func that generates request ids for requests without a valid one.
+func (a *App) SetRequestIDGenerator(generator RequestIDGenerator) {
+	a.requestIDGenerator = generator
+}
+
+// requestID returns the incoming request id, or a new one if the request does not have a valid one.
+func (a *App) requestID(req *http.Request) string {
+	if len(a.requestIDHeader) > 0 {
+		if requestID := req.Header.Get(a.requestIDHeader); IsValidRequestID(requestID) {
+			return requestID
+		}
+	}
+	if a.requestIDGenerator != nil {
+		return a.requestIDGenerator()
+	}
+	return NewRequestID()
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+	exception "github.com/blendlabs/go-exception"
+	logger "github.com/blendlabs/go-logger"
+)
+
+func TestIsValidRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsValidRequestID("abc-123_DEF.4:5"))
+	assert.True(IsValidRequestID(NewRequestID()))
+	assert.Len(NewRequestID(), 32)
+	assert.False(IsValidRequestID(""))
+	assert.False(IsValidRequestID("has space"))
+	assert.False(IsValidRequestID("new\nline"))
+	assert.False(IsValidRequestID(strings.Repeat("a", RequestIDMaxLength+1)))
+}
+
+func TestAppRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	var requestID string
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		requestID = r.RequestID()
+		return r.Text().Result("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderXRequestID, "upstream-id")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal("upstream-id", requestID)
+	assert.Equal("upstream-id", res.Header().Get(HeaderXRequestID))
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderXRequestID, "forged\n[error]")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.True(IsValidRequestID(requestID))
+	assert.NotEqual("upstream-id", requestID)
+	assert.Equal(requestID, res.Header().Get(HeaderXRequestID))
+
+	app.SetRequestIDGenerator(func() string { return "generated" })
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	assert.Equal("generated", res.Header().Get(HeaderXRequestID))
+}
+
+func TestAPIResultProviderRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		return r.API().NotFound()
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderXRequestID, "support-ticket-id")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(http.StatusNotFound, res.Code)
+
+	var response APIResponse
+	assert.Nil(json.Unmarshal(res.Body.Bytes(), &response))
+	assert.Equal("support-ticket-id", response.Meta.RequestID)
+}
+
+func TestAppLogsRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	agent := logger.New(logger.NewEventFlagSetWithEvents(logger.EventWebRequestStart, logger.EventWebRequest), logger.NewLogWriter(buffer))
+
+	app := New()
+	app.SetLogger(agent)
+	app.GET("/", func(r *Ctx) Result {
+		return r.Text().Result("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderXRequestID, "correlate-me")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Nil(agent.Drain())
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(lines, 2)
+	for _, line := range lines {
+		assert.Contains("[correlate-me]", line)
+	}
+}
+
+func TestAppLogsRequestIDOnErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	agent := logger.New(logger.NewEventFlagSetWithEvents(logger.EventFatalError, logger.EventError), logger.NewLogWriter(buffer))
+
+	app := New()
+	app.SetLogger(agent)
+	app.GET("/fatal", func(r *Ctx) Result {
+		return r.JSON().InternalError(exception.New("fatal failure"))
+	})
+	app.GET("/render", func(r *Ctx) Result {
+		return r.JSON().Result(make(chan int))
+	})
+
+	for _, path := range []string{"/fatal", "/render"} {
+		buffer.Reset()
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(HeaderXRequestID, "correlate-me")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Nil(agent.Drain())
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		assert.Len(lines, 1)
+		assert.Contains("[correlate-me]", lines[0])
+	}
+}
@@ -3,7 +3,8 @@ package web
 import "net/http"
 
 // ResponseWriter is a super-type of http.ResponseWriter that includes
-// the StatusCode and ContentLength for the request
+// the StatusCode and ContentLength for the request.
+// It is an `http.Flusher`; `FlushError` flushes and returns any error.
 type ResponseWriter interface {
 	Header() http.Header
 	Write([]byte) (int, error)
@@ -12,6 +13,7 @@ type ResponseWriter interface {
 	StatusCode() int
 	ContentLength() int
 	Bytes() []byte
-	Flush() error
+	Flush()
+	FlushError() error
 	Close() error
 }
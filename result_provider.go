@@ -1,5 +1,7 @@
 package web
 
+import "net/http"
+
 // ResultProvider is the provider interface for results.
 type ResultProvider interface {
 	InternalError(err error) Result
@@ -8,3 +10,40 @@ type ResultProvider interface {
 	NotAuthorized() Result
 	Result(response interface{}) Result
 }
+
+// StatusResultProvider is a result provider that can return results for any status code.
+type StatusResultProvider interface {
+	Status(statusCode int, message string) Result
+}
+
+// StatusResult returns a result for a status code from a provider.
+// Providers that do not implement `StatusResultProvider` fall back to a plain text result.
+func StatusResult(provider ResultProvider, statusCode int, message string) Result {
+	if typed, isTyped := provider.(StatusResultProvider); isTyped {
+		return typed.Status(statusCode, message)
+	}
+	return NewTextResultProvider(nil).Status(statusCode, message)
+}
+
+// BadRequestErrorProvider is a result provider that can return bad request results for errors,
+// e.g. including the field failures of validation and bind errors.
+type BadRequestErrorProvider interface {
+	BadRequestError(err error) Result
+}
+
+// BadRequestErrorResult returns a bad request result for an error from a provider.
+// Providers that do not implement `BadRequestErrorProvider` are given the error message.
+func BadRequestErrorResult(provider ResultProvider, err error) Result {
+	if typed, isTyped := provider.(BadRequestErrorProvider); isTyped {
+		return typed.BadRequestError(err)
+	}
+	return provider.BadRequest(err.Error())
+}
+
+// statusMessage returns the message, or the status text if the message is empty.
+func statusMessage(statusCode int, message string) string {
+	if len(message) > 0 {
+		return message
+	}
+	return http.StatusText(statusCode)
+}
@@ -1,13 +1,17 @@
 package web
 
 import (
-	"database/sql"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+
+	exception "github.com/blendlabs/go-exception"
 )
 
 // Handler is the most basic route handler.
-type Handler func(http.ResponseWriter, *http.Request, *Route, RouteParameters, *sql.Tx)
+// Request scoped values, like a transaction (see `WithContextTx`), are carried by the request context.
+type Handler func(http.ResponseWriter, *http.Request, *Route, RouteParameters)
 
 // PanicHandler is a handler for panics that also takes an error.
 type PanicHandler func(http.ResponseWriter, *http.Request, interface{})
@@ -18,6 +22,10 @@ type Route struct {
 	Method string
 	Path   string
 	Params []string
+	Name   string
+	CORS   *CORS
+
+	MaxBodySize int64
 }
 
 // String returns a string representation of the route.
@@ -25,3 +33,70 @@ type Route struct {
 func (r Route) String() string {
 	return fmt.Sprintf("%s_%s", r.Method, r.Path)
 }
+
+// WithName sets the name of the route, which can then be used with `App.URLFor`.
+func (r *Route) WithName(name string) *Route {
+	r.Name = name
+	return r
+}
+
+// WithCORS sets the CORS config for the route, overriding the app wide config.
+func (r *Route) WithCORS(cors *CORS) *Route {
+	r.CORS = cors
+	return r
+}
+
+// WithMaxBodySize sets the request body size limit for the route, overriding the app wide limit.
+func (r *Route) WithMaxBodySize(maxBodySize int64) *Route {
+	r.MaxBodySize = maxBodySize
+	return r
+}
+
+// URL returns the route path with the `:param` and `*catchAll` segments filled in from the given parameters.
+// Any parameters that do not appear in the path are added to the query string.
+func (r Route) URL(params RouteParameters) (string, error) {
+	segments := strings.Split(r.Path, "/")
+	used := map[string]bool{}
+	for index, segment := range segments {
+		if len(segment) < 2 || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+
+		name := segment[1:]
+		value, hasValue := params[name]
+		if !hasValue {
+			return "", exception.Newf("route `%s` requires parameter `%s`", r.String(), name)
+		}
+		used[name] = true
+
+		if segment[0] == ':' {
+			segments[index] = url.PathEscape(value)
+		} else {
+			segments[index] = strings.TrimPrefix(value, "/")
+		}
+	}
+
+	path := strings.Join(segments, "/")
+	if len(used) == len(params) {
+		return path, nil
+	}
+
+	query := url.Values{}
+	for key, value := range params {
+		if !used[key] {
+			query.Set(key, value)
+		}
+	}
+	return path + "?" + query.Encode(), nil
+}
+
+// routeParamNames returns the names of the `:param` and `*catchAll` segments of a route path.
+func routeParamNames(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			names = append(names, segment[1:])
+		}
+	}
+	return names
+}
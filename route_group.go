@@ -0,0 +1,140 @@
+package web
+
+import "strings"
+
+// Registrar is the set of route registration methods shared by the app and route groups.
+type Registrar interface {
+	GET(path string, action Action, middleware ...Middleware) *Route
+	OPTIONS(path string, action Action, middleware ...Middleware) *Route
+	HEAD(path string, action Action, middleware ...Middleware) *Route
+	PUT(path string, action Action, middleware ...Middleware) *Route
+	PATCH(path string, action Action, middleware ...Middleware) *Route
+	POST(path string, action Action, middleware ...Middleware) *Route
+	DELETE(path string, action Action, middleware ...Middleware) *Route
+	WebSocket(path string, handler WebSocketHandler, middleware ...Middleware) *Route
+	Group(prefix string, middleware ...Middleware) *RouteGroup
+}
+
+// NewRouteGroup returns a new route group for an app.
+func NewRouteGroup(app *App, prefix string, middleware ...Middleware) *RouteGroup {
+	return &RouteGroup{
+		app:        app,
+		prefix:     cleanGroupPrefix(prefix),
+		middleware: middleware,
+	}
+}
+
+// RouteGroup is a set of routes that share a path prefix and middleware.
+// Group middleware runs after the app default middleware and before the route middleware.
+type RouteGroup struct {
+	app        *App
+	parent     *RouteGroup
+	prefix     string
+	middleware []Middleware
+}
+
+// App returns the app the group registers routes with.
+func (rg *RouteGroup) App() *App {
+	return rg.app
+}
+
+// Parent returns the parent group (if any).
+func (rg *RouteGroup) Parent() *RouteGroup {
+	return rg.parent
+}
+
+// Prefix returns the full path prefix for the group, including any parent prefixes.
+func (rg *RouteGroup) Prefix() string {
+	return rg.prefix
+}
+
+// Middleware returns the middleware for the group, not including any parent middleware.
+func (rg *RouteGroup) Middleware() []Middleware {
+	return rg.middleware
+}
+
+// Group returns a nested group with the given prefix and middleware.
+func (rg *RouteGroup) Group(prefix string, middleware ...Middleware) *RouteGroup {
+	return &RouteGroup{
+		app:        rg.app,
+		parent:     rg,
+		prefix:     rg.prefix + cleanGroupPrefix(prefix),
+		middleware: middleware,
+	}
+}
+
+// Register registers a controller under the group.
+func (rg *RouteGroup) Register(c RegistrarController) {
+	c.Register(rg)
+}
+
+// GET registers a GET request handler.
+func (rg *RouteGroup) GET(path string, action Action, middleware ...Middleware) *Route {
+	return rg.app.GET(rg.path(path), action, rg.middlewareFor(middleware)...)
+}
+
+// OPTIONS registers a OPTIONS request handler.
+func (rg *RouteGroup) OPTIONS(path string, action Action, middleware ...Middleware) *Route {
+	return rg.app.OPTIONS(rg.path(path), action, rg.middlewareFor(middleware)...)
+}
+
+// HEAD registers a HEAD request handler.
+func (rg *RouteGroup) HEAD(path string, action Action, middleware ...Middleware) *Route {
+	return rg.app.HEAD(rg.path(path), action, rg.middlewareFor(middleware)...)
+}
+
+// PUT registers a PUT request handler.
+func (rg *RouteGroup) PUT(path string, action Action, middleware ...Middleware) *Route {
+	return rg.app.PUT(rg.path(path), action, rg.middlewareFor(middleware)...)
+}
+
+// PATCH registers a PATCH request handler.
+func (rg *RouteGroup) PATCH(path string, action Action, middleware ...Middleware) *Route {
+	return rg.app.PATCH(rg.path(path), action, rg.middlewareFor(middleware)...)
+}
+
+// POST registers a POST request handler.
+func (rg *RouteGroup) POST(path string, action Action, middleware ...Middleware) *Route {
+	return rg.app.POST(rg.path(path), action, rg.middlewareFor(middleware)...)
+}
+
+// DELETE registers a DELETE request handler.
+func (rg *RouteGroup) DELETE(path string, action Action, middleware ...Middleware) *Route {
+	return rg.app.DELETE(rg.path(path), action, rg.middlewareFor(middleware)...)
+}
+
+// WebSocket registers a websocket handler.
+func (rg *RouteGroup) WebSocket(path string, handler WebSocketHandler, middleware ...Middleware) *Route {
+	return rg.app.WebSocket(rg.path(path), handler, rg.middlewareFor(middleware)...)
+}
+
+func (rg *RouteGroup) path(path string) string {
+	if len(path) == 0 || path[0] != '/' {
+		path = "/" + path
+	}
+	return rg.prefix + path
+}
+
+// middlewareFor returns the route middleware followed by the group's middleware chain.
+// Because the last middleware in the list runs first, this means outer groups run
+// before inner groups, which run before the route middleware.
+func (rg *RouteGroup) middlewareFor(middleware []Middleware) []Middleware {
+	var final []Middleware
+	final = append(final, middleware...)
+	for group := rg; group != nil; group = group.parent {
+		final = append(final, group.middleware...)
+	}
+	return final
+}
+
+// cleanGroupPrefix ensures a prefix has a leading slash and no trailing slash.
+func cleanGroupPrefix(prefix string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if len(prefix) == 0 {
+		return ""
+	}
+	if prefix[0] != '/' {
+		return "/" + prefix
+	}
+	return prefix
+}
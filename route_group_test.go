@@ -0,0 +1,107 @@
+package web
+
+import (
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type mockRegistrarController struct{}
+
+func (mrc mockRegistrarController) Register(r Registrar) {
+	r.GET("/foo", func(ctx *Ctx) Result {
+		return ctx.Raw([]byte("foo"))
+	})
+}
+
+func orderMiddleware(order *[]string, label string) Middleware {
+	return func(action Action) Action {
+		return func(ctx *Ctx) Result {
+			*order = append(*order, label)
+			return action(ctx)
+		}
+	}
+}
+
+func TestRouteGroupPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	api := app.Group("/api/v1/")
+	assert.Equal("/api/v1", api.Prefix())
+
+	api.GET("/users/:id", func(ctx *Ctx) Result {
+		id, _ := ctx.RouteParam("id")
+		return ctx.Raw([]byte(id))
+	})
+
+	res, err := app.Mock().Get("/api/v1/users/123").Bytes()
+	assert.Nil(err)
+	assert.Equal("123", string(res))
+}
+
+func TestRouteGroupNestedMiddlewareOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	var order []string
+	app := New()
+	app.SetDefaultMiddleware(orderMiddleware(&order, "default"))
+
+	api := app.Group("api", orderMiddleware(&order, "api"))
+	v1 := api.Group("v1", orderMiddleware(&order, "v1"))
+	assert.Equal("/api/v1", v1.Prefix())
+	assert.Equal(api, v1.Parent())
+
+	v1.POST("/things", func(ctx *Ctx) Result {
+		order = append(order, "action")
+		return ctx.NoContent()
+	}, orderMiddleware(&order, "route"))
+
+	assert.Nil(app.Mock().Post("/api/v1/things").Execute())
+	assert.Equal([]string{"default", "api", "v1", "route", "action"}, order)
+}
+
+func TestRouteGroupRegister(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.Group("/v2").Register(mockRegistrarController{})
+	app.Register(mockRegistrarController{})
+
+	res, err := app.Mock().Get("/v2/foo").Bytes()
+	assert.Nil(err)
+	assert.Equal("foo", string(res))
+
+	res, err = app.Mock().Get("/foo").Bytes()
+	assert.Nil(err)
+	assert.Equal("foo", string(res))
+}
+
+type mockAppController struct{}
+
+func (mac mockAppController) Register(app *App) {
+	app.GET("/bar", func(ctx *Ctx) Result {
+		return ctx.Text().Result("bar")
+	})
+}
+
+func TestAppRegisterControllers(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.Register(mockAppController{})
+	app.Register(mockRegistrarController{})
+
+	res, err := app.Mock().Get("/bar").Bytes()
+	assert.Nil(err)
+	assert.Equal("bar", string(res))
+
+	res, err = app.Mock().Get("/foo").Bytes()
+	assert.Nil(err)
+	assert.Equal("foo", string(res))
+
+	defer func() {
+		assert.NotNil(recover(), "registering a non-controller should panic")
+	}()
+	app.Register("not a controller")
+}
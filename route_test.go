@@ -0,0 +1,83 @@
+package web
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestRouteURL(t *testing.T) {
+	assert := assert.New(t)
+
+	route := Route{Method: "GET", Path: "/users/:id/files/*filepath"}
+	path, err := route.URL(RouteParameters{"id": "a b", "filepath": "/docs/readme.md"})
+	assert.Nil(err)
+	assert.Equal("/users/a%20b/files/docs/readme.md", path)
+
+	path, err = route.URL(RouteParameters{"id": "1", "filepath": "x", "page": "2"})
+	assert.Nil(err)
+	assert.Equal("/users/1/files/x?page=2", path)
+
+	_, err = route.URL(RouteParameters{"id": "1"})
+	assert.NotNil(err)
+}
+
+func TestAppURLFor(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	route := app.GET("/users/:id", controllerNoOp).WithName("user")
+	assert.Equal([]string{"id"}, route.Params)
+	app.Group("/api").POST("/users/:id/roles/:role", controllerNoOp).WithName("user_role")
+
+	path, err := app.URLFor("user", RouteParameters{"id": "123"})
+	assert.Nil(err)
+	assert.Equal("/users/123", path)
+
+	path, err = app.URLFor("user_role", RouteParameters{"id": "123", "role": "admin"})
+	assert.Nil(err)
+	assert.Equal("/api/users/123/roles/admin", path)
+
+	_, err = app.URLFor("not_a_route", nil)
+	assert.NotNil(err)
+
+	app.GET("/unnamed", controllerNoOp)
+	assert.Nil(app.RouteByName(""))
+	_, err = app.URLFor("", nil)
+	assert.NotNil(err)
+}
+
+func TestCtxRedirectToRoute(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/users/:id", controllerNoOp).WithName("user")
+	app.GET("/", func(ctx *Ctx) Result {
+		return ctx.RedirectToRoute("user", RouteParameters{"id": "123"})
+	})
+
+	meta, err := app.Mock().Get("/").ExecuteWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusTemporaryRedirect, meta.StatusCode)
+	assert.Equal("/users/123", meta.Headers.Get("Location"))
+}
+
+func TestViewCacheURLFor(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/users/:id", controllerNoOp).WithName("user")
+
+	view := template.Must(template.New("test").Funcs(app.ViewCache().FuncMap()).Parse(`{{ url_for "user" "id" .ViewModel }}`))
+	app.ViewCache().SetTemplates(view)
+	app.GET("/", func(ctx *Ctx) Result {
+		return ctx.View().View("test", "123")
+	})
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(app.Mock().Get("/").WithResponseBuffer(buffer).Execute())
+	assert.Equal("/users/123", buffer.String())
+}
@@ -7,22 +7,43 @@ import (
 
 // NewSession returns a new session object.
 func NewSession(userID int64, sessionID string) *Session {
+	now := time.Now().UTC()
 	return &Session{
-		UserID:     userID,
-		SessionID:  sessionID,
-		CreatedUTC: time.Now().UTC(),
-		State:      map[string]interface{}{},
-		lock:       &sync.RWMutex{},
+		UserID:      userID,
+		SessionID:   sessionID,
+		CreatedUTC:  now,
+		LastSeenUTC: now,
+		State:       map[string]interface{}{},
+		lock:        &sync.RWMutex{},
 	}
 }
 
 // Session is an active session
 type Session struct {
-	UserID     int64
-	SessionID  string
-	CreatedUTC time.Time
-	State      map[string]interface{}
-	lock       *sync.RWMutex
+	UserID      int64
+	SessionID   string
+	CreatedUTC  time.Time
+	LastSeenUTC time.Time
+	State       map[string]interface{}
+	lock        *sync.RWMutex
+	// csrfLock guards the csrf token in the session state, as the session lock may already be held by middleware.
+	csrfLock sync.Mutex
+	// lastSeenLock guards `LastSeenUTC`, which is updated on every request without taking the session lock.
+	lastSeenLock sync.Mutex
+}
+
+// LastSeen returns when the session was last used.
+func (s *Session) LastSeen() time.Time {
+	s.lastSeenLock.Lock()
+	defer s.lastSeenLock.Unlock()
+	return s.LastSeenUTC
+}
+
+// MarkSeen records when the session was last used; session stores call it from `Touch`.
+func (s *Session) MarkSeen(asOf time.Time) {
+	s.lastSeenLock.Lock()
+	s.LastSeenUTC = asOf
+	s.lastSeenLock.Unlock()
 }
 
 func (s *Session) ensureLock() {
@@ -36,6 +57,24 @@ func (s *Session) IsZero() bool {
 	return s.UserID == 0 || len(s.SessionID) == 0
 }
 
+// IsExpired returns if the session is older than the lifetime, or has been idle longer than the idle timeout.
+// A zero lifetime or idle timeout disables that check.
+func (s *Session) IsExpired(asOf time.Time, lifetime, idleTimeout time.Duration) bool {
+	if lifetime > 0 && !s.CreatedUTC.IsZero() && asOf.Sub(s.CreatedUTC) > lifetime {
+		return true
+	}
+	if idleTimeout > 0 {
+		lastSeen := s.LastSeen()
+		if lastSeen.IsZero() {
+			lastSeen = s.CreatedUTC
+		}
+		if !lastSeen.IsZero() && asOf.Sub(lastSeen) > idleTimeout {
+			return true
+		}
+	}
+	return false
+}
+
 // Lock locks the session.
 func (s *Session) Lock() {
 	s.ensureLock()
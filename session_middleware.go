@@ -17,7 +17,7 @@ func SessionAwareLockFree(action Action) Action {
 
 func sessionAware(action Action, sessionLockPolicy int) Action {
 	return func(context *Ctx) Result {
-		session, err := context.Auth().VerifySession(context)
+		session, err := verifySession(context)
 		if err != nil && err != ErrSessionIDInvalid {
 			return context.DefaultResultProvider().InternalError(err)
 		}
@@ -63,7 +63,7 @@ func SessionRequiredLockFree(action Action) Action {
 
 func sessionRequired(action Action, sessionLockPolicy int) Action {
 	return func(context *Ctx) Result {
-		session, err := context.Auth().VerifySession(context)
+		session, err := verifySession(context)
 		if err != nil {
 			return context.DefaultResultProvider().InternalError(err)
 		}
@@ -90,3 +90,47 @@ func sessionRequired(action Action, sessionLockPolicy int) Action {
 		return action(context)
 	}
 }
+
+// verifySession verifies the session for a request and marks it active.
+// If an outer session middleware has already set the session on the context it is reused as is,
+// which avoids re-acquiring session locks the outer middleware may be holding.
+func verifySession(context *Ctx) (*Session, error) {
+	if session := context.Session(); session != nil {
+		return session, nil
+	}
+
+	session, err := context.Auth().VerifySession(context)
+	if err != nil || session == nil {
+		return nil, err
+	}
+
+	err = context.Auth().MarkSessionActive(context, session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CSRFProtected is an action that verifies the csrf token on requests with unsafe methods.
+// It should be nested inside a session middleware when using synchronizer tokens.
+// Requests exempted by the auth manager csrf config are not checked.
+func CSRFProtected(action Action) Action {
+	return func(context *Ctx) Result {
+		switch context.Request.Method {
+		case "GET", "HEAD", "OPTIONS", "TRACE":
+			return action(context)
+		}
+
+		auth := context.Auth()
+		if auth == nil || auth.CSRF() == nil {
+			return context.DefaultResultProvider().InternalError(ErrCSRFNotConfigured)
+		}
+		if auth.CSRF().IsExempt(context) {
+			return action(context)
+		}
+		if err := auth.CSRF().Verify(context); err != nil {
+			return context.DefaultResultProvider().NotAuthorized()
+		}
+		return action(context)
+	}
+}
@@ -20,7 +20,7 @@ func TestSessionAware(t *testing.T) {
 		return r.Text().Result("COOL")
 	}, SessionAware)
 
-	app.Auth().SessionCache().Add(&Session{
+	app.Auth().SessionStore().Put(&Session{
 		SessionID: sessionID,
 	})
 
@@ -48,7 +48,7 @@ func TestSessionRequired(t *testing.T) {
 		return r.Text().Result("COOL")
 	}, SessionRequired)
 
-	app.Auth().SessionCache().Add(&Session{
+	app.Auth().SessionStore().Put(&Session{
 		SessionID: sessionID,
 	})
 
@@ -77,7 +77,7 @@ func TestSessionRequiredCustomParamName(t *testing.T) {
 		return r.Text().Result("COOL")
 	}, SessionRequired)
 
-	app.Auth().SessionCache().Add(&Session{
+	app.Auth().SessionStore().Put(&Session{
 		SessionID: sessionID,
 	})
 
@@ -0,0 +1,16 @@
+package web
+
+// SessionStore is a store for active sessions.
+// Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Get returns a session by id, or (nil, nil) if the session is not found or has expired.
+	Get(sessionID string) (*Session, error)
+	// Put adds or replaces a session.
+	Put(session *Session) error
+	// Remove removes a session by id.
+	Remove(sessionID string) error
+	// Touch marks a session as recently used, extending its lifetime in the store.
+	// Sessions it returns from later calls to `Get` must have the time of the touch as their `LastSeenUTC`
+	// (set with `Session.MarkSeen` on sessions other requests may hold), which the auth manager uses to enforce the idle timeout.
+	Touch(sessionID string) error
+}
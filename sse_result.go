@@ -0,0 +1,161 @@
+package web
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultSSEHeartbeatInterval is the default interval heartbeat comments are sent on an idle event stream.
+	DefaultSSEHeartbeatInterval = 15 * time.Second
+
+	// DefaultSSEWriteTimeoutMargin is how long before the server write timeout an event stream is ended,
+	// so that it closes cleanly and the client reconnects instead of being cut off.
+	DefaultSSEWriteTimeoutMargin = time.Second
+)
+
+// SSEEvent is a server-sent event.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// WriteTo writes the event as a frame.
+func (e SSEEvent) WriteTo(w io.Writer) (int64, error) {
+	buffer := bytes.NewBuffer(nil)
+	if len(e.ID) > 0 {
+		buffer.WriteString("id: " + sseFieldValue(e.ID) + "\n")
+	}
+	if len(e.Event) > 0 {
+		buffer.WriteString("event: " + sseFieldValue(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buffer.WriteString("retry: " + strconv.FormatInt(int64(e.Retry/time.Millisecond), 10) + "\n")
+	}
+	if len(e.Data) > 0 || buffer.Len() == 0 {
+		for _, line := range strings.Split(strings.Replace(e.Data, "\r\n", "\n", -1), "\n") {
+			buffer.WriteString("data: " + strings.Replace(line, "\r", "", -1) + "\n")
+		}
+	}
+	buffer.WriteString("\n")
+	return buffer.WriteTo(w)
+}
+
+// sseFieldValue strips line breaks, which would end the field, from a value.
+func sseFieldValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// SSEResult is a `text/event-stream` result that writes events from a channel until the channel is closed,
+// the client disconnects, or the stream reaches its max duration.
+// Heartbeat comments are sent while the stream is idle, so intermediaries keep the connection open.
+// Event streams are never compressed.
+// Clients that reconnect can resume from the last event they received by setting `Replay`.
+type SSEResult struct {
+	Events <-chan SSEEvent
+
+	// LastEventID is the id of the last event a reconnecting client received; `ctx.SSE` sets it from the `Last-Event-ID` header.
+	LastEventID string
+	// Replay, if set, is called with the `LastEventID` of a reconnecting client and returns the events it missed,
+	// which are sent before any from `Events`.
+	Replay func(lastEventID string) []SSEEvent
+
+	// Retry, if set, is sent first to tell the client how long to wait before reconnecting.
+	Retry time.Duration
+	// HeartbeatInterval is how often heartbeats are sent while idle. It defaults to `DefaultSSEHeartbeatInterval`.
+	HeartbeatInterval time.Duration
+	// MaxDuration is how long the stream can stay open. If unset and the app has a write timeout,
+	// it defaults to ending `DefaultSSEWriteTimeoutMargin` before the timeout.
+	MaxDuration time.Duration
+}
+
+// Render renders the result.
+func (sr *SSEResult) Render(ctx *Ctx) error {
+	if disabler, isDisabler := ctx.Response.(interface {
+		disableCompression()
+	}); isDisabler {
+		disabler.disableCompression()
+	}
+
+	header := ctx.Response.Header()
+	header.Set(HeaderContentType, ContentTypeEventStream)
+	header.Set(HeaderCacheControl, "no-cache")
+	header.Del(HeaderContentLength)
+	ctx.Response.WriteHeader(http.StatusOK)
+
+	if sr.Retry > 0 {
+		if _, err := io.WriteString(ctx.Response, "retry: "+strconv.FormatInt(int64(sr.Retry/time.Millisecond), 10)+"\n\n"); err != nil {
+			return err
+		}
+	}
+	if sr.Replay != nil && len(sr.LastEventID) > 0 {
+		for _, event := range sr.Replay(sr.LastEventID) {
+			if _, err := event.WriteTo(ctx.Response); err != nil {
+				return nil // the client is gone
+			}
+		}
+	}
+	if err := ctx.Response.FlushError(); err != nil {
+		return err
+	}
+
+	heartbeatInterval := sr.HeartbeatInterval
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = DefaultSSEHeartbeatInterval
+	}
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	var deadline <-chan time.Time
+	if maxDuration := sr.maxDuration(ctx); maxDuration > 0 {
+		timer := time.NewTimer(maxDuration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	disconnected := ctx.Done()
+
+	for {
+		select {
+		case event, ok := <-sr.Events:
+			if !ok {
+				return nil
+			}
+			if _, err := event.WriteTo(ctx.Response); err != nil {
+				return nil // the client is gone
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(ctx.Response, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		case <-disconnected:
+			return nil
+		case <-deadline:
+			return nil
+		}
+		if err := ctx.Response.FlushError(); err != nil {
+			return nil
+		}
+	}
+}
+
+// maxDuration returns how long the stream may stay open, or 0 for no limit.
+func (sr *SSEResult) maxDuration(ctx *Ctx) time.Duration {
+	if sr.MaxDuration > 0 || ctx.app == nil || ctx.app.writeTimeout <= 0 {
+		return sr.MaxDuration
+	}
+	remaining := ctx.app.writeTimeout - DefaultSSEWriteTimeoutMargin
+	if !ctx.requestStart.IsZero() {
+		remaining -= time.Now().UTC().Sub(ctx.requestStart)
+	}
+	if remaining <= 0 {
+		return time.Millisecond
+	}
+	return remaining
+}
@@ -0,0 +1,170 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func TestSSEEventWriteTo(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	_, err := SSEEvent{ID: "1", Event: "progress", Data: "line one\nline two", Retry: 2 * time.Second}.WriteTo(buffer)
+	assert.Nil(err)
+	assert.Equal("id: 1\nevent: progress\nretry: 2000\ndata: line one\ndata: line two\n\n", buffer.String())
+
+	buffer.Reset()
+	_, err = SSEEvent{Event: "bad\nevent"}.WriteTo(buffer)
+	assert.Nil(err)
+	assert.Equal("event: badevent\n\n", buffer.String())
+}
+
+func TestSSEResultMock(t *testing.T) {
+	assert := assert.New(t)
+
+	var lastEventID string
+	app := New()
+	app.GET("/events", func(ctx *Ctx) Result {
+		lastEventID = ctx.LastEventID()
+		events := make(chan SSEEvent, 2)
+		events <- SSEEvent{ID: "2", Data: "hello"}
+		events <- SSEEvent{ID: "3", Event: "done", Data: "bye"}
+		close(events)
+		result := ctx.SSE(events)
+		result.Retry = time.Second
+		return result
+	})
+
+	contents, meta, err := app.Mock().WithPathf("/events").WithHeader(HeaderLastEventID, "1").WithHeader(HeaderAcceptEncoding, ContentEncodingGZIP).BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal("1", lastEventID)
+	assert.Equal(http.StatusOK, meta.StatusCode)
+	assert.Equal(ContentTypeEventStream, meta.Headers.Get(HeaderContentType))
+	assert.Empty(meta.Headers.Get(HeaderContentEncoding))
+	assert.Equal("retry: 1000\n\nid: 2\ndata: hello\n\nid: 3\nevent: done\ndata: bye\n\n", string(contents))
+}
+
+func TestSSEResultReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	history := []SSEEvent{{ID: "1", Data: "one"}, {ID: "2", Data: "two"}, {ID: "3", Data: "three"}}
+	app := New()
+	app.GET("/events", func(ctx *Ctx) Result {
+		events := make(chan SSEEvent, 1)
+		events <- SSEEvent{ID: "4", Data: "four"}
+		close(events)
+		result := ctx.SSE(events)
+		result.Replay = func(lastEventID string) []SSEEvent {
+			for index, event := range history {
+				if event.ID == lastEventID {
+					return history[index+1:]
+				}
+			}
+			return history
+		}
+		return result
+	})
+
+	contents, err := app.Mock().WithPathf("/events").WithHeader(HeaderLastEventID, "1").Bytes()
+	assert.Nil(err)
+	assert.Equal("id: 2\ndata: two\n\nid: 3\ndata: three\n\nid: 4\ndata: four\n\n", string(contents))
+
+	contents, err = app.Mock().WithPathf("/events").Bytes()
+	assert.Nil(err)
+	assert.Equal("id: 4\ndata: four\n\n", string(contents), "a new client has nothing to replay")
+}
+
+func TestSSEResultHeartbeatAndDisconnect(t *testing.T) {
+	assert := assert.New(t)
+
+	done := make(chan struct{})
+	app := New()
+	app.GET("/events", func(ctx *Ctx) Result {
+		result := ctx.SSE(make(chan SSEEvent))
+		result.HeartbeatInterval = 5 * time.Millisecond
+		return result
+	})
+
+	requestContext, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/events", nil).WithContext(requestContext)
+	res := httptest.NewRecorder()
+	go func() {
+		app.ServeHTTP(res, req)
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("event stream should stop when the client disconnects")
+	}
+	assert.True(strings.Contains(res.Body.String(), ": heartbeat\n\n"))
+}
+
+func TestSSEResultProducerStopsWithPooling(t *testing.T) {
+	assert := assert.New(t)
+
+	stopped := make(chan struct{})
+	app := New()
+	app.SetCtxPool(NewCtxPool())
+	app.GET("/events", func(ctx *Ctx) Result {
+		events := make(chan SSEEvent)
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case events <- SSEEvent{Data: "tick"}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return ctx.SSE(events)
+	})
+
+	requestContext, cancel := context.WithCancel(context.Background())
+	res := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		app.ServeHTTP(res, httptest.NewRequest("GET", "/events", nil).WithContext(requestContext))
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("event stream should stop when the client disconnects")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the producer should stop when the client disconnects")
+	}
+	assert.True(strings.Contains(res.Body.String(), "data: tick\n\n"))
+}
+
+func TestSSEResultWriteTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetWriteTimeout(DefaultSSEWriteTimeoutMargin + 20*time.Millisecond)
+	app.GET("/events", func(ctx *Ctx) Result {
+		return ctx.SSE(make(chan SSEEvent))
+	})
+
+	started := time.Now()
+	_, err := app.Mock().WithPathf("/events").Bytes()
+	assert.Nil(err)
+	assert.True(time.Since(started) < app.WriteTimeout(), "event stream should end before the write timeout")
+}
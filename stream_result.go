@@ -0,0 +1,123 @@
+package web
+
+import (
+	"io"
+	"net/http"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// DefaultStreamChunkSize is the size of the chunks a stream result copies from its reader, flushing after each.
+	DefaultStreamChunkSize = 32 * 1024
+)
+
+// StreamFunc writes a response incrementally.
+type StreamFunc func(w *StreamWriter) error
+
+// StreamResult is a result that is written incrementally, either copied from a reader
+// or written by a stream func, flushing to the client as it goes.
+// If the reader is an `io.Closer` it is closed once the result is rendered.
+type StreamResult struct {
+	StatusCode  int
+	ContentType string
+	Reader      io.Reader
+	Stream      StreamFunc
+	ChunkSize   int
+}
+
+// Render renders the result.
+func (sr *StreamResult) Render(ctx *Ctx) error {
+	if closer, isCloser := sr.Reader.(io.Closer); isCloser {
+		defer closer.Close()
+	}
+
+	w := NewStreamWriter(ctx.Response)
+	if len(sr.ContentType) > 0 {
+		w.Header().Set(HeaderContentType, sr.ContentType)
+	}
+	w.Header().Del(HeaderContentLength)
+	if sr.StatusCode == 0 {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(sr.StatusCode)
+	}
+
+	if sr.Stream != nil {
+		if err := sr.Stream(w); err != nil {
+			return err
+		}
+	} else if sr.Reader != nil {
+		chunkSize := sr.ChunkSize
+		if chunkSize <= 0 {
+			chunkSize = DefaultStreamChunkSize
+		}
+		chunk := make([]byte, chunkSize)
+		for {
+			read, readErr := sr.Reader.Read(chunk)
+			if read > 0 {
+				if _, err := w.Write(chunk[:read]); err != nil {
+					return err
+				}
+				w.Flush()
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				return exception.Wrap(readErr)
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Err()
+}
+
+// NewStreamWriter returns a new stream writer for a response.
+func NewStreamWriter(response ResponseWriter) *StreamWriter {
+	if streaming, isStreaming := response.(interface {
+		stream()
+	}); isStreaming {
+		streaming.stream()
+	}
+	return &StreamWriter{response: response}
+}
+
+// StreamWriter writes a streamed response. It is an `http.Flusher`; `Flush` pushes
+// what has been written so far (through any compression) to the client.
+type StreamWriter struct {
+	response ResponseWriter
+	err      error
+}
+
+// Header returns the response headers.
+func (sw *StreamWriter) Header() http.Header {
+	return sw.response.Header()
+}
+
+// WriteHeader writes the status code.
+func (sw *StreamWriter) WriteHeader(code int) {
+	sw.response.WriteHeader(code)
+}
+
+// Write writes to the response.
+func (sw *StreamWriter) Write(b []byte) (int, error) {
+	written, err := sw.response.Write(b)
+	if err != nil && sw.err == nil {
+		sw.err = err
+	}
+	return written, err
+}
+
+// Flush flushes the response to the client. Errors are available from `Err`.
+func (sw *StreamWriter) Flush() {
+	if err := sw.response.FlushError(); err != nil && sw.err == nil {
+		sw.err = err
+	}
+}
+
+// Err returns the first error from writing or flushing.
+func (sw *StreamWriter) Err() error {
+	return sw.err
+}
@@ -0,0 +1,127 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type streamTestReader struct {
+	*strings.Reader
+	closed bool
+}
+
+func (str *streamTestReader) Close() error {
+	str.closed = true
+	return nil
+}
+
+func TestStreamResultReader(t *testing.T) {
+	assert := assert.New(t)
+
+	reader := &streamTestReader{Reader: strings.NewReader("a,b,c\n1,2,3\n")}
+	app := New()
+	app.GET("/export", func(ctx *Ctx) Result {
+		result := ctx.StreamReader("text/csv", reader)
+		result.ChunkSize = 4
+		return result
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/export", nil))
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("text/csv", res.Header().Get(HeaderContentType))
+	assert.Equal("a,b,c\n1,2,3\n", res.Body.String())
+	assert.True(res.Flushed)
+	assert.True(reader.closed)
+}
+
+func TestCtxStreamGZIP(t *testing.T) {
+	assert := assert.New(t)
+
+	flushes := 0
+	app := New()
+	app.GET("/export", func(ctx *Ctx) Result {
+		return ctx.Stream(func(w *StreamWriter) error {
+			w.Header().Set(HeaderContentType, "text/csv")
+			for x := 0; x < 3; x++ {
+				fmt.Fprintf(w, "row %d\n", x)
+				w.Flush()
+				flushes++
+			}
+			return nil
+		})
+	})
+
+	req := httptest.NewRequest("GET", "/export", nil)
+	req.Header.Set(HeaderAcceptEncoding, ContentEncodingGZIP)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+
+	assert.Equal(3, flushes)
+	assert.Equal(ContentEncodingGZIP, res.Header().Get(HeaderContentEncoding), "streams should be compressed regardless of size")
+	assert.True(res.Flushed)
+
+	reader, err := gzip.NewReader(res.Body)
+	assert.Nil(err)
+	contents, err := ioutil.ReadAll(reader)
+	assert.Nil(err)
+	assert.Equal("row 0\nrow 1\nrow 2\n", string(contents))
+}
+
+func TestCtxStreamError(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, err := NewMockRequestBuilder(nil).Ctx(nil)
+	assert.Nil(err)
+	renderErr := ctx.Stream(func(w *StreamWriter) error {
+		return fmt.Errorf("failed")
+	}).Render(ctx)
+	assert.NotNil(renderErr)
+}
+
+func TestBufferedResponseWriterWritesThrough(t *testing.T) {
+	assert := assert.New(t)
+
+	res := httptest.NewRecorder()
+	rw := NewBufferedResponseWriter(res)
+	rw.Write([]byte("hello"))
+	assert.Equal("hello", res.Body.String(), "buffered writers should not hold the response")
+	assert.Nil(rw.FlushError())
+	assert.True(res.Flushed)
+	assert.Equal("hello", string(rw.Bytes()))
+
+	rw.Write(bytes.Repeat([]byte("a"), BufferedResponseMaxBytes))
+	assert.Len(rw.Bytes(), BufferedResponseMaxBytes)
+	assert.Equal(BufferedResponseMaxBytes+5, rw.ContentLength())
+}
+
+func TestResponseWritersAreFlushers(t *testing.T) {
+	assert := assert.New(t)
+
+	res := httptest.NewRecorder()
+	for _, rw := range []ResponseWriter{
+		NewResponseWriter(res),
+		NewBufferedResponseWriter(res),
+		NewCompression().NewResponseWriter(res, ContentEncodingGZIP),
+		NewHeadResponseWriter(res),
+		NewMockResponseWriter(nil),
+	} {
+		var w http.ResponseWriter = rw
+		_, isFlusher := w.(http.Flusher)
+		assert.True(isFlusher, fmt.Sprintf("%T", rw))
+	}
+
+	rw := NewResponseWriter(httptest.NewRecorder())
+	rw.Write([]byte("hello"))
+	var w http.ResponseWriter = rw
+	w.(http.Flusher).Flush()
+	assert.True(rw.InnerResponse().(*httptest.ResponseRecorder).Flushed)
+}
@@ -70,6 +70,15 @@ func (trp *TextResultProvider) BadRequest(message string) Result {
 	}
 }
 
+// Status returns a text response for a status code.
+func (trp *TextResultProvider) Status(statusCode int, message string) Result {
+	return &RawResult{
+		StatusCode:  statusCode,
+		ContentType: ContentTypeText,
+		Body:        []byte(statusMessage(statusCode, message)),
+	}
+}
+
 // Result returns a plaintext result.
 func (trp *TextResultProvider) Result(response interface{}) Result {
 	return &RawResult{
@@ -0,0 +1,236 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	logger "github.com/blendlabs/go-logger"
+)
+
+// Timeout returns a middleware that runs an action under a deadline, returning `503 Service Unavailable`
+// from the default result provider if it does not finish in time.
+// List it first on a route so it is the innermost middleware, and provider middleware has already run.
+func Timeout(timeout time.Duration) Middleware {
+	return TimeoutWithStatus(timeout, http.StatusServiceUnavailable)
+}
+
+// TimeoutWithStatus returns a middleware that runs an action under a deadline, returning the given status
+// (typically `503 Service Unavailable` or `504 Gateway Timeout`) from the default result provider if it does not finish in time.
+//
+// The action runs on a copy of the ctx whose request context has the deadline, so database calls made with it are canceled.
+// Writes the action makes to the response are buffered and sent once it returns; if it times out they are discarded,
+// as is the result it eventually returns. If the client disconnects first, nothing is written.
+func TimeoutWithStatus(timeout time.Duration, statusCode int) Middleware {
+	return func(action Action) Action {
+		return func(ctx *Ctx) Result {
+			return runWithTimeout(action, ctx, timeout, statusCode)
+		}
+	}
+}
+
+// timeoutOutcome is what the timed action returned, or the panic it raised.
+type timeoutOutcome struct {
+	result    Result
+	recovered interface{}
+	panicked  bool
+}
+
+func runWithTimeout(action Action, ctx *Ctx, timeout time.Duration, statusCode int) Result {
+	response := ctx.Response
+	writer := newTimeoutResponseWriter(response)
+
+	actionCtx := ctx.copyForTimeout(writer)
+	cancel := actionCtx.WithTimeout(timeout)
+	defer cancel()
+
+	done := make(chan timeoutOutcome, 1)
+	go func() {
+		outcome := timeoutOutcome{panicked: true}
+		defer func() {
+			if outcome.panicked {
+				outcome.recovered = recover()
+			}
+			done <- outcome
+		}()
+		outcome.result = action(actionCtx)
+		outcome.panicked = false
+	}()
+
+	select {
+	case outcome := <-done:
+		if outcome.panicked {
+			panic(outcome.recovered)
+		}
+		// the action has returned, so what it may have changed can be folded back into the ctx.
+		ctx.foldTimeoutCopy(actionCtx)
+		writer.writeTo(response)
+		return outcome.result
+	case <-actionCtx.Done():
+		writer.timeout()
+		// the abandoned action may still be running, so the ctx must not be returned to the pool.
+		ctx.Retain()
+		if actionCtx.Err() != context.DeadlineExceeded {
+			// the client went away or the request was canceled, so there is nothing to respond to.
+			return nil
+		}
+		ctx.logErrorEvent(EventWebRequestTimeout, logger.ColorYellow, ErrRequestTimeout)
+		return StatusResult(ctx.DefaultResultProvider(), statusCode, "")
+	}
+}
+
+// copyForTimeout returns a copy of the ctx for an action run under a timeout.
+// The state is copied so an abandoned action never shares it with the ctx, which may be reset and reused.
+// Cached result providers are bound to the ctx they were created for, so they are re-created for the copy.
+func (rc *Ctx) copyForTimeout(response ResponseWriter) *Ctx {
+	copied := *rc
+	copied.Response = response
+	copied.state = State{}
+	for key, value := range rc.state {
+		copied.state[key] = value
+	}
+	copied.view, copied.api, copied.json, copied.xml, copied.text, copied.negotiated, copied.problem = nil, nil, nil, nil, nil, nil, nil
+	copied.defaultResultProvider = copied.rebindResultProvider(rc.defaultResultProvider)
+	return &copied
+}
+
+// foldTimeoutCopy takes the fields an action may change from the copy it ran on, once it has returned.
+// The request (with the deadline), response, spans and result providers of the copy are left behind.
+func (rc *Ctx) foldTimeoutCopy(copied *Ctx) {
+	rc.state = copied.state
+	rc.session = copied.session
+	rc.postBody = copied.postBody
+	rc.body = copied.body
+	rc.tx = copied.tx
+	rc.retained = rc.retained || copied.retained
+}
+
+// rebindResultProvider returns the ctx's provider of the same kind as a provider; other providers are returned as is.
+func (rc *Ctx) rebindResultProvider(provider ResultProvider) ResultProvider {
+	switch provider.(type) {
+	case *APIResultProvider:
+		return rc.API()
+	case *JSONResultProvider:
+		return rc.JSON()
+	case *XMLResultProvider:
+		return rc.XML()
+	case *TextResultProvider:
+		return rc.Text()
+	case *ViewResultProvider:
+		return rc.View()
+	case *ProblemResultProvider:
+		return rc.Problem()
+	case *NegotiatingResultProvider:
+		return rc.Negotiated()
+	}
+	return provider
+}
+
+// newTimeoutResponseWriter returns a new timeout response writer.
+func newTimeoutResponseWriter(inner ResponseWriter) *timeoutResponseWriter {
+	return &timeoutResponseWriter{inner: inner, header: http.Header{}, buffer: bytes.NewBuffer(nil)}
+}
+
+// timeoutResponseWriter buffers the response of an action run under a timeout.
+// Once the action has timed out, writes fail with `ErrRequestTimeout` and are discarded.
+type timeoutResponseWriter struct {
+	sync.Mutex
+	inner      ResponseWriter
+	header     http.Header
+	buffer     *bytes.Buffer
+	statusCode int
+	timedOut   bool
+}
+
+// Header returns the buffered response headers.
+func (trw *timeoutResponseWriter) Header() http.Header {
+	return trw.header
+}
+
+// Write buffers the contents.
+func (trw *timeoutResponseWriter) Write(contents []byte) (int, error) {
+	trw.Lock()
+	defer trw.Unlock()
+	if trw.timedOut {
+		return 0, ErrRequestTimeout
+	}
+	if trw.statusCode == 0 {
+		trw.statusCode = http.StatusOK
+	}
+	return trw.buffer.Write(contents)
+}
+
+// WriteHeader buffers the status code.
+func (trw *timeoutResponseWriter) WriteHeader(statusCode int) {
+	trw.Lock()
+	defer trw.Unlock()
+	if trw.timedOut || trw.statusCode != 0 {
+		return
+	}
+	trw.statusCode = statusCode
+}
+
+// InnerResponse returns the response the buffered response is written to.
+func (trw *timeoutResponseWriter) InnerResponse() http.ResponseWriter {
+	return trw.inner.InnerResponse()
+}
+
+// StatusCode returns the buffered status code.
+func (trw *timeoutResponseWriter) StatusCode() int {
+	trw.Lock()
+	defer trw.Unlock()
+	return trw.statusCode
+}
+
+// ContentLength returns the buffered content length.
+func (trw *timeoutResponseWriter) ContentLength() int {
+	trw.Lock()
+	defer trw.Unlock()
+	return trw.buffer.Len()
+}
+
+// Bytes returns the buffered contents.
+func (trw *timeoutResponseWriter) Bytes() []byte {
+	trw.Lock()
+	defer trw.Unlock()
+	return trw.buffer.Bytes()
+}
+
+// Flush is a no-op; the response is sent once the action returns.
+func (trw *timeoutResponseWriter) Flush() {}
+
+// FlushError is a no-op; the response is sent once the action returns.
+func (trw *timeoutResponseWriter) FlushError() error {
+	return nil
+}
+
+// Close is a no-op; the inner response is closed by the app.
+func (trw *timeoutResponseWriter) Close() error {
+	return nil
+}
+
+// timeout marks the writer as timed out, discarding anything written so far or later.
+func (trw *timeoutResponseWriter) timeout() {
+	trw.Lock()
+	defer trw.Unlock()
+	trw.timedOut = true
+	trw.buffer = bytes.NewBuffer(nil)
+}
+
+// writeTo writes the buffered response to the inner response.
+func (trw *timeoutResponseWriter) writeTo(response ResponseWriter) {
+	trw.Lock()
+	defer trw.Unlock()
+	header := response.Header()
+	for key, values := range trw.header {
+		header[key] = values
+	}
+	if trw.statusCode != 0 {
+		response.WriteHeader(trw.statusCode)
+	}
+	if trw.buffer.Len() > 0 {
+		response.Write(trw.buffer.Bytes())
+	}
+}
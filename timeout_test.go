@@ -0,0 +1,177 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+	logger "github.com/blendlabs/go-logger"
+)
+
+func TestTimeoutCompletes(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		_, hasDeadline := r.Deadline()
+		assert.True(hasDeadline)
+		r.Response.Header().Set("X-Action", "ran")
+		return r.Text().Result("ok")
+	}, Timeout(time.Second))
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("ok", res.Body.String())
+	assert.Equal("ran", res.Header().Get("X-Action"))
+}
+
+func TestTimeoutExpires(t *testing.T) {
+	assert := assert.New(t)
+
+	lateWrite := make(chan error, 1)
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		<-r.Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := r.Response.Write([]byte("late"))
+		lateWrite <- err
+		return r.Text().Result("late")
+	}, TimeoutWithStatus(10*time.Millisecond, http.StatusGatewayTimeout), APIProviderAsDefault)
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(http.StatusGatewayTimeout, res.Code)
+	assert.Contains("\"StatusCode\":504", res.Body.String())
+	assert.Equal(ErrRequestTimeout, <-lateWrite)
+}
+
+func TestTimeoutClientCanceled(t *testing.T) {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	var timeouts int32
+	agent := logger.New(logger.NewEventFlagSetWithEvents(EventWebRequestTimeout))
+	agent.AddEventListener(EventWebRequestTimeout, func(wr logger.Logger, ts logger.TimeSource, eventFlag logger.EventFlag, state ...interface{}) {
+		atomic.AddInt32(&timeouts, 1)
+	})
+
+	app := New()
+	app.SetLogger(agent)
+	app.GET("/", func(r *Ctx) Result {
+		<-release
+		return nil
+	}, Timeout(time.Second))
+
+	requestContext, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil).WithContext(requestContext))
+	assert.False(res.Code == http.StatusServiceUnavailable, "a canceled request is not a timeout")
+	assert.Zero(res.Body.Len())
+	assert.Zero(atomic.LoadInt32(&timeouts))
+}
+
+func TestTimeoutDefaultStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		<-release
+		return nil
+	}, Timeout(time.Millisecond))
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(http.StatusServiceUnavailable, res.Code)
+}
+
+func TestTimeoutRepanics(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetPanicHandler(func(r *Ctx, err interface{}) Result {
+		assert.Equal("boom", err)
+		return r.Text().InternalError(nil)
+	})
+	app.GET("/", func(r *Ctx) Result {
+		panic("boom")
+	}, Timeout(time.Second))
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(http.StatusInternalServerError, res.Code)
+}
+
+func TestTimeoutFoldsBackState(t *testing.T) {
+	assert := assert.New(t)
+
+	var after interface{}
+	outer := func(action Action) Action {
+		return func(r *Ctx) Result {
+			r.SetState("before", "outer")
+			result := action(r)
+			after = r.State("action")
+			return result
+		}
+	}
+
+	app := New()
+	app.GET("/", func(r *Ctx) Result {
+		assert.Equal("outer", r.State("before"))
+		r.SetState("action", "set")
+		return r.Text().Result("ok")
+	}, Timeout(time.Second), outer)
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Equal("set", after)
+}
+
+func TestTimeoutAbandonedActionDoesNotShareState(t *testing.T) {
+	assert := assert.New(t)
+
+	finished := make(chan struct{})
+	outer := func(action Action) Action {
+		return func(r *Ctx) Result {
+			result := action(r)
+			for index := 0; index < 100; index++ {
+				r.SetState("outer", index)
+			}
+			return result
+		}
+	}
+
+	app := New()
+	app.SetCtxPool(NewCtxPool())
+	app.GET("/slow", func(r *Ctx) Result {
+		<-r.Done()
+		for index := 0; index < 100; index++ {
+			r.SetState("abandoned", index)
+		}
+		close(finished)
+		return nil
+	}, Timeout(time.Millisecond), outer)
+	app.GET("/", func(r *Ctx) Result {
+		assert.Nil(r.State("abandoned"))
+		return r.Text().Result("ok")
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/slow", nil))
+	assert.Equal(http.StatusServiceUnavailable, res.Code)
+	for index := 0; index < 10; index++ {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	<-finished
+}
@@ -0,0 +1,412 @@
+package web
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// SpanNameRequest is the name of the request span for unrouted requests; routed requests use `Route.String()`.
+	SpanNameRequest = "web.request"
+	// SpanNameMiddleware is the name of the span around the middleware chain.
+	SpanNameMiddleware = "web.middleware"
+	// SpanNameAction is the name of the span around the action.
+	SpanNameAction = "web.action"
+	// SpanNameRender is the name of the span around `Result.Render`.
+	SpanNameRender = "web.render"
+	// SpanNameView is the name of the span around view template execution.
+	SpanNameView = "web.view"
+
+	// TraceFlagSampled is the W3C trace flag marking a trace as sampled.
+	TraceFlagSampled = byte(0x01)
+
+	// ErrTraceparentInvalid is returned when parsing a malformed `traceparent` header.
+	ErrTraceparentInvalid Error = "traceparent is invalid"
+)
+
+// Tracer returns the app tracer, if set.
+func (a *App) Tracer() *Tracer {
+	return a.tracer
+}
+
+// SetTracer sets the app tracer; when set, each request produces a tree of spans,
+// continuing the trace from the request `traceparent` header if there is one.
+func (a *App) SetTracer(tracer *Tracer) {
+	a.tracer = tracer
+}
+
+// NewTracer returns a new tracer.
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Tracer starts spans and exports them when they finish.
+type Tracer struct {
+	exporter SpanExporter
+}
+
+// Exporter returns the span exporter.
+func (t *Tracer) Exporter() SpanExporter {
+	return t.exporter
+}
+
+// StartSpan starts a span; if the parent is valid the span continues its trace, otherwise it starts a new sampled trace.
+func (t *Tracer) StartSpan(name string, parent SpanContext) *Span {
+	span := &Span{
+		Name:   name,
+		Start:  time.Now().UTC(),
+		tracer: t,
+	}
+	if parent.IsValid() {
+		span.Context = SpanContext{TraceID: parent.TraceID, Flags: parent.Flags, TraceState: parent.TraceState}
+		span.ParentID = parent.SpanID
+	} else {
+		span.Context = SpanContext{TraceID: newTraceID(), Flags: TraceFlagSampled}
+	}
+	span.Context.SpanID = newSpanID()
+	return span
+}
+
+// SpanExporter exports finished spans.
+type SpanExporter interface {
+	ExportSpan(span *Span) error
+}
+
+// NewStdoutSpanExporter returns a span exporter that writes spans to stdout as json lines.
+func NewStdoutSpanExporter() *JSONSpanExporter {
+	return NewJSONSpanExporter(os.Stdout)
+}
+
+// NewJSONSpanExporter returns a span exporter that writes spans to a writer as json lines.
+func NewJSONSpanExporter(w io.Writer) *JSONSpanExporter {
+	return &JSONSpanExporter{encoder: json.NewEncoder(w)}
+}
+
+// JSONSpanExporter writes spans as json lines.
+type JSONSpanExporter struct {
+	sync.Mutex
+	encoder *json.Encoder
+}
+
+// ExportSpan writes the span.
+func (jse *JSONSpanExporter) ExportSpan(span *Span) error {
+	jse.Lock()
+	defer jse.Unlock()
+	return exception.Wrap(jse.encoder.Encode(span))
+}
+
+// TraceID is a W3C trace id.
+type TraceID [16]byte
+
+// IsValid returns if the trace id is not all zeroes.
+func (tid TraceID) IsValid() bool {
+	return tid != TraceID{}
+}
+
+// String returns the trace id as lowercase hex.
+func (tid TraceID) String() string {
+	return hex.EncodeToString(tid[:])
+}
+
+// SpanID is a W3C span (parent) id.
+type SpanID [8]byte
+
+// IsValid returns if the span id is not all zeroes.
+func (sid SpanID) IsValid() bool {
+	return sid != SpanID{}
+}
+
+// String returns the span id as lowercase hex.
+func (sid SpanID) String() string {
+	return hex.EncodeToString(sid[:])
+}
+
+// ParseTraceparent parses a W3C `traceparent` header value, along with the `tracestate` value that goes with it.
+func ParseTraceparent(traceparent, tracestate string) (SpanContext, error) {
+	var spanContext SpanContext
+	traceparent = strings.TrimSpace(traceparent)
+	// version 00 is exactly 55 characters; later versions may append fields after another '-'.
+	if len(traceparent) < 55 || (len(traceparent) > 55 && traceparent[55] != '-') {
+		return spanContext, ErrTraceparentInvalid
+	}
+	if traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' {
+		return spanContext, ErrTraceparentInvalid
+	}
+	version, err := decodeTraceHex(traceparent[0:2])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(traceparent) != 55) {
+		return spanContext, ErrTraceparentInvalid
+	}
+	traceID, err := decodeTraceHex(traceparent[3:35])
+	if err != nil {
+		return spanContext, ErrTraceparentInvalid
+	}
+	spanID, err := decodeTraceHex(traceparent[36:52])
+	if err != nil {
+		return spanContext, ErrTraceparentInvalid
+	}
+	flags, err := decodeTraceHex(traceparent[53:55])
+	if err != nil {
+		return spanContext, ErrTraceparentInvalid
+	}
+	copy(spanContext.TraceID[:], traceID)
+	copy(spanContext.SpanID[:], spanID)
+	spanContext.Flags = flags[0]
+	if !spanContext.IsValid() {
+		return SpanContext{}, ErrTraceparentInvalid
+	}
+	spanContext.TraceState = strings.TrimSpace(tracestate)
+	return spanContext, nil
+}
+
+// decodeTraceHex decodes lowercase hex, as required by the W3C trace context spec.
+func decodeTraceHex(value string) ([]byte, error) {
+	if strings.ToLower(value) != value {
+		return nil, ErrTraceparentInvalid
+	}
+	return hex.DecodeString(value)
+}
+
+// SpanContext is the part of a span that is propagated across services.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+// IsValid returns if the trace and span ids are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled returns if the trace is sampled.
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&TraceFlagSampled == TraceFlagSampled
+}
+
+// Traceparent returns the W3C `traceparent` header value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// Inject sets the `traceparent` and `tracestate` headers, e.g. on an outgoing request.
+func (sc SpanContext) Inject(header http.Header) {
+	header.Set(HeaderTraceparent, sc.Traceparent())
+	if len(sc.TraceState) > 0 {
+		header.Set(HeaderTracestate, sc.TraceState)
+	} else {
+		header.Del(HeaderTracestate)
+	}
+}
+
+// Span is a timed operation in a trace.
+// Span methods are safe to call on a nil span, which is what you get when tracing is disabled.
+type Span struct {
+	sync.Mutex
+	Name       string
+	Context    SpanContext
+	ParentID   SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Err        error
+
+	tracer   *Tracer
+	finished bool
+}
+
+// StartChild starts a child span.
+func (s *Span) StartChild(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.StartSpan(name, s.Context)
+}
+
+// SetAttribute sets an attribute on the span.
+func (s *Span) SetAttribute(key string, value interface{}) *Span {
+	if s == nil {
+		return nil
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = map[string]interface{}{}
+	}
+	s.Attributes[key] = value
+	return s
+}
+
+// SetError marks the span as failed.
+func (s *Span) SetError(err error) *Span {
+	if s == nil || err == nil {
+		return s
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.Err = err
+	return s
+}
+
+// Finish ends the span and exports it if the trace is sampled; only the first call has any effect.
+func (s *Span) Finish() error {
+	if s == nil {
+		return nil
+	}
+	s.Lock()
+	if s.finished {
+		s.Unlock()
+		return nil
+	}
+	s.finished = true
+	s.End = time.Now().UTC()
+	s.Unlock()
+
+	if s.tracer == nil || s.tracer.exporter == nil || !s.Context.IsSampled() {
+		return nil
+	}
+	return s.tracer.exporter.ExportSpan(s)
+}
+
+// Duration returns the span duration, or the time elapsed so far if it has not finished.
+func (s *Span) Duration() time.Duration {
+	if s == nil {
+		return 0
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.End.IsZero() {
+		return time.Now().UTC().Sub(s.Start)
+	}
+	return s.End.Sub(s.Start)
+}
+
+// MarshalJSON marshals the span as json.
+func (s *Span) MarshalJSON() ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	values := map[string]interface{}{
+		"name":    s.Name,
+		"traceId": s.Context.TraceID.String(),
+		"spanId":  s.Context.SpanID.String(),
+		"start":   s.Start,
+		"end":     s.End,
+	}
+	if s.ParentID.IsValid() {
+		values["parentSpanId"] = s.ParentID.String()
+	}
+	if len(s.Context.TraceState) > 0 {
+		values["traceState"] = s.Context.TraceState
+	}
+	if !s.End.IsZero() {
+		values["durationMs"] = float64(s.End.Sub(s.Start)) / float64(time.Millisecond)
+	}
+	if len(s.Attributes) > 0 {
+		values["attributes"] = s.Attributes
+	}
+	if s.Err != nil {
+		values["error"] = s.Err.Error()
+	}
+	return json.Marshal(values)
+}
+
+// Span returns the current span of the request, or nil if tracing is disabled.
+func (rc *Ctx) Span() *Span {
+	return rc.span
+}
+
+// StartSpan starts a child of the current span; it does not change the current span, so it is safe to call from goroutines
+// the action starts. Spans nested under the returned span are started with `Span.StartChild`.
+// It returns nil, which is safe to use, if tracing is disabled.
+func (rc *Ctx) StartSpan(name string) *Span {
+	return rc.span.StartChild(name)
+}
+
+// enterSpan starts a child of the current span and makes it the current span until `exitSpan`.
+// Only the request pipeline calls it, around the steps it runs in order on the request goroutine.
+func (rc *Ctx) enterSpan(name string) (span, parent *Span) {
+	parent = rc.span
+	span = parent.StartChild(name)
+	if span != nil {
+		rc.span = span
+	}
+	return
+}
+
+// exitSpan finishes a span started with `enterSpan` and makes its parent the current span again.
+func (rc *Ctx) exitSpan(span, parent *Span) error {
+	if span == nil {
+		return nil
+	}
+	rc.span = parent
+	return span.Finish()
+}
+
+// startRequestSpan starts the request span, continuing the trace in the request headers if there is one.
+func (rc *Ctx) startRequestSpan(tracer *Tracer) {
+	parent, _ := ParseTraceparent(rc.Request.Header.Get(HeaderTraceparent), rc.Request.Header.Get(HeaderTracestate))
+	name := SpanNameRequest
+	if rc.route != nil {
+		name = rc.route.String()
+	}
+	rc.span = tracer.StartSpan(name, parent)
+	rc.requestSpan = rc.span
+	rc.span.SetAttribute("method", rc.Request.Method)
+	if rc.route != nil {
+		rc.span.SetAttribute("route", rc.route.Path)
+	}
+	if len(rc.requestID) > 0 {
+		rc.span.SetAttribute("request_id", rc.requestID)
+	}
+}
+
+// finishRequestSpan finishes the request span with the response status.
+func (rc *Ctx) finishRequestSpan() error {
+	span := rc.requestSpan
+	if span == nil {
+		return nil
+	}
+	statusCode := rc.getLoggedStatusCode()
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	span.SetAttribute("status", statusCode)
+	if statusCode >= http.StatusInternalServerError {
+		span.SetError(Error(http.StatusText(statusCode)))
+	}
+	rc.span = span
+	return span.Finish()
+}
+
+// tracedAction returns an action that runs in a child span of the current span.
+func tracedAction(name string, action Action) Action {
+	return func(ctx *Ctx) Result {
+		span, parent := ctx.enterSpan(name)
+		defer ctx.exitSpan(span, parent)
+		return action(ctx)
+	}
+}
+
+// newTraceID returns a new random trace id.
+func newTraceID() (traceID TraceID) {
+	if id, err := String.GenerateRandomBytes(len(traceID)); err == nil {
+		copy(traceID[:], id)
+	}
+	return
+}
+
+// newSpanID returns a new random span id.
+func newSpanID() (spanID SpanID) {
+	if id, err := String.GenerateRandomBytes(len(spanID)); err == nil {
+		copy(spanID[:], id)
+	}
+	return
+}
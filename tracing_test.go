@@ -0,0 +1,171 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	assert := assert.New(t)
+
+	spanContext, err := ParseTraceparent(testTraceparent, " vendor=value ")
+	assert.Nil(err)
+	assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID.String())
+	assert.Equal("00f067aa0ba902b7", spanContext.SpanID.String())
+	assert.True(spanContext.IsSampled())
+	assert.Equal("vendor=value", spanContext.TraceState)
+	assert.Equal(testTraceparent, spanContext.Traceparent())
+
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future", "")
+	assert.Nil(err)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceparent(invalid, "")
+		assert.Equal(ErrTraceparentInvalid, err, invalid)
+	}
+}
+
+func TestAppTracing(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	app := New()
+	app.SetTracer(NewTracer(NewJSONSpanExporter(buffer)))
+	app.ViewCache().SetTemplates(template.Must(template.New("").Parse(`{{ define "index" }}ok{{ end }}`)))
+	app.GET("/", func(r *Ctx) Result {
+		r.StartSpan("db").SetAttribute("query", "select 1").Finish()
+		return r.View().View("index", nil)
+	}, APIProviderAsDefault)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderTraceparent, testTraceparent)
+	req.Header.Set(HeaderTracestate, "vendor=value")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(http.StatusOK, res.Code)
+
+	assert.Empty(res.Header().Get(HeaderTraceparent), "the trace context should not be echoed to the client")
+	assert.Empty(res.Header().Get(HeaderTracestate))
+
+	spans := map[string]map[string]interface{}{}
+	names := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var span map[string]interface{}
+		assert.Nil(json.Unmarshal([]byte(line), &span))
+		assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span["traceId"])
+		spans[span["name"].(string)] = span
+		names[span["spanId"].(string)] = span["name"].(string)
+	}
+	assert.Len(spans, 6)
+	parentOf := func(name string) string {
+		return names[spans[name]["parentSpanId"].(string)]
+	}
+
+	request := spans["GET_/"]
+	assert.Equal("00f067aa0ba902b7", request["parentSpanId"])
+	assert.Equal("vendor=value", request["traceState"])
+	assert.Equal(float64(http.StatusOK), request["attributes"].(map[string]interface{})["status"])
+	assert.Equal("GET_/", parentOf(SpanNameMiddleware))
+	assert.Equal(SpanNameMiddleware, parentOf(SpanNameAction))
+	assert.Equal(SpanNameAction, parentOf("db"))
+	assert.Equal("GET_/", parentOf(SpanNameRender))
+	assert.Equal(SpanNameRender, parentOf(SpanNameView))
+	assert.Equal("index", spans[SpanNameView]["attributes"].(map[string]interface{})["template"])
+}
+
+func TestAppTracingUnsampled(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	app := New()
+	app.SetTracer(NewTracer(NewJSONSpanExporter(buffer)))
+	var sampled bool
+	app.GET("/", func(r *Ctx) Result {
+		assert.NotNil(r.Span())
+		sampled = r.Span().Context.IsSampled()
+		return r.Text().Result("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderTraceparent, strings.TrimSuffix(testTraceparent, "01")+"00")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(http.StatusOK, res.Code)
+	assert.Zero(buffer.Len())
+	assert.False(sampled)
+	assert.Empty(res.Header().Get(HeaderTraceparent))
+}
+
+func TestSpanNilSafe(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := NewCtx(NewMockResponseWriter(nil), httptest.NewRequest("GET", "/", nil), nil)
+	assert.Nil(ctx.Span())
+	span := ctx.StartSpan("child")
+	assert.Nil(span)
+	assert.Nil(span.SetAttribute("key", "value").StartChild("grandchild"))
+	assert.Nil(span.Finish())
+	assert.Zero(span.Duration())
+}
+
+func TestCtxStartSpanConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer(nil)
+	app := New()
+	app.SetTracer(NewTracer(NewJSONSpanExporter(buffer)))
+	app.GET("/", func(r *Ctx) Result {
+		current := r.Span()
+		wg := sync.WaitGroup{}
+		for index := 0; index < 8; index++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.StartSpan("worker").Finish()
+			}()
+		}
+		wg.Wait()
+		assert.True(current == r.Span())
+		return r.Text().Result("ok")
+	})
+
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(http.StatusOK, res.Code)
+
+	parents := map[string]string{}
+	var actionID string
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var span map[string]interface{}
+		assert.Nil(json.Unmarshal([]byte(line), &span))
+		if span["name"] == SpanNameAction {
+			actionID = span["spanId"].(string)
+		}
+		if span["name"] == "worker" {
+			parents[span["spanId"].(string)] = span["parentSpanId"].(string)
+		}
+	}
+	assert.Len(parents, 8)
+	for _, parentID := range parents {
+		assert.Equal(actionID, parentID)
+	}
+}
@@ -55,9 +55,9 @@ func (n *node) incrementChildPriority(index int) int {
 	return newIndex
 }
 
-// addRoute adds a node with the given handle to the path.
+// addRoute adds a node with the given handle to the path, returning the new route.
 // Not concurrency-safe!
-func (n *node) addRoute(method, path string, handler Handler) {
+func (n *node) addRoute(method, path string, handler Handler) *Route {
 	fullPath := path
 	n.priority++
 	numParams := countParams(path)
@@ -163,8 +163,7 @@ func (n *node) addRoute(method, path string, handler Handler) {
 					n.incrementChildPriority(len(n.indices) - 1)
 					n = child
 				}
-				n.insertChild(numParams, method, path, fullPath, handler)
-				return
+				return n.insertChild(numParams, method, path, fullPath, handler)
 
 			} else if i == len(path) { // Make node a (in-path) leaf
 				if n.route != nil {
@@ -176,15 +175,16 @@ func (n *node) addRoute(method, path string, handler Handler) {
 					Method:  method,
 				}
 			}
-			return
+			return n.route
 		}
 	} else { // Empty tree
-		n.insertChild(numParams, method, path, fullPath, handler)
+		route := n.insertChild(numParams, method, path, fullPath, handler)
 		n.nodeType = root
+		return route
 	}
 }
 
-func (n *node) insertChild(numParams uint8, method, path, fullPath string, handler Handler) {
+func (n *node) insertChild(numParams uint8, method, path, fullPath string, handler Handler) *Route {
 	var offset int // already handled bytes of the path
 
 	// find prefix until first wildcard (beginning with ':'' or '*'')
@@ -292,7 +292,7 @@ func (n *node) insertChild(numParams uint8, method, path, fullPath string, handl
 			}
 			n.children = []*node{child}
 
-			return
+			return child.route
 		}
 	}
 
@@ -303,6 +303,20 @@ func (n *node) insertChild(numParams uint8, method, path, fullPath string, handl
 		Path:    fullPath,
 		Method:  method,
 	}
+	return n.route
+}
+
+// findRouteByName walks the tree and returns the first route with the given name.
+func (n *node) findRouteByName(name string) *Route {
+	if n.route != nil && n.route.Name == name {
+		return n.route
+	}
+	for _, child := range n.children {
+		if route := child.findRouteByName(name); route != nil {
+			return route
+		}
+	}
+	return nil
 }
 
 // Returns the handle registered with the given path (key). The values of
@@ -0,0 +1,233 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+// ForwardedHeaders is which forwarding headers trusted proxies set.
+type ForwardedHeaders int
+
+const (
+	// ForwardedHeadersXForwarded reads the client from the `X-Forwarded-For`, `X-Forwarded-Proto` and `X-Forwarded-Host` headers.
+	ForwardedHeadersXForwarded ForwardedHeaders = iota
+	// ForwardedHeadersRFC7239 reads the client from the RFC 7239 `Forwarded` header.
+	ForwardedHeadersRFC7239
+)
+
+// forwardedHop is a proxy hop from a `Forwarded` or `X-Forwarded-*` header.
+type forwardedHop struct {
+	For   string
+	Proto string
+	Host  string
+}
+
+// forwardedClient is the client a request was made by, as resolved through trusted proxies.
+type forwardedClient struct {
+	IP     string
+	Scheme string
+	Host   string
+}
+
+// TrustedProxies returns the networks whose forwarding headers are honored.
+func (a *App) TrustedProxies() []*net.IPNet {
+	return a.trustedProxies
+}
+
+// ForwardedHeaders returns which forwarding headers are read from trusted proxies.
+func (a *App) ForwardedHeaders() ForwardedHeaders {
+	return a.forwardedHeaders
+}
+
+// SetForwardedHeaders sets which forwarding headers are read from trusted proxies; it defaults to `ForwardedHeadersXForwarded`.
+// Only the headers your proxies set should be read: the others are passed through from the client, and can be spoofed.
+func (a *App) SetForwardedHeaders(forwardedHeaders ForwardedHeaders) {
+	a.forwardedHeaders = forwardedHeaders
+}
+
+// SetTrustedProxies sets the networks, as CIDRs or single ips, whose forwarding headers (see `SetForwardedHeaders`) are honored.
+func (a *App) SetTrustedProxies(cidrs ...string) error {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return exception.Newf("invalid trusted proxy `%s`", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return exception.Wrap(err)
+		}
+		networks = append(networks, network)
+	}
+	a.trustedProxies = networks
+	return nil
+}
+
+// IsTrustedProxy returns if an ip is in a trusted proxy network.
+func (a *App) IsTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range a.trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveClient returns the client for a request, walking the forwarding headers from the nearest hop
+// and stopping at the first hop that is not a trusted proxy.
+func (a *App) resolveClient(req *http.Request) forwardedClient {
+	client := forwardedClient{IP: remoteAddrIP(req.RemoteAddr), Scheme: "http", Host: req.Host}
+	if req.TLS != nil {
+		client.Scheme = "https"
+	}
+	if a == nil || len(a.trustedProxies) == 0 || !a.IsTrustedProxy(client.IP) {
+		return client
+	}
+
+	hops := forwardedHops(req.Header, a.forwardedHeaders)
+	for index := len(hops) - 1; index >= 0; index-- {
+		hop := hops[index]
+		ip := remoteAddrIP(hop.For)
+		if net.ParseIP(ip) == nil {
+			// obfuscated or unknown hops can't be checked, so they end the walk.
+			break
+		}
+		client.IP = ip
+		if len(hop.Proto) > 0 {
+			client.Scheme = strings.ToLower(hop.Proto)
+		}
+		if len(hop.Host) > 0 {
+			client.Host = hop.Host
+		}
+		if !a.IsTrustedProxy(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// forwardedHops returns the hops from either the `Forwarded` header or the `X-Forwarded-*` headers.
+// Single `X-Forwarded-Proto` and `X-Forwarded-Host` values apply to the client hop.
+func forwardedHops(header http.Header, forwardedHeaders ForwardedHeaders) []forwardedHop {
+	if forwardedHeaders == ForwardedHeadersRFC7239 {
+		if forwarded := header[HeaderForwarded]; len(forwarded) > 0 {
+			return parseForwardedHeader(strings.Join(forwarded, ","))
+		}
+		return nil
+	}
+
+	addrs := headerTokens(header, HeaderXForwardedFor)
+	if len(addrs) == 0 {
+		return nil
+	}
+	protos := headerTokens(header, HeaderXForwardedProto)
+	hosts := headerTokens(header, HeaderXForwardedHost)
+
+	hops := make([]forwardedHop, len(addrs))
+	for index, addr := range addrs {
+		hops[index].For = addr
+		hops[index].Proto = alignedForwardedValue(protos, index, len(addrs))
+		hops[index].Host = alignedForwardedValue(hosts, index, len(addrs))
+	}
+	return hops
+}
+
+// alignedForwardedValue returns the value for a hop if there is one value per hop, or the nearest proxy's value.
+func alignedForwardedValue(values []string, index, hops int) string {
+	if len(values) == hops {
+		return values[index]
+	}
+	if len(values) > 0 {
+		return values[len(values)-1]
+	}
+	return ""
+}
+
+// parseForwardedHeader parses an RFC 7239 `Forwarded` header.
+func parseForwardedHeader(value string) (hops []forwardedHop) {
+	for _, element := range splitQuoted(value, ',') {
+		var hop forwardedHop
+		for _, pair := range splitQuoted(element, ';') {
+			pieces := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(pieces) != 2 {
+				continue
+			}
+			pairValue := strings.Trim(strings.TrimSpace(pieces[1]), `"`)
+			switch strings.ToLower(strings.TrimSpace(pieces[0])) {
+			case "for":
+				hop.For = pairValue
+			case "proto":
+				hop.Proto = pairValue
+			case "host":
+				hop.Host = pairValue
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return
+}
+
+// splitQuoted splits a value on a separator that is not inside double quotes.
+func splitQuoted(value string, separator byte) (pieces []string) {
+	var quoted bool
+	var start int
+	for index := 0; index < len(value); index++ {
+		switch value[index] {
+		case '"':
+			quoted = !quoted
+		case separator:
+			if !quoted {
+				pieces = append(pieces, value[start:index])
+				start = index + 1
+			}
+		}
+	}
+	return append(pieces, value[start:])
+}
+
+// remoteAddrIP returns the ip from an address that may have a port, and may be a bracketed ipv6 address.
+func remoteAddrIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// hostWithoutPort returns a host without its port.
+func hostWithoutPort(host string) string {
+	if withoutPort, _, err := net.SplitHostPort(host); err == nil {
+		return withoutPort
+	}
+	return host
+}
+
+// loggedRequest returns a copy of the request whose remote address is the resolved client ip,
+// without the forwarding headers loggers would otherwise read the client ip from.
+func (a *App) loggedRequest(req *http.Request) *http.Request {
+	logged := *req
+	logged.RemoteAddr = a.resolveClient(req).IP
+	logged.Header = http.Header{}
+	for key, values := range req.Header {
+		switch key {
+		case HeaderForwarded, HeaderXForwardedFor, HeaderXRealIP, "X-Forwarded-Ip":
+			continue
+		}
+		logged.Header[key] = values
+	}
+	return &logged
+}
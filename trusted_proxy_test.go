@@ -0,0 +1,163 @@
+package web
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+func trustedProxyTestCtx(app *App, remoteAddr string, headers map[string]string) *Ctx {
+	req := httptest.NewRequest("GET", "http://internal.local/foo?bar=baz", nil)
+	req.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	ctx := NewCtx(NewMockResponseWriter(nil), req, nil)
+	ctx.app = app
+	return ctx
+}
+
+func TestAppSetTrustedProxies(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	assert.Nil(app.SetTrustedProxies("10.0.0.0/8", "192.168.1.1", "fd00::/8"))
+	assert.Len(app.TrustedProxies(), 3)
+	assert.True(app.IsTrustedProxy("10.1.2.3"))
+	assert.True(app.IsTrustedProxy("192.168.1.1"))
+	assert.False(app.IsTrustedProxy("192.168.1.2"))
+	assert.True(app.IsTrustedProxy("fd00::1"))
+	assert.False(app.IsTrustedProxy("not an ip"))
+
+	assert.NotNil(app.SetTrustedProxies("10.0.0.0/33"))
+	assert.NotNil(app.SetTrustedProxies("proxy.local"))
+}
+
+func TestCtxRemoteIPUntrusted(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := trustedProxyTestCtx(New(), "203.0.113.9:5000", map[string]string{
+		HeaderXForwardedFor:   "198.51.100.1",
+		HeaderXForwardedProto: "https",
+		HeaderXForwardedHost:  "example.com",
+	})
+	assert.Equal("203.0.113.9", ctx.RemoteIP())
+	assert.Equal("http", ctx.Scheme())
+	assert.Equal("internal.local", ctx.Host())
+
+	ctx.Request.TLS = &tls.ConnectionState{}
+	assert.Equal("https", ctx.Scheme())
+}
+
+func TestCtxRemoteIPXForwarded(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetTrustedProxies("10.0.0.0/8")
+
+	ctx := trustedProxyTestCtx(app, "10.0.0.2:5000", map[string]string{
+		HeaderXForwardedFor:   "1.1.1.1, 198.51.100.1, 10.0.0.1",
+		HeaderXForwardedProto: "https",
+		HeaderXForwardedHost:  "example.com",
+	})
+	assert.Equal("198.51.100.1", ctx.RemoteIP(), "the spoofed leftmost address should be ignored")
+	assert.Equal("https", ctx.Scheme())
+	assert.Equal("example.com", ctx.Host())
+	assert.True(ctx.IsSecure())
+	assert.Equal("https://example.com/foo?bar=baz", ctx.URL().String())
+	assert.Equal("example.com", ctx.getCookieDomain())
+
+	ctx = trustedProxyTestCtx(app, "10.0.0.2:5000", map[string]string{HeaderXForwardedFor: "10.0.0.3"})
+	assert.Equal("10.0.0.3", ctx.RemoteIP())
+}
+
+func TestCtxRemoteIPForwarded(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetTrustedProxies("10.0.0.0/8")
+	app.SetForwardedHeaders(ForwardedHeadersRFC7239)
+	assert.Equal(ForwardedHeadersRFC7239, app.ForwardedHeaders())
+
+	ctx := trustedProxyTestCtx(app, "10.0.0.2:5000", map[string]string{
+		HeaderForwarded: `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711";proto=https;host="example.com:8443", for=10.0.0.1`,
+	})
+	assert.Equal("2001:db8:cafe::17", ctx.RemoteIP())
+	assert.Equal("https", ctx.Scheme())
+	assert.Equal("example.com:8443", ctx.Host())
+	assert.Equal("example.com", ctx.getCookieDomain())
+
+	ctx = trustedProxyTestCtx(app, "10.0.0.2:5000", map[string]string{
+		HeaderForwarded: `for=_hidden, for=10.0.0.1`,
+	})
+	assert.Equal("10.0.0.1", ctx.RemoteIP())
+
+	ctx = trustedProxyTestCtx(app, "10.0.0.2:5000", map[string]string{
+		HeaderXForwardedFor:   "203.0.113.9",
+		HeaderXForwardedProto: "https",
+	})
+	assert.Equal("10.0.0.2", ctx.RemoteIP(), "x-forwarded headers should be ignored")
+	assert.Equal("http", ctx.Scheme())
+}
+
+func TestCtxRemoteIPIgnoresClientForwarded(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetTrustedProxies("10.0.0.5")
+
+	// the proxy appends to x-forwarded-for, but passes through a forwarded header set by the client.
+	ctx := trustedProxyTestCtx(app, "10.0.0.5:5000", map[string]string{
+		HeaderXForwardedFor: "203.0.113.9",
+		HeaderForwarded:     "for=1.1.1.1;proto=https;host=evil.example",
+	})
+	client := app.resolveClient(ctx.Request)
+	assert.Equal(forwardedClient{IP: "203.0.113.9", Scheme: "http", Host: "internal.local"}, client)
+	assert.Equal("203.0.113.9", ctx.RemoteIP())
+	assert.False(ctx.IsSecure())
+	assert.Equal("internal.local", ctx.getCookieDomain())
+	assert.Equal("http://internal.local/foo?bar=baz", ctx.URL().String())
+}
+
+func TestAppLoggedRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetTrustedProxies("10.0.0.0/8")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set(HeaderXForwardedFor, "198.51.100.1")
+	req.Header.Set("User-Agent", "test")
+
+	logged := app.loggedRequest(req)
+	assert.Equal("198.51.100.1", logged.RemoteAddr)
+	assert.Empty(logged.Header.Get(HeaderXForwardedFor))
+	assert.Equal("test", logged.Header.Get("User-Agent"))
+	assert.Equal("198.51.100.1", req.Header.Get(HeaderXForwardedFor))
+}
+
+func TestAuthManagerCookieSecureFromProxy(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.SetTrustedProxies("10.0.0.0/8")
+	app.GET("/login", func(r *Ctx) Result {
+		r.Auth().Login(1, r)
+		return r.Text().Result("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/login", nil)
+	req.RemoteAddr = "10.0.0.2:5000"
+	req.Header.Set(HeaderXForwardedFor, "198.51.100.1")
+	req.Header.Set(HeaderXForwardedProto, "https")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+
+	cookies := (&http.Response{Header: res.Header()}).Cookies()
+	assert.Len(cookies, 1)
+	assert.True(cookies[0].Secure)
+}
@@ -1,7 +1,9 @@
 package web
 
 import (
+	"bufio"
 	"bytes"
+	"net"
 	"net/http"
 )
 
@@ -9,6 +11,11 @@ import (
 // UncompressedResponseWriter
 // --------------------------------------------------------------------------------
 
+const (
+	// BufferedResponseMaxBytes is the most of a response body the buffered writers retain for logging.
+	BufferedResponseMaxBytes = 1 << 20
+)
+
 // NewResponseWriter creates a new uncompressed response writer.
 func NewResponseWriter(w http.ResponseWriter) *UncompressedResponseWriter {
 	return &UncompressedResponseWriter{
@@ -16,7 +23,8 @@ func NewResponseWriter(w http.ResponseWriter) *UncompressedResponseWriter {
 	}
 }
 
-// NewBufferedResponseWriter creates a new uncompressed response writer.
+// NewBufferedResponseWriter creates a new uncompressed response writer that retains
+// (up to `BufferedResponseMaxBytes` of) the response body for logging.
 func NewBufferedResponseWriter(w http.ResponseWriter) *UncompressedResponseWriter {
 	return &UncompressedResponseWriter{
 		innerResponse:  w,
@@ -31,17 +39,19 @@ type UncompressedResponseWriter struct {
 	statusCode    int
 
 	responseBuffer *bytes.Buffer
+	hijacked       bool
 }
 
 // Write writes the data to the response.
 func (rw *UncompressedResponseWriter) Write(b []byte) (int, error) {
-	if rw.responseBuffer == nil {
-		written, err := rw.innerResponse.Write(b)
-		rw.contentLength += written
-		return written, err
+	if rw.hijacked {
+		return 0, http.ErrHijacked
 	}
-	written, err := rw.responseBuffer.Write(b)
+	written, err := rw.innerResponse.Write(b)
 	rw.contentLength += written
+	if rw.responseBuffer != nil {
+		retainResponseBytes(rw.responseBuffer, b[:written])
+	}
 	return written, err
 }
 
@@ -52,6 +62,9 @@ func (rw *UncompressedResponseWriter) Header() http.Header {
 
 // WriteHeader is actually a terrible name and this writes the status code.
 func (rw *UncompressedResponseWriter) WriteHeader(code int) {
+	if rw.hijacked {
+		return
+	}
 	rw.statusCode = code
 	rw.innerResponse.WriteHeader(code)
 }
@@ -79,13 +92,18 @@ func (rw *UncompressedResponseWriter) Bytes() []byte {
 	return rw.responseBuffer.Bytes()
 }
 
-// Flush is a no op on raw response writers.
-func (rw *UncompressedResponseWriter) Flush() error {
-	if rw.responseBuffer == nil {
+// Flush implements `http.Flusher`; see `FlushError`.
+func (rw *UncompressedResponseWriter) Flush() {
+	rw.FlushError()
+}
+
+// FlushError pushes any data written so far to the client, if the inner response supports it.
+func (rw *UncompressedResponseWriter) FlushError() error {
+	if rw.hijacked {
 		return nil
 	}
-	_, err := rw.responseBuffer.WriteTo(rw.innerResponse)
-	return err
+	flushInnerResponse(rw.innerResponse)
+	return nil
 }
 
 // Close disposes of the response writer.
@@ -93,3 +111,46 @@ func (rw *UncompressedResponseWriter) Close() error {
 
 	return nil
 }
+
+// Hijack takes over the underlying connection, if the inner response supports it.
+func (rw *UncompressedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buffered, err := hijackInnerResponse(rw.innerResponse)
+	if err == nil {
+		rw.hijacked = true
+		rw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, buffered, err
+}
+
+// reset clears the writer so it can be reused for another response.
+func (rw *UncompressedResponseWriter) reset() {
+	*rw = UncompressedResponseWriter{}
+}
+
+// retainResponseBytes copies written bytes into a logging buffer, up to `BufferedResponseMaxBytes`.
+func retainResponseBytes(buffer *bytes.Buffer, b []byte) {
+	remaining := BufferedResponseMaxBytes - buffer.Len()
+	if remaining <= 0 {
+		return
+	}
+	if len(b) > remaining {
+		b = b[:remaining]
+	}
+	buffer.Write(b)
+}
+
+// hijackInnerResponse hijacks the inner response if it is an `http.Hijacker`.
+func hijackInnerResponse(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, isHijacker := w.(http.Hijacker)
+	if !isHijacker {
+		return nil, nil, ErrHijackNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// flushInnerResponse flushes the inner response to the client if it is an `http.Flusher`.
+func flushInnerResponse(w http.ResponseWriter) {
+	if flusher, isFlusher := w.(http.Flusher); isFlusher {
+		flusher.Flush()
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/blendlabs/go-exception"
 )
@@ -66,6 +67,18 @@ func WriteXML(w http.ResponseWriter, r *http.Request, statusCode int, response i
 	return exception.Wrap(err)
 }
 
+// addVaryHeader adds a value to the "Vary" header if it is not already present.
+func addVaryHeader(header http.Header, value string) {
+	for _, existing := range header[HeaderVary] {
+		for _, field := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), value) {
+				return
+			}
+		}
+	}
+	header.Add(HeaderVary, value)
+}
+
 // DeserializeReaderAsJSON deserializes a post body as json to a given object.
 func DeserializeReaderAsJSON(object interface{}, body io.ReadCloser) error {
 	defer body.Close()
@@ -0,0 +1,300 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// TagNameValidate is the struct tag that lists the validation rules for a field.
+	TagNameValidate = "validate"
+
+	// ValidationRuleRequired requires a field to be set to a non-zero value.
+	ValidationRuleRequired = "required"
+	// ValidationRuleMin requires a number to be at least, or a string, slice or map length to be at least, the param.
+	ValidationRuleMin = "min"
+	// ValidationRuleMax requires a number to be at most, or a string, slice or map length to be at most, the param.
+	ValidationRuleMax = "max"
+	// ValidationRuleEmail requires a string to be an email address.
+	ValidationRuleEmail = "email"
+	// ValidationRuleOneOf requires a value to be one of a space separated list of values.
+	ValidationRuleOneOf = "oneof"
+)
+
+var (
+	// DefaultValidator is the validator used when there is no app validator, e.g. for the package level `ReadConfigFromEnvironment`.
+	DefaultValidator = NewValidator()
+
+	validationEmailExpr = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+	validationNameTags = []string{"json", TagNameQuery, TagNameForm, TagNameRoute, TagNameHeader, TagNameEnvironmentVariableName}
+)
+
+// ValidatorFunc is a validation rule. It is passed the field value (dereferenced if it is a pointer, nil if the pointer is nil)
+// and the rule param (the part after `=`), and returns an error describing the failure.
+type ValidatorFunc func(value interface{}, param string) error
+
+// ValidationFieldError is a validation failure for an individual field.
+type ValidationFieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error returns the error message.
+func (vfe ValidationFieldError) Error() string {
+	return fmt.Sprintf("%s: %s", vfe.Field, vfe.Message)
+}
+
+// ValidationError is an aggregate of validation failures.
+type ValidationError struct {
+	Fields []ValidationFieldError `json:"fields"`
+}
+
+// Error returns the combined error message.
+func (ve *ValidationError) Error() string {
+	buffer := bytes.NewBufferString("validation failed")
+	for index, field := range ve.Fields {
+		if index == 0 {
+			buffer.WriteString(": ")
+		} else {
+			buffer.WriteString("; ")
+		}
+		buffer.WriteString(field.Error())
+	}
+	return buffer.String()
+}
+
+// badRequestDetails returns the structured form of validation and bind errors, or nil for other errors.
+func badRequestDetails(err error) interface{} {
+	switch typed := err.(type) {
+	case *ValidationError:
+		return typed
+	case *BindError:
+		return typed
+	}
+	return nil
+}
+
+// NewValidator returns a new validator with the built in rules registered.
+func NewValidator() *Validator {
+	return &Validator{
+		rules: map[string]ValidatorFunc{
+			ValidationRuleMin:   validateMin,
+			ValidationRuleMax:   validateMax,
+			ValidationRuleEmail: validateEmail,
+			ValidationRuleOneOf: validateOneOf,
+		},
+	}
+}
+
+// Validator validates structs using the `validate` struct tag.
+// Rules are comma separated, with params given after `=`, e.g. `validate:"required,min=1,max=100"`.
+// Fields that are not `required` are only checked if they are set; nil pointers and empty strings, slices and maps are unset,
+// but zero numbers and false are values, so `min=1` fails for `0`. Use a pointer for an optional number.
+type Validator struct {
+	rules map[string]ValidatorFunc
+}
+
+// Register registers a named rule.
+func (v *Validator) Register(name string, rule ValidatorFunc) {
+	v.rules[name] = rule
+}
+
+// Validate validates a struct (or pointer to a struct).
+// It returns a `*ValidationError` listing any failing fields, or an error if a rule is not registered.
+func (v *Validator) Validate(target interface{}) error {
+	if target == nil || reflectType(target).Kind() != reflect.Struct {
+		return exception.New("validation target must be a struct")
+	}
+
+	targetValue := reflectValue(target)
+	targetType := targetValue.Type()
+	validationErr := &ValidationError{}
+	for index := 0; index < targetType.NumField(); index++ {
+		field := targetType.Field(index)
+		tag := field.Tag.Get(TagNameValidate)
+		if len(tag) == 0 || tag == "-" {
+			continue
+		}
+
+		fieldValue := targetValue.Field(index)
+		isZero := isZeroValue(fieldValue)
+		isUnset := isUnsetValue(fieldValue)
+		var value interface{}
+		if fieldValue.Kind() == reflect.Ptr {
+			if !fieldValue.IsNil() {
+				value = fieldValue.Elem().Interface()
+			}
+		} else if fieldValue.CanInterface() {
+			value = fieldValue.Interface()
+		}
+
+		for _, rule := range strings.Split(tag, ",") {
+			name, param := rule, ""
+			if equals := strings.Index(rule, "="); equals >= 0 {
+				name, param = rule[:equals], rule[equals+1:]
+			}
+			name = strings.TrimSpace(name)
+			if len(name) == 0 {
+				continue
+			}
+
+			if name == ValidationRuleRequired {
+				if isZero {
+					validationErr.Fields = append(validationErr.Fields, ValidationFieldError{
+						Field:   validationFieldName(field),
+						Rule:    name,
+						Message: "is required",
+					})
+					break
+				}
+				continue
+			}
+
+			validator, hasValidator := v.rules[name]
+			if !hasValidator {
+				return exception.Newf("validation rule `%s` is not registered", name)
+			}
+			if isUnset {
+				continue
+			}
+			if err := validator(value, param); err != nil {
+				validationErr.Fields = append(validationErr.Fields, ValidationFieldError{
+					Field:   validationFieldName(field),
+					Rule:    name,
+					Param:   param,
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+
+	if len(validationErr.Fields) > 0 {
+		return validationErr
+	}
+	return nil
+}
+
+// validationFieldName returns the name a field is known by externally, i.e. its json or bind tag name.
+func validationFieldName(field reflect.StructField) string {
+	for _, tagName := range validationNameTags {
+		if name := bindTagValue(field, tagName); len(name) > 0 {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func isZeroValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	}
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+// isUnsetValue returns if a field has no value to validate; numbers and bools always have one.
+func isUnsetValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return false
+	}
+	return isZeroValue(value)
+}
+
+// validationMagnitude returns the number to compare for min and max; the value for numbers, and the length otherwise.
+func validationMagnitude(value interface{}) (float64, bool, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true, nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, nil
+	}
+	return 0, false, exception.Newf("cannot compare value of type %T", value)
+}
+
+func validateMin(value interface{}, param string) error {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return exception.Newf("invalid min `%s`", param)
+	}
+	magnitude, isLength, err := validationMagnitude(value)
+	if err != nil {
+		return err
+	}
+	if magnitude < limit {
+		if isLength {
+			return fmt.Errorf("must have a length of at least %s", param)
+		}
+		return fmt.Errorf("must be at least %s", param)
+	}
+	return nil
+}
+
+func validateMax(value interface{}, param string) error {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return exception.Newf("invalid max `%s`", param)
+	}
+	magnitude, isLength, err := validationMagnitude(value)
+	if err != nil {
+		return err
+	}
+	if magnitude > limit {
+		if isLength {
+			return fmt.Errorf("must have a length of at most %s", param)
+		}
+		return fmt.Errorf("must be at most %s", param)
+	}
+	return nil
+}
+
+func validateEmail(value interface{}, param string) error {
+	if typed, isString := value.(string); isString && validationEmailExpr.MatchString(typed) {
+		return nil
+	}
+	return fmt.Errorf("must be an email address")
+}
+
+func validateOneOf(value interface{}, param string) error {
+	formatted := fmt.Sprintf("%v", value)
+	options := strings.Fields(param)
+	for _, option := range options {
+		if option == formatted {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of: %s", strings.Join(options, ", "))
+}
@@ -0,0 +1,217 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+type validationTestRequest struct {
+	Name    string   `json:"name" validate:"required,min=2,max=10"`
+	Email   string   `json:"email" validate:"email"`
+	Age     int      `query:"age" validate:"min=18,max=120"`
+	Color   string   `validate:"oneof=red green blue"`
+	Tags    []string `validate:"max=2"`
+	Nick    *string  `validate:"min=3"`
+	Ignored string
+}
+
+func TestValidatorValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	nick := "bo"
+	err := NewValidator().Validate(&validationTestRequest{
+		Email: "not an email",
+		Age:   12,
+		Color: "purple",
+		Tags:  []string{"a", "b", "c"},
+		Nick:  &nick,
+	})
+	assert.NotNil(err)
+	validationErr, isValidationErr := err.(*ValidationError)
+	assert.True(isValidationErr)
+	assert.Len(validationErr.Fields, 6)
+
+	assert.Equal("name", validationErr.Fields[0].Field)
+	assert.Equal(ValidationRuleRequired, validationErr.Fields[0].Rule)
+	assert.Equal("email", validationErr.Fields[1].Field)
+	assert.Equal(ValidationRuleEmail, validationErr.Fields[1].Rule)
+	assert.Equal("age", validationErr.Fields[2].Field)
+	assert.Equal(ValidationRuleMin, validationErr.Fields[2].Rule)
+	assert.Equal("18", validationErr.Fields[2].Param)
+	assert.Equal("Color", validationErr.Fields[3].Field)
+	assert.Equal(ValidationRuleOneOf, validationErr.Fields[3].Rule)
+	assert.Equal("Tags", validationErr.Fields[4].Field)
+	assert.Equal(ValidationRuleMax, validationErr.Fields[4].Rule)
+	assert.Equal("Nick", validationErr.Fields[5].Field)
+	assert.Contains("validation failed: name: is required", err.Error())
+}
+
+func TestValidatorValidateValid(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(NewValidator().Validate(validationTestRequest{
+		Name:  "bailey",
+		Email: "bailey@example.com",
+		Age:   30,
+		Color: "green",
+	}))
+}
+
+func TestValidatorCustomRule(t *testing.T) {
+	assert := assert.New(t)
+
+	type request struct {
+		Code string `validate:"required,upper"`
+	}
+
+	validator := NewValidator()
+	assert.NotNil(validator.Validate(request{Code: "abc"}), "unregistered rules should error")
+
+	validator.Register("upper", func(value interface{}, param string) error {
+		if value.(string) != "ABC" {
+			return fmt.Errorf("must be upper case")
+		}
+		return nil
+	})
+	assert.Nil(validator.Validate(request{Code: "ABC"}))
+
+	err := validator.Validate(request{Code: "abc"})
+	assert.NotNil(err)
+	assert.Equal("must be upper case", err.(*ValidationError).Fields[0].Message)
+}
+
+func TestCtxBindValidates(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.RegisterValidator("even", func(value interface{}, param string) error {
+		if value.(int)%2 != 0 {
+			return fmt.Errorf("must be even")
+		}
+		return nil
+	})
+	app.POST("/", func(ctx *Ctx) Result {
+		var req struct {
+			Name  string `json:"name" validate:"required"`
+			Count int    `query:"count" validate:"even"`
+		}
+		if err := ctx.Bind(&req); err != nil {
+			return ctx.API().BadRequestError(err)
+		}
+		return ctx.NoContent()
+	})
+
+	var res struct {
+		Meta     APIResponseMeta
+		Response ValidationError
+	}
+	meta, err := app.Mock().Post("/").
+		WithQueryString("count", "3").
+		WithHeader(HeaderContentType, ContentTypeApplicationJSON).
+		WithPostBody([]byte("{}")).
+		JSONWithMeta(&res)
+	assert.Nil(err)
+	assert.Equal(http.StatusBadRequest, meta.StatusCode)
+	assert.Equal(http.StatusBadRequest, res.Meta.StatusCode)
+	assert.Len(res.Response.Fields, 2)
+	assert.Equal("name", res.Response.Fields[0].Field)
+	assert.Equal("count", res.Response.Fields[1].Field)
+	assert.Equal("must be even", res.Response.Fields[1].Message)
+}
+
+func TestJSONResultProviderBadRequestError(t *testing.T) {
+	assert := assert.New(t)
+
+	validationErr := &ValidationError{Fields: []ValidationFieldError{{Field: "name", Rule: ValidationRuleRequired, Message: "is required"}}}
+	result := NewJSONResultProvider(nil).BadRequestError(validationErr).(*JSONResult)
+	assert.Equal(http.StatusBadRequest, result.StatusCode)
+
+	contents, err := json.Marshal(result.Response)
+	assert.Nil(err)
+	assert.Equal(`{"fields":[{"field":"name","rule":"required","message":"is required"}]}`, string(contents))
+
+	apiResult := NewAPIResultProvider(nil).BadRequestError(validationErr).(*JSONResult)
+	apiContents, err := json.Marshal(apiResult.Response.(*APIResponse).Response)
+	assert.Nil(err)
+	assert.Equal(string(contents), string(apiContents))
+
+	plain := NewJSONResultProvider(nil).BadRequestError(fmt.Errorf("bad")).(*JSONResult)
+	assert.Equal("bad", plain.Response)
+}
+
+func TestReadConfigFromEnvironmentValidates(t *testing.T) {
+	assert := assert.New(t)
+
+	type config struct {
+		Port string `env:"VALIDATION_TEST_PORT" validate:"required"`
+	}
+	defer MockEnvVar("VALIDATION_TEST_PORT", "")()
+
+	_, err := ReadConfigFromEnvironment(&config{})
+	assert.NotNil(err)
+	assert.Equal("VALIDATION_TEST_PORT", err.(*ValidationError).Fields[0].Field)
+}
+
+func TestValidatorZeroNumbers(t *testing.T) {
+	assert := assert.New(t)
+
+	type request struct {
+		Count    int  `validate:"min=1"`
+		Optional *int `validate:"min=1"`
+	}
+
+	err := NewValidator().Validate(request{})
+	assert.NotNil(err)
+	assert.Len(err.(*ValidationError).Fields, 1)
+	assert.Equal("Count", err.(*ValidationError).Fields[0].Field)
+
+	zero := 0
+	err = NewValidator().Validate(request{Count: 1, Optional: &zero})
+	assert.NotNil(err)
+	assert.Equal("Optional", err.(*ValidationError).Fields[0].Field)
+	assert.Nil(NewValidator().Validate(request{Count: 1}))
+}
+
+func TestAppReadConfigFromEnvironment(t *testing.T) {
+	assert := assert.New(t)
+
+	type config struct {
+		Region string `env:"VALIDATION_TEST_REGION" validate:"region"`
+	}
+	defer MockEnvVar("VALIDATION_TEST_REGION", "mars")()
+
+	app := New()
+	app.RegisterValidator("region", func(value interface{}, param string) error {
+		if value.(string) != "us-east-1" {
+			return fmt.Errorf("must be a known region")
+		}
+		return nil
+	})
+
+	_, err := app.ReadConfigFromEnvironment(&config{})
+	assert.NotNil(err)
+	assert.Equal("must be a known region", err.(*ValidationError).Fields[0].Message)
+
+	_, err = ReadConfigFromEnvironment(&config{})
+	assert.NotNil(err, "the default validator does not know the app rule")
+	_, isValidationErr := err.(*ValidationError)
+	assert.False(isValidationErr)
+}
+
+func TestBadRequestErrorResult(t *testing.T) {
+	assert := assert.New(t)
+
+	validationErr := &ValidationError{Fields: []ValidationFieldError{{Field: "name", Rule: ValidationRuleRequired, Message: "is required"}}}
+
+	var provider ResultProvider = NewAPIResultProvider(nil)
+	result := BadRequestErrorResult(provider, validationErr).(*JSONResult)
+	assert.Equal(validationErr, result.Response.(*APIResponse).Response)
+
+	raw := BadRequestErrorResult(NewTextResultProvider(nil), validationErr).(*RawResult)
+	assert.Equal(http.StatusBadRequest, raw.StatusCode)
+	assert.Contains("name: is required", string(raw.Body))
+}
@@ -6,6 +6,16 @@ import (
 	"time"
 )
 
+const (
+	// ViewFuncURLFor is the name of the view func that returns the url for a named route.
+	// Usage: {{ url_for "user" "id" .ViewModel.ID }}
+	ViewFuncURLFor = "url_for"
+
+	// ViewFuncCSRFToken is the name of the view func that returns the csrf token for a request.
+	// Usage: <input type="hidden" name="csrf_token" value="{{ csrf_token .Ctx }}">
+	ViewFuncCSRFToken = "csrf_token"
+)
+
 // NewViewCache returns a new view cache.
 func NewViewCache() *ViewCache {
 	return &ViewCache{
@@ -105,5 +115,8 @@ func viewUtils() template.FuncMap {
 		"money": func(d float64) string {
 			return fmt.Sprintf("$%0.2f", d)
 		},
+		ViewFuncCSRFToken: func(ctx *Ctx) (string, error) {
+			return ctx.CSRFToken()
+		},
 	}
 }
@@ -9,9 +9,11 @@ import (
 )
 
 // ViewModel is a wrapping viewmodel.
+// Error views can show the `RequestID` so users can quote it when reporting a problem.
 type ViewModel struct {
 	Ctx       *Ctx
 	Template  string
+	RequestID string
 	ViewModel interface{}
 }
 
@@ -22,6 +24,7 @@ type ViewResult struct {
 	Template   string
 
 	viewCache *ViewCache
+	fallback  Result
 }
 
 // Render renders the result to the given response writer.
@@ -50,21 +53,34 @@ func (vr *ViewResult) Render(ctx *Ctx) error {
 		return err
 	}
 
+	if vr.fallback != nil && viewTemplates.Lookup(vr.Template) == nil {
+		return vr.fallback.Render(ctx)
+	}
+
 	ctx.Response.Header().Set(HeaderContentType, ContentTypeHTML)
 
+	// template execution stops at the next write once the client goes away.
 	buffer := bytes.NewBuffer([]byte{})
-	err = viewTemplates.ExecuteTemplate(buffer, vr.Template, &ViewModel{
+	span := ctx.StartSpan(SpanNameView).SetAttribute("template", vr.Template)
+	err = viewTemplates.ExecuteTemplate(contextWriter{ctx: ctx.Context(), w: buffer}, vr.Template, &ViewModel{
 		Ctx:       ctx,
 		Template:  vr.Template,
+		RequestID: ctx.RequestID(),
 		ViewModel: vr.ViewModel,
 	})
+	span.SetError(err).Finish()
 
+	// the client went away mid render, so there is no one to respond to and nothing worth logging.
+	if ctx.Err() != nil {
+		return nil
+	}
 	if err != nil {
 		buffer.Reset()
 
 		err = vr.viewCache.Templates().ExecuteTemplate(buffer, DefaultTemplateInternalServerError, &ViewModel{
 			Ctx:       ctx,
 			Template:  DefaultTemplateInternalServerError,
+			RequestID: ctx.RequestID(),
 			ViewModel: err,
 		})
 
@@ -74,5 +90,8 @@ func (vr *ViewResult) Render(ctx *Ctx) error {
 	}
 	ctx.Response.WriteHeader(vr.StatusCode)
 	_, err = ctx.Response.Write(buffer.Bytes())
+	if err != nil && ctx.Err() != nil {
+		return nil
+	}
 	return err
 }
@@ -14,6 +14,11 @@ const (
 
 	// DefaultTemplateNotAuthorized is the default template name for not authorized error view results.
 	DefaultTemplateNotAuthorized = "not_authorized"
+
+	// DefaultTemplateStatus is the default template name for view results for other status codes,
+	// like `429 Too Many Requests` from rate limiting, `503`/`504` from timeouts and `413` from body size limits.
+	// Its view model is the status message. It is optional: apps that don't define it get text results.
+	DefaultTemplateStatus = "status"
 )
 
 // NewViewResultProvider creates a new ViewResults object.
@@ -71,6 +76,19 @@ func (vr *ViewResultProvider) NotAuthorized() Result {
 	}
 }
 
+// Status returns a view result for a status code, rendered with the `status` template.
+// The view model is the message, or the status text if the message is empty.
+// If the app does not define the `status` template, it falls back to a text result.
+func (vr *ViewResultProvider) Status(statusCode int, message string) Result {
+	return &ViewResult{
+		StatusCode: statusCode,
+		ViewModel:  statusMessage(statusCode, message),
+		Template:   DefaultTemplateStatus,
+		viewCache:  vr.viewCache,
+		fallback:   NewTextResultProvider(vr.ctx).Status(statusCode, message),
+	}
+}
+
 // View returns a view result.
 func (vr *ViewResultProvider) View(viewName string, viewModel interface{}) Result {
 	return &ViewResult{
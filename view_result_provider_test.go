@@ -103,3 +103,27 @@ func TestViewResultProviderView(t *testing.T) {
 	assert.True(isTyped)
 	assert.Equal(http.StatusOK, typed.StatusCode)
 }
+
+func TestViewResultProviderStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	app := New()
+	app.ViewCache().SetTemplates(template.Must(template.New("").Parse(`{{ define "index" }}index{{ end }}`)))
+	app.GET("/", func(r *Ctx) Result {
+		return StatusResult(r.DefaultResultProvider(), http.StatusTooManyRequests, "")
+	}, ViewProviderAsDefault)
+
+	// without a `status` template, the status falls back to text rather than an internal error.
+	contents, meta, err := app.Mock().BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusTooManyRequests, meta.StatusCode)
+	assert.Equal(ContentTypeText, meta.Headers.Get(HeaderContentType))
+	assert.Equal(http.StatusText(http.StatusTooManyRequests), string(contents))
+
+	app.ViewCache().SetTemplates(template.Must(template.New("").Parse(`{{ define "status" }}<p>{{ .ViewModel }}</p>{{ end }}`)))
+	contents, meta, err = app.Mock().BytesWithMeta()
+	assert.Nil(err)
+	assert.Equal(http.StatusTooManyRequests, meta.StatusCode)
+	assert.Equal(ContentTypeHTML, meta.Headers.Get(HeaderContentType))
+	assert.Equal("<p>Too Many Requests</p>", string(contents))
+}
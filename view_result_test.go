@@ -2,6 +2,7 @@ package web
 
 import (
 	"bytes"
+	"context"
 	"html/template"
 	"net/http"
 	"strings"
@@ -35,6 +36,30 @@ func TestViewResultRender(t *testing.T) {
 	assert.True(strings.Contains(buffer.String(), "bar"))
 }
 
+func TestViewResultRenderClientGone(t *testing.T) {
+	assert := assert.New(t)
+
+	buffer := bytes.NewBuffer([]byte{})
+	rc, err := NewMockRequestBuilder(nil).WithResponseBuffer(buffer).Ctx(nil)
+	assert.Nil(err)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	rc.WithContext(canceled)
+
+	testView := template.New("testView")
+	testView.Parse("{{.ViewModel.Text}}")
+
+	vr := &ViewResult{
+		StatusCode: http.StatusOK,
+		ViewModel:  testViewModel{Text: "bar"},
+		Template:   "testView",
+		viewCache:  NewViewCacheWithTemplates(template.Must(testView, nil)),
+	}
+
+	assert.Nil(vr.Render(rc), "a client that went away should not be reported as a render error")
+	assert.Zero(buffer.Len())
+}
+
 func TestViewResultRenderError(t *testing.T) {
 	assert := assert.New(t)
 
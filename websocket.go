@@ -0,0 +1,244 @@
+package web
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	exception "github.com/blendlabs/go-exception"
+)
+
+const (
+	// DefaultWebSocketMaxMessageSize is the default limit on the size of a received websocket message.
+	DefaultWebSocketMaxMessageSize = 1 << 20
+
+	// webSocketGUID is the magic value the accept key is derived with.
+	webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	webSocketVersion          = "13"
+	webSocketExtensionDeflate = "permessage-deflate"
+)
+
+// WebSocketHandler handles an upgraded websocket connection.
+// The connection is closed when the handler returns; if it returns an error, it is logged
+// and the connection is closed with `WebSocketCloseInternalError`.
+// When the app starts shutting down the connection is closed with `WebSocketCloseGoingAway`, so reads fail
+// and the handler should return; handlers that do not read can wait on `App.ShutdownStarted`.
+type WebSocketHandler func(ctx *Ctx, conn *WebSocketConn) error
+
+// NewWebSocketUpgrader returns a new websocket upgrader with the default message size limit and compression enabled.
+func NewWebSocketUpgrader() *WebSocketUpgrader {
+	return &WebSocketUpgrader{
+		MaxMessageSize:    DefaultWebSocketMaxMessageSize,
+		EnableCompression: true,
+	}
+}
+
+// WebSocketUpgrader upgrades requests to websocket connections.
+type WebSocketUpgrader struct {
+	// MaxMessageSize is the largest message (after decompression) that can be read; 0 is unlimited.
+	MaxMessageSize int64
+	// EnableCompression enables permessage-deflate if the client offers it.
+	EnableCompression bool
+	// Subprotocols are the supported subprotocols, in order of preference.
+	Subprotocols []string
+	// CheckOrigin returns if the request origin is allowed. If unset, the origin (if any) must match the host.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Upgrade completes the websocket handshake and hijacks the connection.
+// If the handshake fails, it returns a result describing the failure instead.
+func (wsu *WebSocketUpgrader) Upgrade(ctx *Ctx) (*WebSocketConn, Result) {
+	r := ctx.Request
+	if r.Method != "GET" {
+		return nil, ctx.DefaultResultProvider().BadRequest("websocket: upgrade requires a GET request")
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return nil, ctx.DefaultResultProvider().BadRequest("websocket: missing upgrade headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != webSocketVersion {
+		ctx.Response.Header().Set("Sec-WebSocket-Version", webSocketVersion)
+		return nil, &RawResult{StatusCode: http.StatusUpgradeRequired, ContentType: ContentTypeText, Body: []byte("websocket: unsupported version")}
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, ctx.DefaultResultProvider().BadRequest("websocket: invalid key")
+	}
+	checkOrigin := wsu.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = isSameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, StatusResult(ctx.DefaultResultProvider(), http.StatusForbidden, "websocket: origin not allowed")
+	}
+
+	hijacker, isHijacker := ctx.Response.(http.Hijacker)
+	if !isHijacker {
+		return nil, ctx.DefaultResultProvider().InternalError(exception.New("websocket: response does not support hijacking"))
+	}
+
+	subprotocol := wsu.negotiateSubprotocol(r)
+	compressed := wsu.EnableCompression && offersWebSocketDeflate(r.Header)
+
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, ctx.DefaultResultProvider().InternalError(exception.Wrap(err))
+	}
+	ctx.hijacked()
+
+	response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + webSocketAcceptKey(key) + "\r\n"
+	if len(subprotocol) > 0 {
+		response += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	if compressed {
+		response += "Sec-WebSocket-Extensions: " + webSocketExtensionDeflate + "; server_no_context_takeover; client_no_context_takeover\r\n"
+	}
+	response += "\r\n"
+
+	if _, err = buffered.WriteString(response); err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		ctx.logError(exception.Wrap(err))
+		return nil, nil
+	}
+	// the server read and write timeouts are meant for the request, not the connection it was upgraded to.
+	if err = conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		ctx.logError(exception.Wrap(err))
+		return nil, nil
+	}
+	return newWebSocketConn(conn, buffered.Reader, subprotocol, compressed, wsu.MaxMessageSize), nil
+}
+
+func (wsu *WebSocketUpgrader) negotiateSubprotocol(r *http.Request) string {
+	offered := headerTokens(r.Header, "Sec-WebSocket-Protocol")
+	for _, supported := range wsu.Subprotocols {
+		for _, protocol := range offered {
+			if protocol == supported {
+				return supported
+			}
+		}
+	}
+	return ""
+}
+
+// WebSocket registers a websocket handler for a path. The route and app middleware
+// run before the upgrade, so they can reject the request (e.g. `SessionRequired`).
+func (a *App) WebSocket(path string, handler WebSocketHandler, middleware ...Middleware) *Route {
+	return a.GET(path, a.webSocketAction(handler), middleware...)
+}
+
+// WebSocketUpgrader returns the upgrader used by websocket routes.
+func (a *App) WebSocketUpgrader() *WebSocketUpgrader {
+	return a.webSocketUpgrader
+}
+
+// SetWebSocketUpgrader sets the upgrader used by websocket routes.
+func (a *App) SetWebSocketUpgrader(upgrader *WebSocketUpgrader) {
+	a.webSocketUpgrader = upgrader
+}
+
+// webSocketAction is the action that upgrades the request and runs the handler.
+func (a *App) webSocketAction(handler WebSocketHandler) Action {
+	return func(ctx *Ctx) Result {
+		upgrader := a.webSocketUpgrader
+		if upgrader == nil {
+			upgrader = NewWebSocketUpgrader()
+		}
+		conn, result := upgrader.Upgrade(ctx)
+		if conn == nil {
+			return result
+		}
+
+		handled := make(chan struct{})
+		defer close(handled)
+		go func() {
+			select {
+			case <-a.ShutdownStarted():
+				conn.Close(WebSocketCloseGoingAway, "server shutting down")
+			case <-handled:
+			}
+		}()
+
+		if err := handler(ctx, conn); err != nil {
+			if _, isClose := err.(*WebSocketCloseError); !isClose && !a.isShuttingDown() {
+				ctx.logError(err)
+				conn.Close(WebSocketCloseInternalError, "")
+				return nil
+			}
+		}
+		conn.Close(WebSocketCloseNormal, "")
+		return nil
+	}
+}
+
+// webSocketAcceptKey returns the `Sec-WebSocket-Accept` value for a key.
+func webSocketAcceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// offersWebSocketDeflate returns if the client offers permessage-deflate with parameters we can accept.
+// Go's flate always uses a 32k window, so offers that limit the server window are declined.
+func offersWebSocketDeflate(header http.Header) bool {
+	for _, offer := range headerTokens(header, "Sec-WebSocket-Extensions") {
+		params := strings.Split(offer, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), webSocketExtensionDeflate) {
+			continue
+		}
+		acceptable := true
+		for _, param := range params[1:] {
+			name := strings.ToLower(strings.TrimSpace(param))
+			if index := strings.Index(name, "="); index >= 0 {
+				if strings.TrimSpace(name[:index]) == "server_max_window_bits" && strings.Trim(strings.TrimSpace(name[index+1:]), `"`) != "15" {
+					acceptable = false
+				}
+			}
+		}
+		if acceptable {
+			return true
+		}
+	}
+	return false
+}
+
+// isSameOrigin returns if the request has no origin, or an origin matching the host.
+func isSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(parsed.Host, r.Host)
+}
+
+// headerTokens returns the comma separated values of a header, across all of its lines.
+func headerTokens(header http.Header, key string) []string {
+	var tokens []string
+	for _, value := range header[http.CanonicalHeaderKey(key)] {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); len(token) > 0 {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// headerHasToken returns if a comma separated header contains a token, ignoring case.
+func headerHasToken(header http.Header, key, token string) bool {
+	for _, value := range headerTokens(header, key) {
+		if strings.EqualFold(value, token) {
+			return true
+		}
+	}
+	return false
+}
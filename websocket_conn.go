@@ -0,0 +1,414 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocketMessageType is the opcode of a websocket frame.
+type WebSocketMessageType int
+
+const (
+	// WebSocketContinuationFrame continues a fragmented message.
+	WebSocketContinuationFrame WebSocketMessageType = 0
+	// WebSocketTextMessage is a UTF-8 text message.
+	WebSocketTextMessage WebSocketMessageType = 1
+	// WebSocketBinaryMessage is a binary message.
+	WebSocketBinaryMessage WebSocketMessageType = 2
+	// WebSocketCloseMessage is a close control frame.
+	WebSocketCloseMessage WebSocketMessageType = 8
+	// WebSocketPingMessage is a ping control frame.
+	WebSocketPingMessage WebSocketMessageType = 9
+	// WebSocketPongMessage is a pong control frame.
+	WebSocketPongMessage WebSocketMessageType = 10
+)
+
+// Websocket close codes, from RFC 6455 section 7.4.1.
+const (
+	WebSocketCloseNormal             = 1000
+	WebSocketCloseGoingAway          = 1001
+	WebSocketCloseProtocolError      = 1002
+	WebSocketCloseUnsupportedData    = 1003
+	WebSocketCloseNoStatus           = 1005
+	WebSocketCloseAbnormal           = 1006
+	WebSocketCloseInvalidPayload     = 1007
+	WebSocketClosePolicyViolation    = 1008
+	WebSocketCloseMessageTooBig      = 1009
+	WebSocketCloseMandatoryExtension = 1010
+	WebSocketCloseInternalError      = 1011
+)
+
+const (
+	webSocketFinalBit      = 0x80
+	webSocketRSV1Bit       = 0x40
+	webSocketRSV2Bit       = 0x20
+	webSocketRSV3Bit       = 0x10
+	webSocketOpcodeMask    = 0x0f
+	webSocketMaskBit       = 0x80
+	webSocketMaxControlLen = 125
+)
+
+var (
+	// webSocketDeflateTail is the empty stored block the sender strips from the end of each compressed message.
+	webSocketDeflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+	// webSocketDeflateFinal is a final empty block, so the decompressor reaches the end of the stream.
+	webSocketDeflateFinal = []byte{0x01, 0x00, 0x00, 0xff, 0xff}
+)
+
+// WebSocketCloseError is returned from `ReadMessage` when the connection is closed.
+type WebSocketCloseError struct {
+	Code   int
+	Reason string
+}
+
+// Error implements error.
+func (wce *WebSocketCloseError) Error() string {
+	if len(wce.Reason) > 0 {
+		return fmt.Sprintf("websocket closed: %d %s", wce.Code, wce.Reason)
+	}
+	return fmt.Sprintf("websocket closed: %d", wce.Code)
+}
+
+// newWebSocketConn returns a new server side websocket connection.
+func newWebSocketConn(conn net.Conn, reader *bufio.Reader, subprotocol string, compressed bool, maxMessageSize int64) *WebSocketConn {
+	return &WebSocketConn{
+		conn:           conn,
+		reader:         reader,
+		subprotocol:    subprotocol,
+		compressed:     compressed,
+		maxMessageSize: maxMessageSize,
+	}
+}
+
+// WebSocketConn is the server side of a websocket connection.
+// Reads are not safe to call concurrently; writes are.
+type WebSocketConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	subprotocol    string
+	compressed     bool
+	maxMessageSize int64
+	pongHandler    func(data []byte)
+
+	writeLock sync.Mutex
+	closeSent bool
+}
+
+// Subprotocol returns the negotiated subprotocol, if any.
+func (wsc *WebSocketConn) Subprotocol() string {
+	return wsc.subprotocol
+}
+
+// IsCompressed returns if permessage-deflate was negotiated.
+func (wsc *WebSocketConn) IsCompressed() bool {
+	return wsc.compressed
+}
+
+// RemoteAddr returns the remote network address.
+func (wsc *WebSocketConn) RemoteAddr() net.Addr {
+	return wsc.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the deadline for future reads.
+func (wsc *WebSocketConn) SetReadDeadline(deadline time.Time) error {
+	return wsc.conn.SetReadDeadline(deadline)
+}
+
+// SetWriteDeadline sets the deadline for future writes.
+func (wsc *WebSocketConn) SetWriteDeadline(deadline time.Time) error {
+	return wsc.conn.SetWriteDeadline(deadline)
+}
+
+// SetPongHandler sets a handler called with the payload of pongs received while reading.
+func (wsc *WebSocketConn) SetPongHandler(handler func(data []byte)) {
+	wsc.pongHandler = handler
+}
+
+// ReadMessage reads the next text or binary message. Pings are answered and pongs are
+// passed to the pong handler while reading. When the peer closes the connection
+// (or violates the protocol) the close handshake is completed and a `*WebSocketCloseError` is returned.
+func (wsc *WebSocketConn) ReadMessage() (WebSocketMessageType, []byte, error) {
+	var messageType WebSocketMessageType
+	var message []byte
+	var messageCompressed bool
+
+	for {
+		final, rsv1, opcode, payload, err := wsc.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case WebSocketPingMessage:
+			if err := wsc.writeFrame(WebSocketPongMessage, payload, false); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case WebSocketPongMessage:
+			if wsc.pongHandler != nil {
+				wsc.pongHandler(payload)
+			}
+			continue
+		case WebSocketCloseMessage:
+			return 0, nil, wsc.onClose(payload)
+		case WebSocketTextMessage, WebSocketBinaryMessage:
+			if messageType != 0 {
+				return 0, nil, wsc.fail(WebSocketCloseProtocolError, "expected continuation frame")
+			}
+			messageType = opcode
+			messageCompressed = rsv1
+		case WebSocketContinuationFrame:
+			if messageType == 0 {
+				return 0, nil, wsc.fail(WebSocketCloseProtocolError, "unexpected continuation frame")
+			}
+			if rsv1 {
+				return 0, nil, wsc.fail(WebSocketCloseProtocolError, "unexpected rsv1 bit")
+			}
+		default:
+			return 0, nil, wsc.fail(WebSocketCloseProtocolError, "unknown opcode")
+		}
+
+		if wsc.maxMessageSize > 0 && int64(len(message)+len(payload)) > wsc.maxMessageSize {
+			return 0, nil, wsc.fail(WebSocketCloseMessageTooBig, "message too big")
+		}
+		message = append(message, payload...)
+		if !final {
+			continue
+		}
+
+		if messageCompressed {
+			message, err = wsc.decompress(message)
+			if err != nil {
+				return 0, nil, err
+			}
+		}
+		if messageType == WebSocketTextMessage && !utf8.Valid(message) {
+			return 0, nil, wsc.fail(WebSocketCloseInvalidPayload, "invalid utf-8")
+		}
+		return messageType, message, nil
+	}
+}
+
+// WriteMessage writes a text or binary message, compressed if permessage-deflate was negotiated.
+func (wsc *WebSocketConn) WriteMessage(messageType WebSocketMessageType, data []byte) error {
+	if messageType != WebSocketTextMessage && messageType != WebSocketBinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	if !wsc.compressed {
+		return wsc.writeFrame(messageType, data, false)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	compressor, err := flate.NewWriter(buffer, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+	if _, err = compressor.Write(data); err != nil {
+		return err
+	}
+	if err = compressor.Flush(); err != nil {
+		return err
+	}
+	return wsc.writeFrame(messageType, bytes.TrimSuffix(buffer.Bytes(), webSocketDeflateTail), true)
+}
+
+// WriteText writes a text message.
+func (wsc *WebSocketConn) WriteText(text string) error {
+	return wsc.WriteMessage(WebSocketTextMessage, []byte(text))
+}
+
+// Ping sends a ping with an optional payload of up to 125 bytes.
+func (wsc *WebSocketConn) Ping(data []byte) error {
+	if len(data) > webSocketMaxControlLen {
+		return fmt.Errorf("websocket: control frame payload too long")
+	}
+	return wsc.writeFrame(WebSocketPingMessage, data, false)
+}
+
+// Close sends a close frame with a code and reason and closes the connection.
+func (wsc *WebSocketConn) Close(code int, reason string) error {
+	wsc.writeClose(code, reason)
+	return wsc.conn.Close()
+}
+
+func (wsc *WebSocketConn) writeClose(code int, reason string) error {
+	var payload []byte
+	if code != WebSocketCloseNoStatus {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+		if len(payload) > webSocketMaxControlLen {
+			payload = payload[:webSocketMaxControlLen]
+		}
+	}
+
+	wsc.writeLock.Lock()
+	defer wsc.writeLock.Unlock()
+	if wsc.closeSent {
+		return nil
+	}
+	wsc.closeSent = true
+	return wsc.writeFrameUnlocked(WebSocketCloseMessage, payload, false)
+}
+
+// onClose answers a close frame from the peer.
+func (wsc *WebSocketConn) onClose(payload []byte) error {
+	closeErr := &WebSocketCloseError{Code: WebSocketCloseNoStatus}
+	if len(payload) == 1 {
+		return wsc.fail(WebSocketCloseProtocolError, "invalid close frame")
+	}
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+		if !isValidWebSocketCloseCode(closeErr.Code) || !utf8.ValidString(closeErr.Reason) {
+			return wsc.fail(WebSocketCloseProtocolError, "invalid close frame")
+		}
+	}
+	wsc.writeClose(closeErr.Code, "")
+	wsc.conn.Close()
+	return closeErr
+}
+
+// fail closes the connection because of a protocol error.
+func (wsc *WebSocketConn) fail(code int, reason string) error {
+	wsc.Close(code, reason)
+	return &WebSocketCloseError{Code: code, Reason: reason}
+}
+
+func (wsc *WebSocketConn) readFrame() (final, rsv1 bool, opcode WebSocketMessageType, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(wsc.reader, header[:]); err != nil {
+		return
+	}
+
+	final = header[0]&webSocketFinalBit != 0
+	rsv1 = header[0]&webSocketRSV1Bit != 0
+	opcode = WebSocketMessageType(header[0] & webSocketOpcodeMask)
+	if header[0]&(webSocketRSV2Bit|webSocketRSV3Bit) != 0 || (rsv1 && !wsc.compressed) {
+		err = wsc.fail(WebSocketCloseProtocolError, "unexpected reserved bits")
+		return
+	}
+	if header[1]&webSocketMaskBit == 0 {
+		err = wsc.fail(WebSocketCloseProtocolError, "client frames must be masked")
+		return
+	}
+
+	isControl := opcode >= WebSocketCloseMessage
+	if isControl && (!final || rsv1) {
+		err = wsc.fail(WebSocketCloseProtocolError, "invalid control frame")
+		return
+	}
+
+	length := int64(header[1] &^ webSocketMaskBit)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(wsc.reader, extended[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(wsc.reader, extended[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(extended[:]))
+	}
+	if isControl && length > webSocketMaxControlLen {
+		err = wsc.fail(WebSocketCloseProtocolError, "control frame too long")
+		return
+	}
+	if length < 0 || (wsc.maxMessageSize > 0 && length > wsc.maxMessageSize) {
+		err = wsc.fail(WebSocketCloseMessageTooBig, "message too big")
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(wsc.reader, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(wsc.reader, payload); err != nil {
+		return
+	}
+	for index := range payload {
+		payload[index] ^= mask[index%4]
+	}
+	return
+}
+
+func (wsc *WebSocketConn) writeFrame(opcode WebSocketMessageType, payload []byte, compressed bool) error {
+	wsc.writeLock.Lock()
+	defer wsc.writeLock.Unlock()
+	if wsc.closeSent {
+		return &WebSocketCloseError{Code: WebSocketCloseNormal, Reason: "close already sent"}
+	}
+	return wsc.writeFrameUnlocked(opcode, payload, compressed)
+}
+
+func (wsc *WebSocketConn) writeFrameUnlocked(opcode WebSocketMessageType, payload []byte, compressed bool) error {
+	frame := make([]byte, 0, len(payload)+10)
+	first := byte(webSocketFinalBit) | byte(opcode)
+	if compressed {
+		first |= webSocketRSV1Bit
+	}
+	frame = append(frame, first)
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 126, byte(length>>8), byte(length))
+	default:
+		var extended [8]byte
+		binary.BigEndian.PutUint64(extended[:], uint64(length))
+		frame = append(frame, 127)
+		frame = append(frame, extended[:]...)
+	}
+	frame = append(frame, payload...)
+
+	_, err := wsc.conn.Write(frame)
+	return err
+}
+
+// decompress inflates a permessage-deflate message, enforcing the max message size.
+func (wsc *WebSocketConn) decompress(message []byte) ([]byte, error) {
+	reader := flate.NewReader(io.MultiReader(bytes.NewReader(message), bytes.NewReader(webSocketDeflateTail), bytes.NewReader(webSocketDeflateFinal)))
+	defer reader.Close()
+
+	var limited io.Reader = reader
+	if wsc.maxMessageSize > 0 {
+		limited = io.LimitReader(reader, wsc.maxMessageSize+1)
+	}
+	decompressed, err := ioutil.ReadAll(limited)
+	if err != nil {
+		return nil, wsc.fail(WebSocketCloseInvalidPayload, "invalid compressed message")
+	}
+	if wsc.maxMessageSize > 0 && int64(len(decompressed)) > wsc.maxMessageSize {
+		return nil, wsc.fail(WebSocketCloseMessageTooBig, "message too big")
+	}
+	return decompressed, nil
+}
+
+// isValidWebSocketCloseCode returns if a close code can be sent in a close frame.
+func isValidWebSocketCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003:
+		return true
+	case code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
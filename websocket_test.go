@@ -0,0 +1,267 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	assert "github.com/blendlabs/go-assert"
+)
+
+const webSocketTestKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+// webSocketTestDial performs the client handshake and returns the connection and response.
+func webSocketTestDial(t *testing.T, server *httptest.Server, path string, headers map[string]string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := "GET " + path + " HTTP/1.1\r\nHost: " + strings.TrimPrefix(server.URL, "http://") + "\r\n" +
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: " + webSocketTestKey + "\r\n"
+	for key, value := range headers {
+		request += key + ": " + value + "\r\n"
+	}
+	if _, err = conn.Write([]byte(request + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, reader, res
+}
+
+// webSocketTestWriteFrame writes a masked client frame.
+func webSocketTestWriteFrame(conn net.Conn, first byte, payload []byte) {
+	frame := []byte{first}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for index, b := range payload {
+		frame = append(frame, b^mask[index%4])
+	}
+	conn.Write(frame)
+}
+
+// webSocketTestReadFrame reads an unmasked server frame.
+func webSocketTestReadFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var extended [2]byte
+		io.ReadFull(reader, extended[:])
+		length = int(binary.BigEndian.Uint16(extended[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0], payload
+}
+
+func newWebSocketTestApp() *App {
+	app := New()
+	app.WebSocket("/echo", func(ctx *Ctx, conn *WebSocketConn) error {
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return err
+			}
+			if err = conn.WriteMessage(messageType, message); err != nil {
+				return err
+			}
+		}
+	})
+	app.WebSocket("/private", func(ctx *Ctx, conn *WebSocketConn) error {
+		return nil
+	}, func(action Action) Action {
+		return func(ctx *Ctx) Result {
+			return ctx.DefaultResultProvider().NotAuthorized()
+		}
+	})
+	return app
+}
+
+func TestWebSocketAcceptKey(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", webSocketAcceptKey(webSocketTestKey))
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(newWebSocketTestApp())
+	defer server.Close()
+
+	conn, reader, res := webSocketTestDial(t, server, "/echo", map[string]string{HeaderAcceptEncoding: ContentEncodingGZIP})
+	defer conn.Close()
+	assert.Equal(http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal("s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", res.Header.Get("Sec-WebSocket-Accept"))
+	assert.Empty(res.Header.Get("Sec-WebSocket-Extensions"))
+
+	webSocketTestWriteFrame(conn, 0x81, []byte("hello"))
+	first, payload := webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0x81), first)
+	assert.Equal("hello", string(payload))
+
+	// fragmented message with an interleaved ping
+	webSocketTestWriteFrame(conn, 0x01, []byte("hel"))
+	webSocketTestWriteFrame(conn, 0x89, []byte("ping"))
+	webSocketTestWriteFrame(conn, 0x80, []byte("lo again"))
+	first, payload = webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0x8a), first)
+	assert.Equal("ping", string(payload))
+	first, payload = webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0x81), first)
+	assert.Equal("hello again", string(payload))
+
+	webSocketTestWriteFrame(conn, 0x88, []byte{0x03, 0xe8})
+	first, payload = webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0x88), first)
+	assert.Equal(WebSocketCloseNormal, int(binary.BigEndian.Uint16(payload)))
+}
+
+func TestWebSocketOutlivesServerReadTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewUnstartedServer(newWebSocketTestApp())
+	server.Config.ReadTimeout = 20 * time.Millisecond
+	server.Config.WriteTimeout = 20 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	conn, reader, res := webSocketTestDial(t, server, "/echo", nil)
+	defer conn.Close()
+	assert.Equal(http.StatusSwitchingProtocols, res.StatusCode)
+
+	time.Sleep(50 * time.Millisecond)
+	webSocketTestWriteFrame(conn, 0x81, []byte("still here"))
+	first, payload := webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0x81), first)
+	assert.Equal("still here", string(payload))
+}
+
+func TestWebSocketHijackedNotInFlight(t *testing.T) {
+	assert := assert.New(t)
+
+	app := newWebSocketTestApp()
+	app.SetMetricsCollector(NewMetrics())
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	conn, reader, res := webSocketTestDial(t, server, "/echo", nil)
+	defer conn.Close()
+	assert.Equal(http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Zero(app.InFlight(), "a hijacked connection should not be counted as in flight")
+
+	metrics := bytes.NewBuffer(nil)
+	_, err := app.MetricsCollector().WriteTo(metrics)
+	assert.Nil(err)
+	assert.False(strings.Contains(metrics.String(), MetricRequestsInFlight+"{route=\"GET_/echo\""))
+	assert.Contains(MetricRequestsTotal+"{route=\"GET_/echo\",method=\"GET\",status=\"101\"} 1", metrics.String())
+
+	shutdown, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(app.Shutdown(shutdown))
+	assert.Nil(shutdown.Err(), "shutdown should not wait on the websocket")
+
+	first, payload := webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0x88), first)
+	assert.Equal(WebSocketCloseGoingAway, int(binary.BigEndian.Uint16(payload)))
+}
+
+func TestWebSocketCompression(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(newWebSocketTestApp())
+	defer server.Close()
+
+	conn, reader, res := webSocketTestDial(t, server, "/echo", map[string]string{"Sec-WebSocket-Extensions": "permessage-deflate; client_max_window_bits"})
+	defer conn.Close()
+	assert.Equal(http.StatusSwitchingProtocols, res.StatusCode)
+	assert.True(strings.HasPrefix(res.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate"))
+
+	message := strings.Repeat("compress me ", 50)
+	buffer := bytes.NewBuffer(nil)
+	compressor, _ := flate.NewWriter(buffer, flate.BestCompression)
+	compressor.Write([]byte(message))
+	compressor.Flush()
+	webSocketTestWriteFrame(conn, 0xc1, bytes.TrimSuffix(buffer.Bytes(), []byte{0, 0, 0xff, 0xff}))
+
+	first, payload := webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0xc1), first, "the response should be compressed")
+	decompressed, err := ioutil.ReadAll(flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader([]byte{0, 0, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}))))
+	assert.Nil(err)
+	assert.Equal(message, string(decompressed))
+}
+
+func TestWebSocketMessageTooBig(t *testing.T) {
+	assert := assert.New(t)
+
+	app := newWebSocketTestApp()
+	app.WebSocketUpgrader().MaxMessageSize = 16
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	conn, reader, _ := webSocketTestDial(t, server, "/echo", nil)
+	defer conn.Close()
+
+	webSocketTestWriteFrame(conn, 0x82, bytes.Repeat([]byte("a"), 17))
+	first, payload := webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0x88), first)
+	assert.Equal(WebSocketCloseMessageTooBig, int(binary.BigEndian.Uint16(payload)))
+}
+
+func TestWebSocketUnmaskedFrame(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(newWebSocketTestApp())
+	defer server.Close()
+
+	conn, reader, _ := webSocketTestDial(t, server, "/echo", nil)
+	defer conn.Close()
+
+	conn.Write([]byte{0x81, 0x02, 'h', 'i'})
+	first, payload := webSocketTestReadFrame(t, reader)
+	assert.Equal(byte(0x88), first)
+	assert.Equal(WebSocketCloseProtocolError, int(binary.BigEndian.Uint16(payload)))
+}
+
+func TestWebSocketHandshakeFailures(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(newWebSocketTestApp())
+	defer server.Close()
+
+	conn, _, res := webSocketTestDial(t, server, "/private", nil)
+	conn.Close()
+	assert.Equal(http.StatusForbidden, res.StatusCode, "middleware should run before the upgrade")
+
+	conn, _, res = webSocketTestDial(t, server, "/echo", map[string]string{"Origin": "http://evil.example.com"})
+	conn.Close()
+	assert.Equal(http.StatusForbidden, res.StatusCode)
+
+	res, err := http.Get(server.URL + "/echo")
+	assert.Nil(err)
+	res.Body.Close()
+	assert.Equal(http.StatusBadRequest, res.StatusCode)
+}
@@ -48,6 +48,14 @@ func (xrp *XMLResultProvider) BadRequest(message string) Result {
 	}
 }
 
+// Status returns a service response for a status code.
+func (xrp *XMLResultProvider) Status(statusCode int, message string) Result {
+	return &XMLResult{
+		StatusCode: statusCode,
+		Response:   statusMessage(statusCode, message),
+	}
+}
+
 // OK returns a service response.
 func (xrp *XMLResultProvider) OK() Result {
 	return &XMLResult{